@@ -0,0 +1,70 @@
+package execctx
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// BenchStats holds the results of `Bench`.
+type BenchStats struct {
+	// Runs is the number of measured runs, not including warmup runs.
+	Runs   int
+	Min    time.Duration
+	Median time.Duration
+	P95    time.Duration
+	Max    time.Duration
+	// UserTime is the total user CPU time of all measured runs.
+	UserTime time.Duration
+	// SystemTime is the total system CPU time of all measured runs.
+	SystemTime time.Duration
+}
+
+// Bench runs a clone of the command warmup+n times, discarding the first
+// warmup runs, and reports statistics on the wall time of the remaining runs.
+//
+// Each run is governed by the passed in context. Bench stops with the context
+// error if the context is cancelled, or with the error of the first run which
+// fails.
+func (c *Cmd) Bench(ctx context.Context, n, warmup int) (*BenchStats, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("execctx: bench requires at least one run")
+	}
+
+	stats := &BenchStats{Runs: n}
+	times := make([]time.Duration, 0, n)
+
+	for i := 0; i < warmup+n; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		run := c.Clone(ctx)
+		start := time.Now()
+		if err := run.Run(); err != nil {
+			return nil, fmt.Errorf("bench run %d failed: %w", i, err)
+		}
+		elapsed := time.Since(start)
+
+		if i < warmup {
+			continue
+		}
+		times = append(times, elapsed)
+		stats.UserTime += run.cmd.ProcessState.UserTime()
+		stats.SystemTime += run.cmd.ProcessState.SystemTime()
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	stats.Min = times[0]
+	stats.Max = times[n-1]
+	if n%2 == 1 {
+		stats.Median = times[n/2]
+	} else {
+		stats.Median = (times[n/2-1] + times[n/2]) / 2
+	}
+	p95 := (n*95+99)/100 - 1
+	stats.P95 = times[p95]
+
+	return stats, nil
+}
@@ -0,0 +1,28 @@
+package execctx
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestBench(t *testing.T) {
+	c := FromCmd(context.Background(), exec.Command("true"), nil)
+
+	stats, err := c.Bench(context.Background(), 5, 1)
+	assert.NilError(t, err)
+	assert.Equal(t, stats.Runs, 5)
+	assert.Assert(t, stats.Min <= stats.Median)
+	assert.Assert(t, stats.Median <= stats.P95)
+	assert.Assert(t, stats.P95 <= stats.Max)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.Bench(ctx, 5, 0)
+	assert.Equal(t, err, context.Canceled)
+
+	_, err = FromCmd(context.Background(), exec.Command("false"), nil).Bench(context.Background(), 1, 0)
+	assert.ErrorContains(t, err, "bench run 0 failed")
+}
@@ -0,0 +1,160 @@
+package execctx
+
+import (
+	"bytes"
+	"io"
+)
+
+// CapturePolicy controls which part of the output is kept when captured
+// output exceeds its limit.
+type CapturePolicy int
+
+const (
+	// CaptureHead keeps the start of the output.
+	CaptureHead CapturePolicy = iota
+	// CaptureTail keeps the end of the output.
+	CaptureTail
+	// CaptureHeadTail keeps the start and the end of the output, with a note
+	// of how much was omitted in between.
+	CaptureHeadTail
+)
+
+// WithStdoutLimit limits how much stdout is captured by `Output` and
+// `CombinedOutput`. A limit <= 0 means no limit, which is the default.
+//
+// With `CombinedOutput` both streams are written to the same buffer, so only
+// the start of each stream can be kept and the policy is ignored.
+func WithStdoutLimit(limit int, policy CapturePolicy) Option {
+	return func(c *Cmd) {
+		c.stdoutLimit = captureLimit{limit: limit, policy: policy}
+	}
+}
+
+// WithStderrLimit is like `WithStdoutLimit` but for stderr.
+// For `Output` this is the stderr returned in `exec.ExitError`, which by
+// default keeps the first and last 32KiB.
+func WithStderrLimit(limit int, policy CapturePolicy) Option {
+	return func(c *Cmd) {
+		c.stderrLimit = captureLimit{limit: limit, policy: policy}
+	}
+}
+
+// Truncated reports whether captured stdout or stderr was cut short because
+// it exceeded its limit.
+func (c *Cmd) Truncated() (stdout, stderr bool) {
+	if c.stdoutCapture != nil {
+		stdout = c.stdoutCapture.Truncated()
+	}
+	if c.stderrCapture != nil {
+		stderr = c.stderrCapture.Truncated()
+	}
+	return stdout, stderr
+}
+
+type captureLimit struct {
+	limit  int
+	policy CapturePolicy
+}
+
+type capture interface {
+	io.Writer
+	Bytes() []byte
+	Truncated() bool
+}
+
+func newCapture(l captureLimit) capture {
+	if l.limit <= 0 {
+		return &unlimitedCapture{}
+	}
+	switch l.policy {
+	case CaptureTail:
+		return &tailCapture{n: l.limit}
+	case CaptureHeadTail:
+		n := l.limit / 2
+		if n < 1 {
+			n = 1
+		}
+		return &headTailCapture{prefixSuffixSaver{N: n}}
+	default:
+		return &headCapture{n: l.limit}
+	}
+}
+
+type unlimitedCapture struct {
+	bytes.Buffer
+}
+
+func (*unlimitedCapture) Truncated() bool {
+	return false
+}
+
+// headCapture keeps the first n bytes written to it, optionally writing them
+// through to w.
+type headCapture struct {
+	n         int
+	buf       []byte
+	w         io.Writer
+	written   int
+	truncated bool
+}
+
+func (h *headCapture) Write(p []byte) (int, error) {
+	n := len(p)
+	if remain := h.n - h.written; len(p) > remain {
+		p = p[:remain]
+		h.truncated = true
+	}
+	h.written += len(p)
+	if h.w != nil {
+		if _, err := h.w.Write(p); err != nil {
+			return 0, err
+		}
+	} else {
+		h.buf = append(h.buf, p...)
+	}
+	return n, nil
+}
+
+func (h *headCapture) Bytes() []byte {
+	return h.buf
+}
+
+func (h *headCapture) Truncated() bool {
+	return h.truncated
+}
+
+// tailCapture keeps the last n bytes written to it.
+type tailCapture struct {
+	n         int
+	buf       []byte
+	truncated bool
+}
+
+func (t *tailCapture) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	// Only move data around once the buffer is twice the limit.
+	if len(t.buf) > 2*t.n {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-t.n:]...)
+		t.truncated = true
+	}
+	return len(p), nil
+}
+
+func (t *tailCapture) Bytes() []byte {
+	if len(t.buf) > t.n {
+		return t.buf[len(t.buf)-t.n:]
+	}
+	return t.buf
+}
+
+func (t *tailCapture) Truncated() bool {
+	return t.truncated || len(t.buf) > t.n
+}
+
+type headTailCapture struct {
+	prefixSuffixSaver
+}
+
+func (h *headTailCapture) Truncated() bool {
+	return h.skipped > 0
+}
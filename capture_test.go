@@ -0,0 +1,45 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestCaptureLimits(t *testing.T) {
+	script := `printf 0123456789; printf abcdefghij >&2; exit 1`
+
+	c := FromCmd(context.Background(), exec.Command("sh", "-c", script), nil,
+		WithStdoutLimit(4, CaptureTail),
+		WithStderrLimit(4, CaptureHead),
+	)
+	out, err := c.Output(context.Background())
+	assert.Equal(t, string(out), "6789")
+	var ee *exec.ExitError
+	assert.Assert(t, errors.As(err, &ee), err)
+	assert.Equal(t, string(ee.Stderr), "abcd")
+	stdoutTrunc, stderrTrunc := c.Truncated()
+	assert.Assert(t, stdoutTrunc)
+	assert.Assert(t, stderrTrunc)
+
+	c = FromCmd(context.Background(), exec.Command("sh", "-c", script), nil,
+		WithStdoutLimit(4, CaptureHeadTail),
+	)
+	out, _ = c.Output(context.Background())
+	assert.Equal(t, string(out), "01\n... omitting 6 bytes ...\n89")
+	stdoutTrunc, stderrTrunc = c.Truncated()
+	assert.Assert(t, stdoutTrunc)
+	assert.Assert(t, !stderrTrunc)
+
+	c = FromCmd(context.Background(), exec.Command("sh", "-c", script), nil,
+		WithStdoutLimit(3, CaptureHead),
+	)
+	out, _ = c.CombinedOutput()
+	assert.Assert(t, strings.Contains(string(out), "abcdefghij"), string(out))
+	assert.Assert(t, strings.Contains(string(out), "012"), string(out))
+	assert.Assert(t, !strings.Contains(string(out), "0123"), string(out))
+}
@@ -0,0 +1,65 @@
+package execctx
+
+// CgroupSpec describes the cgroup v2 a command is run in, see `WithCgroup`.
+// Zero values leave the corresponding limit unset.
+type CgroupSpec struct {
+	// Parent is the cgroup the command's cgroup is created in, as a path
+	// relative to the root of the cgroup2 hierarchy, e.g.
+	// "/user.slice/user-1000.slice/user@1000.service/app.slice".
+	// It defaults to the cgroup of the current process.
+	//
+	// The controllers for the limits must be enabled in the
+	// cgroup.subtree_control of the parent, and the current process must be
+	// allowed to write to it, as in a systemd unit with Delegate=yes.
+	Parent string
+	// CPU is the number of CPUs worth of time the command may use, e.g. 0.5
+	// for half a CPU, written to cpu.max.
+	CPU float64
+	// MemoryMax is the memory limit in bytes, written to memory.max.
+	MemoryMax int64
+	// PidsMax is the maximum number of processes, written to pids.max.
+	PidsMax int64
+}
+
+// WithCgroup runs the command in a new cgroup v2 created under spec.Parent
+// with the limits of spec. The limits apply to the process and all its
+// descendants.
+//
+// The process is moved into the cgroup by the /bin/sh shim also used by
+// `WithUmask` before it execs the command, so nothing runs outside of it.
+// With `WithUser` the process can't move itself, so it is started in the
+// cgroup by the parent instead, which requires Go 1.20 and Linux 5.7 or
+// newer.
+// Once the command has exited, any process left in the cgroup is killed and
+// the cgroup is removed.
+//
+// Cgroups are only supported on Linux, with the cgroup2 filesystem mounted.
+func WithCgroup(spec CgroupSpec) Option {
+	return func(c *Cmd) {
+		c.cgroup = &spec
+	}
+}
+
+// Cgroup returns the path of the cgroup created for the command by
+// `WithCgroup` in the cgroup2 filesystem, or "" if there is none. It is only
+// set once the command is started and is removed once the command exits.
+func (c *Cmd) Cgroup() string {
+	return c.cgroupPath
+}
+
+func (c *Cmd) setupCgroup() error {
+	if c.cgroup == nil {
+		return nil
+	}
+	path, err := createCgroup(*c.cgroup)
+	if err != nil {
+		return err
+	}
+	c.cgroupPath = path
+	c.onRelease(func() { removeCgroup(path) })
+	if c.user != "" {
+		c.cgroupFromParent = true
+		return c.startInCgroup(path)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package execctx
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// cgroupCPUPeriod is the period written to cpu.max, in microseconds.
+const cgroupCPUPeriod = 100000
+
+func createCgroup(spec CgroupSpec) (string, error) {
+	root, err := cgroup2Mount()
+	if err != nil {
+		return "", err
+	}
+	parent := spec.Parent
+	if parent == "" {
+		parent, err = currentCgroup()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	dir, err := ioutil.TempDir(filepath.Join(root, parent), "execctx-")
+	if err != nil {
+		return "", fmt.Errorf("execctx: error creating cgroup: %w", err)
+	}
+
+	var limits [][2]string
+	if spec.CPU > 0 {
+		limits = append(limits, [2]string{"cpu.max", fmt.Sprintf("%d %d", int64(spec.CPU*cgroupCPUPeriod), cgroupCPUPeriod)})
+	}
+	if spec.MemoryMax > 0 {
+		limits = append(limits, [2]string{"memory.max", strconv.FormatInt(spec.MemoryMax, 10)})
+	}
+	if spec.PidsMax > 0 {
+		limits = append(limits, [2]string{"pids.max", strconv.FormatInt(spec.PidsMax, 10)})
+	}
+	for _, l := range limits {
+		file, value := l[0], l[1]
+		// The interface files of a controller only exist if it is enabled,
+		// cgroupfs doesn't allow creating other files.
+		if _, err := os.Stat(filepath.Join(dir, file)); os.IsNotExist(err) {
+			os.Remove(dir)
+			controller := strings.Split(file, ".")[0]
+			return "", fmt.Errorf("execctx: error setting cgroup limit: the %s controller is not enabled in %s", controller, parent)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(value), 0); err != nil {
+			os.Remove(dir)
+			return "", fmt.Errorf("execctx: error setting cgroup limit: %w", err)
+		}
+	}
+	return dir, nil
+}
+
+// removeCgroup kills the processes left in the cgroup and removes it.
+func removeCgroup(dir string) {
+	if err := ioutil.WriteFile(filepath.Join(dir, "cgroup.kill"), []byte("1"), 0); err != nil {
+		// cgroup.kill is only available since Linux 5.14.
+		data, _ := ioutil.ReadFile(filepath.Join(dir, "cgroup.procs"))
+		for _, f := range strings.Fields(string(data)) {
+			if pid, err := strconv.Atoi(f); err == nil {
+				syscall.Kill(pid, syscall.SIGKILL)
+			}
+		}
+	}
+
+	// The cgroup can only be removed once the killed processes are gone.
+	for i := 0; i < 100; i++ {
+		if err := os.Remove(dir); err == nil || os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+var errNoCgroup2 = errors.New("execctx: cgroup2 filesystem is not mounted")
+
+// cgroup2Mount returns where the cgroup2 filesystem is mounted.
+func cgroup2Mount() (string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// 42 32 0:38 / /sys/fs/cgroup/unified rw,relatime - cgroup2 cgroup2 rw
+		line := s.Text()
+		sep := strings.Index(line, " - ")
+		if sep < 0 {
+			continue
+		}
+		fields := strings.Fields(line[:sep])
+		fstype := strings.Fields(line[sep+3:])
+		if len(fields) < 5 || len(fstype) == 0 || fstype[0] != "cgroup2" {
+			continue
+		}
+		return fields[4], nil
+	}
+	if err := s.Err(); err != nil {
+		return "", err
+	}
+	return "", errNoCgroup2
+}
+
+// currentCgroup returns the cgroup v2 of the current process.
+func currentCgroup() (string, error) {
+	data, err := ioutil.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("0::")) {
+			return string(line[3:]), nil
+		}
+	}
+	return "", errNoCgroup2
+}
@@ -0,0 +1,84 @@
+package execctx
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestCgroup(t *testing.T) {
+	root, err := cgroup2Mount()
+	if err != nil {
+		t.Skip(err)
+	}
+	parent, err := currentCgroup()
+	assert.NilError(t, err)
+	if _, err := os.Stat(filepath.Join(root, parent, "cgroup.procs")); err != nil {
+		t.Skip(err)
+	}
+	probe, err := ioutil.TempDir(filepath.Join(root, parent), "execctx-probe")
+	if err != nil {
+		t.Skip(err)
+	}
+	os.Remove(probe)
+
+	var out strings.Builder
+	c := FromCmd(context.Background(), exec.Command("sh", "-c", "cat /proc/self/cgroup; sleep 10 >/dev/null & echo"), nil, WithStdout(&out), WithCgroup(CgroupSpec{}))
+	assert.NilError(t, c.Run())
+
+	path := c.Cgroup()
+	assert.Assert(t, strings.HasPrefix(path, filepath.Join(root, parent)), path)
+	rel := strings.TrimPrefix(path, root)
+	assert.Assert(t, strings.Contains(out.String(), "0::"+rel+"\n"), out.String())
+
+	// The background sleep was killed with the cgroup.
+	_, err = os.Stat(path)
+	assert.Assert(t, os.IsNotExist(err), err)
+}
+
+func TestCgroupMissingController(t *testing.T) {
+	root, err := cgroup2Mount()
+	if err != nil {
+		t.Skip(err)
+	}
+	parent, err := currentCgroup()
+	assert.NilError(t, err)
+	controllers, err := ioutil.ReadFile(filepath.Join(root, parent, "cgroup.subtree_control"))
+	if err != nil || strings.Contains(string(controllers), "pids") {
+		t.Skip("pids controller enabled")
+	}
+
+	c := FromCmd(context.Background(), exec.Command("true"), nil, WithCgroup(CgroupSpec{PidsMax: 10}))
+	assert.ErrorContains(t, c.Run(), "pids controller is not enabled")
+}
+
+func TestCgroupWithUser(t *testing.T) {
+	root, err := cgroup2Mount()
+	if err != nil {
+		t.Skip(err)
+	}
+	if os.Getuid() != 0 {
+		t.Skip("requires root")
+	}
+	parent, err := currentCgroup()
+	assert.NilError(t, err)
+	probe, err := ioutil.TempDir(filepath.Join(root, parent), "execctx-probe")
+	if err != nil {
+		t.Skip(err)
+	}
+	os.Remove(probe)
+
+	// The process can't move itself into the cgroup, it is started there.
+	var out strings.Builder
+	c := FromCmd(context.Background(), exec.Command("sh", "-c", "id -u; cat /proc/self/cgroup"), nil, WithStdout(&out), WithCgroup(CgroupSpec{}), WithUser("nobody"))
+	assert.NilError(t, c.Run())
+	rel := strings.TrimPrefix(c.Cgroup(), root)
+	assert.Assert(t, strings.Contains(out.String(), "0::"+rel+"\n"), out.String())
+	assert.Assert(t, !strings.HasPrefix(out.String(), "0\n"), out.String())
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package execctx
+
+import "errors"
+
+func createCgroup(spec CgroupSpec) (string, error) {
+	return "", errors.New("execctx: cgroups are only supported on linux")
+}
+
+func removeCgroup(path string) {}
@@ -0,0 +1,28 @@
+//go:build go1.20
+// +build go1.20
+
+package execctx
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// startInCgroup makes the process start in the cgroup at path, placed there
+// by the parent rather than by the shim.
+func (c *Cmd) startInCgroup(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("execctx: error opening cgroup: %w", err)
+	}
+	c.onStarted(func() { f.Close() })
+	c.onRelease(func() { f.Close() })
+
+	if c.cmd.SysProcAttr == nil {
+		c.cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	c.cmd.SysProcAttr.UseCgroupFD = true
+	c.cmd.SysProcAttr.CgroupFD = int(f.Fd())
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux || !go1.20
+// +build !linux !go1.20
+
+package execctx
+
+import "errors"
+
+// startInCgroup makes the process start in the cgroup at path, placed there
+// by the parent rather than by the shim. This needs Go 1.20 or newer.
+func (c *Cmd) startInCgroup(path string) error {
+	return errors.New("execctx: running a command in a cgroup as another user requires Go 1.20 or newer")
+}
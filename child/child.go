@@ -0,0 +1,89 @@
+// Package child is used by programs started through execctx to cooperate
+// with their parent on cancellation.
+//
+// The parent opts in with `execctx.WithDeadlineEnv` and `execctx.WithCancelFD`,
+// the child then uses `Context` to get a context which reflects the
+// cancellation of the parent's context. With `execctx.WithHealthFile` the
+// child reports it is alive by calling `Healthy`.
+//
+// This package only depends on the standard library so it is cheap to import
+// in small tools.
+package child
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// EnvDeadline holds the deadline of the parent's context in RFC 3339
+	// format.
+	EnvDeadline = "EXECCTX_DEADLINE"
+	// EnvCancelFD holds the number of a file descriptor which reaches EOF
+	// when the command is cancelled.
+	EnvCancelFD = "EXECCTX_CANCEL_FD"
+	// EnvHealthFile holds the path of a file whose modification time must
+	// be kept up to date to signal the process is healthy.
+	EnvHealthFile = "EXECCTX_HEALTH_FILE"
+)
+
+// Context returns a context derived from parent which carries the deadline
+// passed down by the parent process and is cancelled when the parent
+// cancels the command.
+// If the parent did not pass down this information, the returned context is
+// simply a cancellable child of parent.
+//
+// Context should only be called once per process since it takes ownership
+// of the cancellation file descriptor.
+func Context(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+
+	if v := os.Getenv(EnvDeadline); v != "" {
+		if deadline, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			var cancelDeadline context.CancelFunc
+			ctx, cancelDeadline = context.WithDeadline(ctx, deadline)
+			cancelParent := cancel
+			cancel = func() {
+				cancelDeadline()
+				cancelParent()
+			}
+		}
+	}
+
+	if v := os.Getenv(EnvCancelFD); v != "" {
+		if fd, err := strconv.Atoi(v); err == nil && fd > 2 {
+			watchCancelFD(os.NewFile(uintptr(fd), "execctx-cancel"), cancel)
+		}
+	}
+
+	return ctx, cancel
+}
+
+// watchCancelFD calls cancel once f reaches EOF (or fails to read), which
+// happens when the parent closes its end.
+func watchCancelFD(f *os.File, cancel context.CancelFunc) {
+	if f == nil {
+		return
+	}
+	go func() {
+		io.Copy(ioutil.Discard, f)
+		f.Close()
+		cancel()
+	}()
+}
+
+// Healthy marks the process as healthy by updating the modification time of
+// the health file passed down by the parent. It does nothing if the parent
+// did not pass a health file.
+func Healthy() error {
+	path := os.Getenv(EnvHealthFile)
+	if path == "" {
+		return nil
+	}
+	now := time.Now()
+	return os.Chtimes(path, now, now)
+}
@@ -0,0 +1,64 @@
+package child
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestContext(t *testing.T) {
+	deadline := time.Now().Add(time.Hour).Round(0)
+	os.Setenv(EnvDeadline, deadline.Format(time.RFC3339Nano))
+	defer os.Unsetenv(EnvDeadline)
+
+	r, w, err := os.Pipe()
+	assert.NilError(t, err)
+	os.Setenv(EnvCancelFD, strconv.Itoa(int(r.Fd())))
+	defer os.Unsetenv(EnvCancelFD)
+
+	ctx, cancel := Context(context.Background())
+	defer cancel()
+
+	dl, ok := ctx.Deadline()
+	assert.Assert(t, ok)
+	assert.Assert(t, dl.Equal(deadline), dl)
+
+	select {
+	case <-ctx.Done():
+		t.Fatal("context cancelled early")
+	default:
+	}
+
+	w.Close()
+	select {
+	case <-ctx.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for cancellation")
+	}
+}
+
+func TestHealthy(t *testing.T) {
+	assert.NilError(t, Healthy())
+
+	dir, err := ioutil.TempDir("", "execctx-health")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "health")
+	assert.NilError(t, ioutil.WriteFile(path, nil, 0600))
+	old := time.Now().Add(-time.Hour)
+	assert.NilError(t, os.Chtimes(path, old, old))
+
+	os.Setenv(EnvHealthFile, path)
+	defer os.Unsetenv(EnvHealthFile)
+	assert.NilError(t, Healthy())
+
+	fi, err := os.Stat(path)
+	assert.NilError(t, err)
+	assert.Assert(t, time.Since(fi.ModTime()) < time.Minute, fi.ModTime())
+}
@@ -0,0 +1,86 @@
+package execctx
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cpuguy83/execctx/child"
+)
+
+// WithDeadlineEnv passes the deadline of the command's context, if any, to
+// the process in the `child.EnvDeadline` environment variable.
+// The child can use `child.Context` to pick it up.
+func WithDeadlineEnv() Option {
+	return func(c *Cmd) {
+		c.deadlineEnv = true
+	}
+}
+
+// WithCancelFD passes the process a file descriptor which reaches EOF when
+// the command is cancelled, or when the parent process goes away.
+// The number of the descriptor is passed in the `child.EnvCancelFD`
+// environment variable and the child can use `child.Context` to watch it.
+//
+// The descriptor is closed before the cancel handler is called. It is not
+// supported on Windows.
+func WithCancelFD() Option {
+	return func(c *Cmd) {
+		c.cancelFD = true
+	}
+}
+
+// injectedEnv returns the environment variables added to the process by the
+// package.
+func (c *Cmd) injectedEnv() []string {
+	var env []string
+	if c.deadlineEnv {
+		if deadline, ok := c.ctx.Deadline(); ok {
+			env = append(env, child.EnvDeadline+"="+deadline.Format(time.RFC3339Nano))
+		}
+	}
+	if c.cancelFD {
+		fd := c.cancelFDNum
+		if fd == 0 {
+			fd = 3 + len(c.cmd.ExtraFiles)
+		}
+		env = append(env, child.EnvCancelFD+"="+strconv.Itoa(fd))
+	}
+	env = append(env, c.healthEnv()...)
+	env = append(env, c.elevationEnv()...)
+	env = append(env, c.userEnv()...)
+	env = append(env, c.localeEnv()...)
+	env = append(env, c.hermeticInjectedEnv()...)
+	return append(env, c.lazyEnvValues...)
+}
+
+// setupChildEnv prepares the injected environment and files for the process.
+func (c *Cmd) setupChildEnv() error {
+	if c.cancelFD {
+		if runtime.GOOS == "windows" {
+			return errors.New("execctx: cancellation file descriptors are not supported on windows")
+		}
+
+		r, w, err := os.Pipe()
+		if err != nil {
+			return err
+		}
+		c.cancelFDNum = 3 + len(c.cmd.ExtraFiles)
+		c.cmd.ExtraFiles = append(c.cmd.ExtraFiles, r)
+
+		var once sync.Once
+		c.closeCancelFD = func() {
+			once.Do(func() { w.Close() })
+		}
+		c.onRelease(c.closeCancelFD)
+		c.onStarted(func() { r.Close() })
+	}
+
+	if len(c.injectedEnv()) > 0 {
+		c.cmd.Env = c.EffectiveEnv()
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package execctx
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestChildEnv(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	cmd := exec.Command("sh", "-c", `echo "$EXECCTX_DEADLINE"; cat <&"$EXECCTX_CANCEL_FD"; echo cancelled`)
+	var out strings.Builder
+	cmd.Stdout = &out
+
+	// The handler doesn't do anything, the child is expected to exit on its
+	// own once the cancellation fd is closed.
+	c := FromCmd(ctx, cmd, func() {}, WithDeadlineEnv(), WithCancelFD())
+	assert.Equal(t, c.EnvMap()["EXECCTX_CANCEL_FD"], "3")
+
+	assert.NilError(t, c.Start())
+	c.Cancel(nil)
+	assert.NilError(t, c.Wait())
+
+	deadline, _ := ctx.Deadline()
+	assert.Equal(t, out.String(), deadline.Format(time.RFC3339Nano)+"\ncancelled\n")
+}
@@ -0,0 +1,63 @@
+package execctx
+
+// Severity is the severity of a line of output, see `WithStderrClassifier`.
+type Severity int
+
+// Severities returned by classifiers.
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// WithStderrClassifier classifies every line the command writes to stderr.
+// The number of lines per severity is available from `StderrCounts`.
+// Many tools write warnings and progress to stderr, this makes it possible to
+// tell them apart from actual errors, for instance to decide whether to
+// retry or alert.
+//
+// Lines are passed to classify without the line ending, after any stderr
+// filters are applied. The classifier is used even if stderr is not set on
+// the command.
+func WithStderrClassifier(classify func(line string) Severity) Option {
+	return func(c *Cmd) {
+		c.stderrClassifier = classify
+	}
+}
+
+// StderrCounts returns the number of stderr lines per severity, as classified
+// by the function passed to `WithStderrClassifier`.
+func (c *Cmd) StderrCounts() map[Severity]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := make(map[Severity]int, len(c.stderrCounts))
+	for k, v := range c.stderrCounts {
+		counts[k] = v
+	}
+	return counts
+}
+
+func (c *Cmd) classifyStderr(line []byte) {
+	sev := c.stderrClassifier(string(line))
+
+	c.mu.Lock()
+	if c.stderrCounts == nil {
+		c.stderrCounts = make(map[Severity]int)
+	}
+	c.stderrCounts[sev]++
+	c.mu.Unlock()
+}
@@ -0,0 +1,32 @@
+package execctx
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestStderrClassifier(t *testing.T) {
+	classify := func(line string) Severity {
+		switch {
+		case strings.HasPrefix(line, "WARNING:"):
+			return SeverityWarning
+		case strings.HasPrefix(line, "ERROR:"):
+			return SeverityError
+		default:
+			return SeverityInfo
+		}
+	}
+
+	cmd := exec.Command("sh", "-c", `printf 'WARNING: a\r\nWARNING: b\nprogress\nERROR: c' >&2`)
+	c := FromCmd(context.Background(), cmd, nil, WithStderrClassifier(classify))
+	assert.NilError(t, c.Run())
+	assert.DeepEqual(t, c.StderrCounts(), map[Severity]int{
+		SeverityWarning: 2,
+		SeverityInfo:    1,
+		SeverityError:   1,
+	})
+}
@@ -0,0 +1,35 @@
+package execctx
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// RunCleanup runs a cleanup command and waits for it to exit.
+// See `WithCleanup` for details.
+func RunCleanup(ctx context.Context, cmd *exec.Cmd, cancel func(), timeout time.Duration) error {
+	return FromCmd(ctx, cmd, cancel, WithCleanup(timeout)).Run()
+}
+
+// detachedContext is a context which carries the values of its parent but is
+// never cancelled.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
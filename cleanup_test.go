@@ -0,0 +1,36 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRunCleanup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.NilError(t, RunCleanup(ctx, exec.Command("true"), nil, time.Minute))
+
+	err := RunCleanup(ctx, exec.Command("sleep", "99999"), nil, 100*time.Millisecond)
+	assert.Assert(t, errors.Is(err, context.DeadlineExceeded), err)
+}
+
+func TestGraceBudget(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := FromCmd(ctx, exec.Command("true"), nil).Run()
+	var sce *StartCancelledError
+	assert.Assert(t, errors.As(err, &sce), err)
+	assert.Equal(t, sce.Cause, context.Canceled)
+
+	assert.NilError(t, FromCmd(ctx, exec.Command("true"), nil, WithGraceBudget(time.Minute)).Run())
+
+	err = FromCmd(ctx, exec.Command("sleep", "99999"), nil, WithGraceBudget(10*time.Millisecond)).Run()
+	assert.Assert(t, errors.Is(err, context.DeadlineExceeded), err)
+}
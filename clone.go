@@ -0,0 +1,88 @@
+package execctx
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"reflect"
+	"unsafe"
+)
+
+// Clone returns a new, unstarted command with the same configuration as c,
+// governed by the passed in context. The options attached to ctx with
+// `WithOptions`, and then opts, are applied to the clone on top of the
+// copied configuration.
+//
+// The clone is made from the command as it was configured before it was
+// started, so it doesn't inherit what the package set up to run c. The stdio
+// of the wrapped exec.Cmd is shared with the clone. In particular a stdin
+// reader which was already consumed by c will not be replayed.
+//
+// The cancel function passed to `FromCmd` or set with `WithCancelFunc` is
+// not copied, since it usually signals the process of the original
+// exec.Cmd. The clone is cancelled as if it was nil, unless a new one is
+// passed with `WithCancelFunc` in opts.
+func (c *Cmd) Clone(ctx context.Context, opts ...Option) *Cmd {
+	c.mu.Lock()
+	src := c.pristine
+	if src == nil {
+		src = c.cmd
+	}
+	cmd := copyExecCmd(src)
+	c.mu.Unlock()
+
+	nc := allocCmd(ctx, cmd, nil)
+	nc.config = c.config
+	clipSlices(&nc.config)
+	nc.labels = c.Labels()
+	group := nc.group
+	nc.group = nil
+	for _, o := range contextOptions(ctx) {
+		o(nc)
+	}
+	for _, o := range opts {
+		o(nc)
+	}
+	if nc.group == nil && group != nil {
+		WithGroup(group)(nc)
+	}
+	return nc
+}
+
+// copyExecCmd returns an unstarted copy of cmd.
+func copyExecCmd(cmd *exec.Cmd) *exec.Cmd {
+	cp := &exec.Cmd{
+		Path:       cmd.Path,
+		Args:       append([]string(nil), cmd.Args...),
+		Dir:        cmd.Dir,
+		Stdin:      cmd.Stdin,
+		Stdout:     cmd.Stdout,
+		Stderr:     cmd.Stderr,
+		ExtraFiles: append([]*os.File(nil), cmd.ExtraFiles...),
+	}
+	if cmd.Env != nil {
+		cp.Env = append([]string(nil), cmd.Env...)
+	}
+	if cmd.SysProcAttr != nil {
+		attr := *cmd.SysProcAttr
+		cp.SysProcAttr = &attr
+	}
+	return cp
+}
+
+// clipSlices limits the capacity of the slices in the config to their
+// length, so options appending to a copy of the config never write to the
+// arrays shared with the original.
+func clipSlices(cfg *config) {
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if f.Kind() != reflect.Slice || f.IsNil() {
+			continue
+		}
+		// The fields are unexported, so they are set through their address.
+		f = reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem()
+		n := f.Len()
+		f.Set(f.Slice3(0, n, n))
+	}
+}
@@ -0,0 +1,69 @@
+package execctx
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestCloneCancel(t *testing.T) {
+	cmd := exec.Command("sleep", "10")
+	c := FromCmd(context.Background(), cmd, func() {
+		cmd.Process.Kill()
+	})
+
+	// The cancel function is bound to cmd, which is never started, so it
+	// must not be used to cancel the clone.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	clone := c.Clone(ctx)
+	start := time.Now()
+	assert.Assert(t, clone.Run() != nil)
+	assert.Assert(t, time.Since(start) < 5*time.Second)
+
+	var called bool
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	clone = c.Clone(ctx, WithCancelFunc(func() {
+		called = true
+		clone.Signal(os.Kill)
+	}))
+	assert.Assert(t, clone.Run() != nil)
+	assert.Assert(t, called)
+}
+
+func TestCloneStarted(t *testing.T) {
+	// The clone doesn't inherit what was set up to run the command, here the
+	// umask shim and the capture of Output.
+	c := FromCmd(context.Background(), exec.Command("sh", "-c", "umask"), nil, WithUmask(027))
+	out, err := c.Output(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "0027\n")
+
+	out, err = c.Clone(context.Background()).Output(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "0027\n")
+}
+
+func TestCloneOptions(t *testing.T) {
+	var order []string
+	hook := func(name string) Option {
+		return OnExit(func(Result) { order = append(order, name) })
+	}
+	c := FromCmd(context.Background(), exec.Command("true"), nil, hook("a"), hook("b"), hook("c"))
+
+	// Options attached to the context of the clone are applied, and the
+	// options of one clone don't leak into another.
+	ctx := WithOptions(context.Background(), hook("ctx"))
+	c1 := c.Clone(ctx, hook("1"))
+	c2 := c.Clone(context.Background(), hook("2"))
+	assert.NilError(t, c1.Run())
+	assert.DeepEqual(t, order, []string{"a", "b", "c", "ctx", "1"})
+	order = nil
+	assert.NilError(t, c2.Run())
+	assert.DeepEqual(t, order, []string{"a", "b", "c", "2"})
+}
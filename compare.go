@@ -0,0 +1,247 @@
+package execctx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CompareOptions controls how `CompareRuns` compares two runs.
+type CompareOptions struct {
+	// Filters normalize the captured output of both runs before it is
+	// compared, for instance `StripANSI`, or a filter which masks timestamps
+	// or temporary paths. They are applied in order, like the filters
+	// passed to `WithStdoutFilter`.
+	Filters []OutputFilter
+	// IgnoreStderr skips comparing stderr.
+	IgnoreStderr bool
+	// IgnoreCause skips comparing the cancellation cause.
+	IgnoreCause bool
+}
+
+// FieldDiff is a piece of exit metadata which differs between two runs.
+type FieldDiff struct {
+	// Field is the name of the `Result` field, such as "ExitCode".
+	Field string
+	// A and B are the values in the first and second run.
+	A, B string
+}
+
+// LineDiff is a line of output which is only in one of two runs.
+type LineDiff struct {
+	// Op is '-' for a line only in the first run, '+' for a line only in
+	// the second run.
+	Op byte
+	// Line is the line number, starting at 1, in the run the line is from.
+	Line int
+	Text string
+}
+
+func (d LineDiff) String() string {
+	return fmt.Sprintf("%c%d: %s", d.Op, d.Line, d.Text)
+}
+
+// RunDiff describes how two runs differ, see `CompareRuns`.
+type RunDiff struct {
+	// Fields lists the exit metadata which differs.
+	Fields []FieldDiff
+	// Stdout and Stderr list the lines of normalized output which differ,
+	// in order. Lines which were removed are listed before the lines which
+	// replace them.
+	Stdout []LineDiff
+	Stderr []LineDiff
+}
+
+// Equal reports whether the runs did not differ.
+func (d *RunDiff) Equal() bool {
+	return len(d.Fields) == 0 && len(d.Stdout) == 0 && len(d.Stderr) == 0
+}
+
+// String formats the differences, one per line.
+func (d *RunDiff) String() string {
+	var b strings.Builder
+	for _, f := range d.Fields {
+		fmt.Fprintf(&b, "%s: %s != %s\n", f.Field, f.A, f.B)
+	}
+	for _, l := range d.Stdout {
+		fmt.Fprintf(&b, "stdout %s\n", l)
+	}
+	for _, l := range d.Stderr {
+		fmt.Fprintf(&b, "stderr %s\n", l)
+	}
+	return b.String()
+}
+
+// CompareRuns compares the results of two runs of a command, for instance
+// an old and a new version of a tool run side by side, and returns how they
+// differ.
+//
+// The exit code, terminating signal, cancellation cause, and whether output
+// was truncated are compared, as well as the captured output, line by
+// line, after it is normalized with the filters in opts. Timing and
+// samples are not compared. If opts is nil, the output is compared as is.
+func CompareRuns(a, b *Result, opts *CompareOptions) (*RunDiff, error) {
+	if opts == nil {
+		opts = &CompareOptions{}
+	}
+
+	d := &RunDiff{}
+	field := func(name, x, y string) {
+		if x != y {
+			d.Fields = append(d.Fields, FieldDiff{Field: name, A: x, B: y})
+		}
+	}
+	field("ExitCode", strconv.Itoa(a.ExitCode), strconv.Itoa(b.ExitCode))
+	field("Signal", signalString(a.Signal), signalString(b.Signal))
+	if !opts.IgnoreCause {
+		field("Cause", errorString(a.Cause), errorString(b.Cause))
+	}
+	field("StdoutTruncated", strconv.FormatBool(a.StdoutTruncated), strconv.FormatBool(b.StdoutTruncated))
+	if !opts.IgnoreStderr {
+		field("StderrTruncated", strconv.FormatBool(a.StderrTruncated), strconv.FormatBool(b.StderrTruncated))
+	}
+
+	var err error
+	if d.Stdout, err = opts.diffOutput(a.Stdout, b.Stdout); err != nil {
+		return nil, err
+	}
+	if !opts.IgnoreStderr {
+		if d.Stderr, err = opts.diffOutput(a.Stderr, b.Stderr); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+func (o *CompareOptions) diffOutput(a, b []byte) ([]LineDiff, error) {
+	a, err := o.normalize(a)
+	if err != nil {
+		return nil, err
+	}
+	b, err = o.normalize(b)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(a, b) {
+		return nil, nil
+	}
+	return diffLines(splitLines(a), splitLines(b)), nil
+}
+
+// normalize passes out through the filters.
+func (o *CompareOptions) normalize(out []byte) ([]byte, error) {
+	if len(o.Filters) == 0 {
+		return out, nil
+	}
+
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	closers := make([]io.Closer, len(o.Filters))
+	for i := len(o.Filters) - 1; i >= 0; i-- {
+		wc := o.Filters[i](w)
+		closers[i] = wc
+		w = wc
+	}
+	if _, err := w.Write(out); err != nil {
+		return nil, fmt.Errorf("error normalizing output: %w", err)
+	}
+	for _, c := range closers {
+		if err := c.Close(); err != nil {
+			return nil, fmt.Errorf("error normalizing output: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func splitLines(out []byte) []string {
+	if len(out) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+}
+
+// diffLines returns the lines which are only in a or only in b, using the
+// shortest edit script found by Myers' algorithm.
+func diffLines(a, b []string) []LineDiff {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace holds, for every d, the furthest x reached on the diagonals
+	// -d+1 to d-1 by d-1 edits.
+	var trace [][]int
+
+found:
+	for d := 0; d <= max; d++ {
+		if d == 0 {
+			trace = append(trace, nil)
+		} else {
+			trace = append(trace, append([]int(nil), v[offset-d+1:offset+d]...))
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break found
+			}
+		}
+	}
+
+	// Walk the trace back from the end to recover the edits.
+	var diffs []LineDiff
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		v := func(k int) int { return trace[d][k+d-1] }
+		k := x - y
+		var prevK int
+		if k == -d || k != d && v(k-1) < v(k+1) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+		}
+		if x == prevX {
+			diffs = append(diffs, LineDiff{Op: '+', Line: prevY + 1, Text: b[prevY]})
+		} else {
+			diffs = append(diffs, LineDiff{Op: '-', Line: prevX + 1, Text: a[prevX]})
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(diffs)-1; i < j; i, j = i+1, j-1 {
+		diffs[i], diffs[j] = diffs[j], diffs[i]
+	}
+	return diffs
+}
+
+func signalString(sig os.Signal) string {
+	if sig == nil {
+		return ""
+	}
+	return sig.String()
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,74 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"runtime"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestDiffLines(t *testing.T) {
+	a := []string{"a", "b", "c", "d"}
+	b := []string{"a", "x", "c", "d", "e"}
+	assert.DeepEqual(t, diffLines(a, b), []LineDiff{
+		{Op: '-', Line: 2, Text: "b"},
+		{Op: '+', Line: 2, Text: "x"},
+		{Op: '+', Line: 5, Text: "e"},
+	})
+	assert.Equal(t, len(diffLines(a, a)), 0)
+	assert.DeepEqual(t, diffLines(nil, []string{"a"}), []LineDiff{{Op: '+', Line: 1, Text: "a"}})
+	assert.DeepEqual(t, diffLines([]string{"a"}, nil), []LineDiff{{Op: '-', Line: 1, Text: "a"}})
+}
+
+func TestCompareRuns(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a posix shell")
+	}
+
+	run := func(script string) *Result {
+		c := CommandContext(context.Background(), "sh", "-c", script)
+		r, _ := c.RunResult()
+		return &r
+	}
+	old := run(`echo "took 12ms"; echo same; echo warn >&2`)
+	cur := run(`echo "took 30ms"; echo same; echo changed; exit 2`)
+
+	// Mask the timings, which always differ.
+	mask := func(w io.Writer) io.WriteCloser {
+		return &regexpFilter{w: w, re: regexp.MustCompile(`[0-9]+ms`)}
+	}
+	d, err := CompareRuns(old, cur, &CompareOptions{Filters: []OutputFilter{mask}})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, d.Fields, []FieldDiff{{Field: "ExitCode", A: "0", B: "2"}})
+	assert.DeepEqual(t, d.Stdout, []LineDiff{{Op: '+', Line: 3, Text: "changed"}})
+	assert.DeepEqual(t, d.Stderr, []LineDiff{{Op: '-', Line: 1, Text: "warn"}})
+	assert.Assert(t, !d.Equal())
+
+	d, err = CompareRuns(old, old, nil)
+	assert.NilError(t, err)
+	assert.Assert(t, d.Equal(), d)
+
+	d, err = CompareRuns(&Result{Cause: errors.New("a")}, &Result{Cause: errors.New("b")}, &CompareOptions{IgnoreCause: true})
+	assert.NilError(t, err)
+	assert.Assert(t, d.Equal(), d)
+}
+
+// regexpFilter replaces matches of re with "X", assuming matches are not
+// split across writes.
+type regexpFilter struct {
+	w  io.Writer
+	re *regexp.Regexp
+}
+
+func (f *regexpFilter) Write(p []byte) (int, error) {
+	if _, err := f.w.Write(f.re.ReplaceAll(p, []byte("X"))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (f *regexpFilter) Close() error { return nil }
@@ -0,0 +1,101 @@
+package execctx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrLocked is returned by `Start` when the lock requested with `WithDirLock`
+// or `WithLockFile` is held by someone else and `LockFailFast` is used.
+var ErrLocked = errors.New("execctx: lock is held by another process")
+
+// LockMode controls what happens when a lock is already held.
+type LockMode int
+
+const (
+	// LockWait waits for the lock to be released, until the command is
+	// cancelled. `Start` then returns a `*StartCancelledError`.
+	LockWait LockMode = iota
+	// LockFailFast makes `Start` return `ErrLocked` immediately.
+	LockFailFast
+)
+
+// WithDirLock takes an exclusive advisory lock (flock(2)) on the working
+// directory of the command before it is started, and releases it after the
+// command exits.
+// This serializes tools which corrupt their state when run concurrently in
+// the same directory, like terraform.
+//
+// The lock only excludes other processes (or commands) which take the same
+// lock. Locking is not supported on all platforms, where it isn't `Start`
+// returns an error.
+func WithDirLock(mode LockMode) Option {
+	return func(c *Cmd) {
+		c.dirLock = true
+		c.dirLockFile = ""
+		c.dirLockMode = mode
+	}
+}
+
+// WithLockFile is like `WithDirLock` but locks the provided file instead of
+// the working directory. The file is created if it does not exist.
+func WithLockFile(path string, mode LockMode) Option {
+	return func(c *Cmd) {
+		c.dirLock = true
+		c.dirLockFile = path
+		c.dirLockMode = mode
+	}
+}
+
+const lockPollInterval = 50 * time.Millisecond
+
+func (c *Cmd) lockDir() error {
+	if !c.dirLock {
+		return nil
+	}
+
+	var (
+		f   *os.File
+		err error
+	)
+	if c.dirLockFile != "" {
+		f, err = os.OpenFile(c.dirLockFile, os.O_RDONLY|os.O_CREATE, 0600)
+	} else {
+		dir := c.cmd.Dir
+		if dir == "" {
+			dir = "."
+		}
+		f, err = os.Open(dir)
+	}
+	if err != nil {
+		return fmt.Errorf("error opening lock target: %w", err)
+	}
+
+	for {
+		err = tryLock(f)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrLocked) || c.dirLockMode == LockFailFast {
+			f.Close()
+			return err
+		}
+
+		select {
+		case <-c.ctx.Done():
+		case <-c.cancelled:
+		case <-time.After(lockPollInterval):
+			continue
+		}
+		f.Close()
+		return c.cancelledErr()
+	}
+
+	c.onRelease(func() {
+		unlock(f)
+		f.Close()
+	})
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package execctx
+
+import (
+	"errors"
+	"os"
+)
+
+func tryLock(f *os.File) error {
+	return errors.New("execctx: directory locking is not supported on this platform")
+}
+
+func unlock(f *os.File) {}
@@ -0,0 +1,58 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestDirLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "execctx-dirlock")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cmd := exec.Command("sleep", "99999")
+	cmd.Dir = dir
+	holder := FromCmd(ctx, cmd, nil, WithDirLock(LockWait))
+	assert.NilError(t, holder.Start())
+
+	cmd = exec.Command("true")
+	cmd.Dir = dir
+	err = FromCmd(context.Background(), cmd, nil, WithDirLock(LockFailFast)).Run()
+	assert.Assert(t, errors.Is(err, ErrLocked), err)
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer waitCancel()
+	cmd = exec.Command("true")
+	cmd.Dir = dir
+	err = FromCmd(waitCtx, cmd, nil, WithDirLock(LockWait)).Run()
+	var startErr *StartCancelledError
+	assert.Assert(t, errors.As(err, &startErr), err)
+	assert.Equal(t, startErr.Cause, context.DeadlineExceeded)
+
+	// Cancelling the command stops waiting as well.
+	cmd = exec.Command("true")
+	cmd.Dir = dir
+	waiter := FromCmd(context.Background(), cmd, nil, WithDirLock(LockWait))
+	cause := errors.New("shutting down")
+	time.AfterFunc(50*time.Millisecond, func() { waiter.Cancel(cause) })
+	err = waiter.Run()
+	assert.Assert(t, errors.As(err, &startErr), err)
+	assert.Equal(t, startErr.Cause, cause)
+
+	cancel()
+	holder.Wait()
+
+	cmd = exec.Command("true")
+	cmd.Dir = dir
+	assert.NilError(t, FromCmd(context.Background(), cmd, nil, WithDirLock(LockFailFast)).Run())
+}
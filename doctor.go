@@ -0,0 +1,158 @@
+package execctx
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Capability is an optional feature of the platform, as probed by `Doctor`.
+type Capability struct {
+	Name      string
+	Available bool
+	// Detail explains why the capability is not available, or gives more
+	// information about it when it is.
+	Detail string
+}
+
+func (c Capability) String() string {
+	s := c.Name + ": "
+	if c.Available {
+		s += "available"
+	} else {
+		s += "unavailable"
+	}
+	if c.Detail != "" {
+		s += " (" + c.Detail + ")"
+	}
+	return s
+}
+
+// Report is the result of `Doctor`.
+type Report struct {
+	Capabilities []Capability
+}
+
+// Available reports whether the named capability is available.
+func (r Report) Available(name string) bool {
+	for _, c := range r.Capabilities {
+		if c.Name == name {
+			return c.Available
+		}
+	}
+	return false
+}
+
+// Missing returns the capabilities which are not available.
+func (r Report) Missing() []Capability {
+	var missing []Capability
+	for _, c := range r.Capabilities {
+		if !c.Available {
+			missing = append(missing, c)
+		}
+	}
+	return missing
+}
+
+func (r Report) String() string {
+	lines := make([]string, 0, len(r.Capabilities))
+	for _, c := range r.Capabilities {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// capabilityProbe checks a capability and returns whether it is available
+// along with the detail for `Capability`.
+type capabilityProbe func(ctx context.Context) (bool, string)
+
+// capabilityNames are the capabilities probed by `Doctor`, in order.
+var capabilityNames = []string{
+	"shim",
+	"strace",
+	"sudo",
+	"doas",
+	"sampling",
+	"cpu-throttle",
+	"cgroup2",
+	"pidfd",
+	"user-namespaces",
+	"network-isolation",
+	"seccomp",
+	"landlock",
+	"job-objects",
+	"conpty",
+}
+
+// Doctor probes the current platform for the optional capabilities used by
+// the package and reports which are available, so programs can fail fast or
+// warn about degraded behavior at startup:
+//
+//   - shim: /bin/sh, used by `WithUmask`, `WithRlimits`, and `WithCgroup`.
+//   - strace, sudo, doas: the tools used by `Strace` and `WithElevation`.
+//   - sampling: /proc based sampling for `WithSampling`.
+//   - cpu-throttle: `WithCPUThrottle`.
+//   - cgroup2: a cgroup2 hierarchy where the current process may create
+//     cgroups for `WithCgroup`, the detail lists the enabled controllers.
+//   - pidfd: pidfd_open(2), for race-free process handles.
+//   - user-namespaces: processes can be started in a new user namespace.
+//   - network-isolation: `Hermetic` can start processes without network.
+//   - seccomp, landlock: the kernel sandboxing interfaces.
+//   - job-objects: Windows job objects for `WithJobObject`.
+//   - conpty: Windows pseudo consoles.
+//
+// Some probes start short-lived processes, ctx bounds them.
+func Doctor(ctx context.Context) Report {
+	probes := map[string]capabilityProbe{
+		"shim":         probeShim,
+		"strace":       probeTool("strace"),
+		"sudo":         probeTool("sudo"),
+		"doas":         probeTool("doas"),
+		"sampling":     probeSupported(samplingSupported),
+		"cpu-throttle": probeSupported(throttleSupported),
+	}
+	for name, probe := range platformProbes() {
+		probes[name] = probe
+	}
+
+	var r Report
+	for _, name := range capabilityNames {
+		c := Capability{Name: name, Detail: "not supported on " + runtime.GOOS}
+		if probe, ok := probes[name]; ok {
+			c.Available, c.Detail = probe(ctx)
+		}
+		r.Capabilities = append(r.Capabilities, c)
+	}
+	return r
+}
+
+func probeSupported(supported bool) capabilityProbe {
+	return func(context.Context) (bool, string) {
+		if !supported {
+			return false, "not supported on " + runtime.GOOS
+		}
+		return true, ""
+	}
+}
+
+func probeShim(context.Context) (bool, string) {
+	if runtime.GOOS == "windows" {
+		return false, "not supported on windows"
+	}
+	if _, err := os.Stat(shimShell); err != nil {
+		return false, err.Error()
+	}
+	return true, shimShell
+}
+
+func probeTool(name string) capabilityProbe {
+	return func(context.Context) (bool, string) {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			return false, err.Error()
+		}
+		return true, path
+	}
+}
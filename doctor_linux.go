@@ -0,0 +1,116 @@
+package execctx
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	sysPidfdOpen             = 434
+	sysLandlockCreateRuleset = 444
+
+	landlockCreateRulesetVersion = 1
+)
+
+func platformProbes() map[string]capabilityProbe {
+	return map[string]capabilityProbe{
+		"cgroup2":           probeCgroup2,
+		"pidfd":             probePidfd,
+		"user-namespaces":   probeUserNamespaces,
+		"network-isolation": probeNetworkIsolation,
+		"seccomp":           probeSeccomp,
+		"landlock":          probeLandlock,
+	}
+}
+
+func probeCgroup2(context.Context) (bool, string) {
+	root, err := cgroup2Mount()
+	if err != nil {
+		return false, err.Error()
+	}
+	current, err := currentCgroup()
+	if err != nil {
+		return false, err.Error()
+	}
+	dir := filepath.Join(root, current)
+	probe, err := ioutil.TempDir(dir, "execctx-probe")
+	if err != nil {
+		return false, "cannot create cgroups in " + dir + ": " + err.Error()
+	}
+	os.Remove(probe)
+
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "cgroup.subtree_control"))
+	controllers := strings.Fields(string(data))
+	if len(controllers) == 0 {
+		return true, "no controllers enabled"
+	}
+	return true, "controllers: " + strings.Join(controllers, " ")
+}
+
+func probePidfd(context.Context) (bool, string) {
+	fd, _, errno := syscall.Syscall(sysPidfdOpen, uintptr(os.Getpid()), 0, 0)
+	if errno != 0 {
+		return false, "pidfd_open: " + errno.Error()
+	}
+	syscall.Close(int(fd))
+	return true, ""
+}
+
+func probeUserNamespaces(ctx context.Context) (bool, string) {
+	if _, err := os.Stat(shimShell); err != nil {
+		return false, "no program to probe with: " + err.Error()
+	}
+	cmd := exec.CommandContext(ctx, shimShell, "-c", "true")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Cloneflags: syscall.CLONE_NEWUSER}
+	if err := cmd.Run(); err != nil {
+		return false, "cannot create a user namespace: " + err.Error()
+	}
+	return true, ""
+}
+
+func probeNetworkIsolation(context.Context) (bool, string) {
+	if !hasCapability(capSysAdmin) {
+		return false, "creating a network namespace requires CAP_SYS_ADMIN"
+	}
+	return true, ""
+}
+
+func probeSeccomp(context.Context) (bool, string) {
+	mode, ok := procStatusField("Seccomp")
+	if !ok {
+		return false, "kernel built without seccomp"
+	}
+	return true, "mode of the current process: " + mode
+}
+
+func probeLandlock(context.Context) (bool, string) {
+	abi, _, errno := syscall.Syscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	if errno != 0 {
+		return false, "landlock_create_ruleset: " + errno.Error()
+	}
+	return true, "abi version " + strconv.Itoa(int(abi))
+}
+
+// procStatusField returns the value of a field of /proc/self/status.
+func procStatusField(name string) (string, bool) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if v := strings.TrimPrefix(s.Text(), name+":"); v != s.Text() {
+			return strings.TrimSpace(v), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package execctx
+
+func platformProbes() map[string]capabilityProbe {
+	return nil
+}
@@ -0,0 +1,34 @@
+package execctx
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestDoctor(t *testing.T) {
+	r := Doctor(context.Background())
+
+	var names []string
+	for _, c := range r.Capabilities {
+		names = append(names, c.Name)
+	}
+	assert.DeepEqual(t, names, capabilityNames)
+
+	assert.Assert(t, !r.Available("no-such-capability"))
+	assert.Assert(t, !r.Available("job-objects") || runtime.GOOS == "windows")
+	for _, c := range r.Missing() {
+		assert.Assert(t, !c.Available, c)
+		assert.Assert(t, c.Detail != "", c)
+	}
+
+	if runtime.GOOS == "linux" {
+		assert.Assert(t, r.Available("shim"))
+		assert.Assert(t, r.Available("sampling"))
+		assert.Assert(t, strings.Contains(r.String(), "shim: available (/bin/sh)\n"), r.String())
+		assert.Assert(t, strings.Contains(r.String(), "conpty: unavailable (not supported on linux)"), r.String())
+	}
+}
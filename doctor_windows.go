@@ -0,0 +1,31 @@
+package execctx
+
+import (
+	"context"
+	"syscall"
+)
+
+var procCreatePseudoConsole = modkernel32.NewProc("CreatePseudoConsole")
+
+func platformProbes() map[string]capabilityProbe {
+	return map[string]capabilityProbe{
+		"job-objects": probeJobObjects,
+		"conpty":      probeConPTY,
+	}
+}
+
+func probeJobObjects(context.Context) (bool, string) {
+	r, _, err := procCreateJobObjectW.Call(0, 0)
+	if r == 0 {
+		return false, "CreateJobObjectW: " + err.Error()
+	}
+	syscall.CloseHandle(syscall.Handle(r))
+	return true, ""
+}
+
+func probeConPTY(context.Context) (bool, string) {
+	if err := procCreatePseudoConsole.Find(); err != nil {
+		return false, "requires Windows 10 1809 or later"
+	}
+	return true, ""
+}
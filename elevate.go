@@ -0,0 +1,186 @@
+package execctx
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ElevationMethod is the tool used to run a command with elevated privileges.
+type ElevationMethod int
+
+const (
+	// Sudo runs the command through sudo.
+	Sudo ElevationMethod = iota
+	// Doas runs the command through doas, as found on OpenBSD.
+	Doas
+)
+
+func (m ElevationMethod) String() string {
+	if m == Doas {
+		return "doas"
+	}
+	return "sudo"
+}
+
+// Elevation configures `WithElevation`.
+type Elevation struct {
+	Method ElevationMethod
+	// NonInteractive makes the elevation fail rather than prompt for a
+	// password.
+	NonInteractive bool
+	// User to run the command as, root if empty.
+	User string
+	// Askpass is a program which prints the password, passed to sudo through
+	// SUDO_ASKPASS. It is ignored for doas, which has no equivalent; use the
+	// pty package to answer its prompt.
+	Askpass string
+}
+
+// ElevationErrorKind classifies an `ElevationError`.
+type ElevationErrorKind int
+
+const (
+	// ElevationMissing means the elevation tool is not installed.
+	ElevationMissing ElevationErrorKind = iota
+	// ElevationNotPermitted means the user may not run the command elevated.
+	ElevationNotPermitted
+	// ElevationPasswordRequired means a password was needed, but the
+	// elevation was non-interactive.
+	ElevationPasswordRequired
+	// ElevationWrongPassword means authentication failed.
+	ElevationWrongPassword
+)
+
+func (k ElevationErrorKind) String() string {
+	switch k {
+	case ElevationMissing:
+		return "not installed"
+	case ElevationNotPermitted:
+		return "not permitted"
+	case ElevationPasswordRequired:
+		return "password required"
+	default:
+		return "wrong password"
+	}
+}
+
+// ElevationError is returned when a command could not be run with elevated
+// privileges, as opposed to the command itself failing.
+type ElevationError struct {
+	Method ElevationMethod
+	Kind   ElevationErrorKind
+	// Err is the error from starting or waiting on the elevation tool.
+	Err error
+}
+
+func (e *ElevationError) Error() string {
+	return fmt.Sprintf("execctx: %s: %s: %v", e.Method, e.Kind, e.Err)
+}
+
+func (e *ElevationError) Unwrap() error {
+	return e.Err
+}
+
+// WithElevation runs the command with elevated privileges by wrapping it in
+// sudo or doas. Failures of the elevation itself, like a missing password
+// or a user which may not use sudo, are returned as `*ElevationError` from
+// `Start` or `Wait`, so they can be told apart from failures of the command.
+//
+// The elevation tool decides which environment the command gets, sudo resets
+// it by default. Elevation is not supported on Windows.
+func WithElevation(e Elevation) Option {
+	return func(c *Cmd) {
+		c.elevation = &e
+	}
+}
+
+func (c *Cmd) setupElevation() error {
+	e := c.elevation
+	if e == nil {
+		return nil
+	}
+	if runtime.GOOS == "windows" {
+		return errors.New("execctx: elevation is not supported on windows")
+	}
+
+	tool, err := exec.LookPath(e.Method.String())
+	if err != nil {
+		return &ElevationError{Method: e.Method, Kind: ElevationMissing, Err: err}
+	}
+
+	args := []string{e.Method.String()}
+	if e.NonInteractive {
+		args = append(args, "-n")
+	}
+	if e.User != "" {
+		args = append(args, "-u", e.User)
+	}
+	if e.Askpass != "" && e.Method == Sudo {
+		args = append(args, "-A")
+	}
+	if e.Method == Sudo {
+		args = append(args, "--")
+	}
+	args = append(args, c.cmd.Path)
+	args = append(args, c.cmd.Args[1:]...)
+
+	c.cmd.Path = tool
+	c.cmd.Args = args
+	return nil
+}
+
+// elevationEnv returns the environment variables the elevation tool needs.
+func (c *Cmd) elevationEnv() []string {
+	e := c.elevation
+	if e == nil || e.Askpass == "" || e.Method != Sudo {
+		return nil
+	}
+	return []string{"SUDO_ASKPASS=" + e.Askpass}
+}
+
+// classifyElevation is a line sink which looks for failures of the elevation
+// tool on stderr.
+func (c *Cmd) classifyElevation(line []byte) {
+	prefix := c.elevation.Method.String() + ": "
+	s := string(line)
+	if !strings.HasPrefix(s, prefix) {
+		return
+	}
+	s = strings.ToLower(s)
+
+	var kind ElevationErrorKind
+	switch {
+	case strings.Contains(s, "password is required"):
+		kind = ElevationPasswordRequired
+	case strings.Contains(s, "incorrect password"), strings.Contains(s, "authentication failed"):
+		kind = ElevationWrongPassword
+	case strings.Contains(s, "not in the sudoers file"), strings.Contains(s, "not allowed to"), strings.Contains(s, "operation not permitted"):
+		kind = ElevationNotPermitted
+	default:
+		return
+	}
+
+	c.mu.Lock()
+	if c.elevationFailure == nil {
+		c.elevationFailure = &kind
+	}
+	c.mu.Unlock()
+}
+
+// elevationErr turns the error from waiting on the command into an
+// `*ElevationError` if the elevation tool reported a failure.
+func (c *Cmd) elevationErr(err error) error {
+	if err == nil || c.elevation == nil {
+		return err
+	}
+	c.mu.Lock()
+	kind := c.elevationFailure
+	c.mu.Unlock()
+	if kind == nil {
+		return err
+	}
+	return &ElevationError{Method: c.elevation.Method, Kind: *kind, Err: err}
+}
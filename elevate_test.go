@@ -0,0 +1,104 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+// fakeTool puts a fake program with the given name at the front of PATH,
+// until the returned function is called.
+func fakeTool(t *testing.T, name, script string) func() {
+	dir, err := ioutil.TempDir("", "execctx-"+name)
+	assert.NilError(t, err)
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0755))
+
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	return func() {
+		os.Setenv("PATH", path)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestElevation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("elevation is not supported on windows")
+	}
+
+	defer fakeTool(t, "sudo", `printf '%s\n' "$*"`)()
+	var out strings.Builder
+	cmd := exec.Command("echo", "hi")
+	cmd.Stdout = &out
+	c := FromCmd(context.Background(), cmd, nil, WithElevation(Elevation{NonInteractive: true, User: "nobody"}))
+	assert.NilError(t, c.Run())
+	path, _ := exec.LookPath("echo")
+	assert.Equal(t, out.String(), "-n -u nobody -- "+path+" hi\n")
+}
+
+func TestElevationAskpass(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("elevation is not supported on windows")
+	}
+
+	defer fakeTool(t, "sudo", `printf '%s\n' "$SUDO_ASKPASS"`)()
+	truePath, err := exec.LookPath("true")
+	assert.NilError(t, err)
+
+	// The hermetic environment doesn't drop the askpass helper.
+	var out strings.Builder
+	c := FromCmd(context.Background(), exec.Command("true"), nil,
+		WithStdout(&out),
+		Hermetic(filepath.Dir(truePath)),
+		WithElevation(Elevation{Askpass: "/usr/bin/ssh-askpass"}),
+	)
+	assert.NilError(t, c.Run())
+	assert.Equal(t, out.String(), "/usr/bin/ssh-askpass\n")
+}
+
+func TestElevationErrors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("elevation is not supported on windows")
+	}
+
+	for _, tc := range []struct {
+		stderr string
+		kind   ElevationErrorKind
+	}{
+		{"sudo: a password is required", ElevationPasswordRequired},
+		{"sudo: 3 incorrect password attempts", ElevationWrongPassword},
+		{"alice is not in the sudoers file.", ElevationNotPermitted},
+		{"sudo: alice is not in the sudoers file. This incident will be reported.", ElevationNotPermitted},
+	} {
+		restore := fakeTool(t, "sudo", "echo '"+tc.stderr+"' >&2; exit 1")
+		err := FromCmd(context.Background(), exec.Command("true"), nil, WithElevation(Elevation{})).Run()
+		restore()
+
+		var ee *ElevationError
+		if !strings.HasPrefix(tc.stderr, "sudo: ") {
+			// Only messages from sudo itself are considered.
+			assert.Assert(t, !errors.As(err, &ee), err)
+			continue
+		}
+		assert.Assert(t, errors.As(err, &ee), err)
+		assert.Equal(t, ee.Kind, tc.kind, tc.stderr)
+		var exitErr *exec.ExitError
+		assert.Assert(t, errors.As(err, &exitErr))
+	}
+
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", "")
+	defer os.Setenv("PATH", path)
+	err := FromCmd(context.Background(), exec.Command("true"), nil, WithElevation(Elevation{Method: Doas})).Start()
+	var ee *ElevationError
+	assert.Assert(t, errors.As(err, &ee), err)
+	assert.Equal(t, ee.Kind, ElevationMissing)
+}
@@ -0,0 +1,198 @@
+package execctx
+
+import (
+	"encoding/binary"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// InvalidPolicy controls what decoding output filters do with input which
+// is not valid in the declared encoding.
+type InvalidPolicy int
+
+const (
+	// InvalidReplace replaces invalid input with U+FFFD.
+	InvalidReplace InvalidPolicy = iota
+	// InvalidDrop drops invalid input.
+	InvalidDrop
+)
+
+// DecodeUTF16LE returns an output filter which transcodes little endian UTF-16
+// output, as produced by many Windows tools, to UTF-8.
+// A leading byte order mark is removed.
+func DecodeUTF16LE(policy InvalidPolicy) OutputFilter {
+	return func(w io.Writer) io.WriteCloser {
+		return &utf16Writer{w: w, order: binary.LittleEndian, policy: policy}
+	}
+}
+
+// DecodeUTF16BE is like `DecodeUTF16LE` but for big endian UTF-16.
+func DecodeUTF16BE(policy InvalidPolicy) OutputFilter {
+	return func(w io.Writer) io.WriteCloser {
+		return &utf16Writer{w: w, order: binary.BigEndian, policy: policy}
+	}
+}
+
+type utf16Writer struct {
+	w       io.Writer
+	order   binary.ByteOrder
+	policy  InvalidPolicy
+	pending []byte
+	started bool
+	out     []byte
+}
+
+func (u *utf16Writer) Write(p []byte) (int, error) {
+	data := append(u.pending, p...)
+	u.out = u.out[:0]
+
+	i := 0
+	for i+1 < len(data) {
+		r := rune(u.order.Uint16(data[i:]))
+
+		switch {
+		case utf16.IsSurrogate(r) && r < 0xdc00:
+			if i+3 >= len(data) {
+				// Need the low surrogate from the next write.
+				break
+			}
+			if dec := utf16.DecodeRune(r, rune(u.order.Uint16(data[i+2:]))); dec != utf8.RuneError {
+				u.emit(dec)
+				i += 4
+				continue
+			}
+			u.invalid()
+			i += 2
+			continue
+		case utf16.IsSurrogate(r):
+			u.invalid()
+			i += 2
+			continue
+		default:
+			if u.started || r != 0xfeff {
+				u.emit(r)
+			}
+			u.started = true
+			i += 2
+			continue
+		}
+		break
+	}
+
+	u.pending = append(u.pending[:0], data[i:]...)
+	if _, err := u.w.Write(u.out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (u *utf16Writer) emit(r rune) {
+	u.started = true
+	u.out = appendRune(u.out, r)
+}
+
+func (u *utf16Writer) invalid() {
+	u.started = true
+	if u.policy == InvalidReplace {
+		u.out = appendRune(u.out, utf8.RuneError)
+	}
+}
+
+// Close flushes any incomplete trailing input as invalid data.
+func (u *utf16Writer) Close() error {
+	if len(u.pending) == 0 {
+		return nil
+	}
+	u.pending = u.pending[:0]
+	u.out = u.out[:0]
+	u.invalid()
+	_, err := u.w.Write(u.out)
+	return err
+}
+
+// Charmap is a single byte encoding which is identical to ASCII for bytes
+// below 0x80.
+// It holds the runes for bytes 0x80 to 0xff, 0 marks bytes which are not
+// defined by the encoding.
+type Charmap [128]rune
+
+// DecodeCharmap returns an output filter which transcodes output in a single
+// byte encoding to UTF-8.
+// This is useful for Windows tools which write in the console or ANSI code
+// page.
+func DecodeCharmap(cm *Charmap, policy InvalidPolicy) OutputFilter {
+	return func(w io.Writer) io.WriteCloser {
+		return &charmapWriter{w: w, cm: cm, policy: policy}
+	}
+}
+
+type charmapWriter struct {
+	w      io.Writer
+	cm     *Charmap
+	policy InvalidPolicy
+	out    []byte
+}
+
+func (c *charmapWriter) Write(p []byte) (int, error) {
+	c.out = c.out[:0]
+	for _, b := range p {
+		if b < 0x80 {
+			c.out = append(c.out, b)
+			continue
+		}
+		r := c.cm[b-0x80]
+		if r == 0 {
+			if c.policy == InvalidDrop {
+				continue
+			}
+			r = utf8.RuneError
+		}
+		c.out = appendRune(c.out, r)
+	}
+	if _, err := c.w.Write(c.out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *charmapWriter) Close() error {
+	return nil
+}
+
+func appendRune(b []byte, r rune) []byte {
+	var buf [utf8.UTFMax]byte
+	n := utf8.EncodeRune(buf[:], r)
+	return append(b, buf[:n]...)
+}
+
+// Windows1252 is the Windows-1252 (Western European ANSI) code page.
+var Windows1252 = func() *Charmap {
+	cm := &Charmap{
+		0x20ac, 0, 0x201a, 0x0192, 0x201e, 0x2026, 0x2020, 0x2021,
+		0x02c6, 0x2030, 0x0160, 0x2039, 0x0152, 0, 0x017d, 0,
+		0, 0x2018, 0x2019, 0x201c, 0x201d, 0x2022, 0x2013, 0x2014,
+		0x02dc, 0x2122, 0x0161, 0x203a, 0x0153, 0, 0x017e, 0x0178,
+	}
+	// The rest of the code page matches ISO 8859-1
+	for b := 0xa0; b <= 0xff; b++ {
+		cm[b-0x80] = rune(b)
+	}
+	return cm
+}()
+
+// CodePage437 is the original IBM PC (OEM United States) code page, used by
+// the Windows console by default in many locales.
+var CodePage437 = func() *Charmap {
+	var cm Charmap
+	copy(cm[:], []rune(""+
+		"ÇüéâäàåçêëèïîìÄÅ"+
+		"ÉæÆôöòûùÿÖÜ¢£¥₧ƒ"+
+		"áíóúñÑªº¿⌐¬½¼¡«»"+
+		"░▒▓│┤╡╢╖╕╣║╗╝╜╛┐"+
+		"└┴┬├─┼╞╟╚╔╩╦╠═╬╧"+
+		"╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀"+
+		"αßΓπΣσµτΦΘΩδ∞φε∩"+
+		"≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0"))
+	return &cm
+}()
@@ -0,0 +1,46 @@
+package execctx
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestDecodeUTF16(t *testing.T) {
+	var buf bytes.Buffer
+	w := DecodeUTF16LE(InvalidReplace)(&buf)
+
+	// BOM, "hi", then U+1F600 split across writes, then a lone low surrogate
+	// and a dangling byte.
+	input := []byte{0xff, 0xfe, 'h', 0, 'i', 0, 0x3d, 0xd8, 0x00, 0xde, 0x00, 0xdc, 'x'}
+	for _, b := range input {
+		_, err := w.Write([]byte{b})
+		assert.NilError(t, err)
+	}
+	assert.NilError(t, w.Close())
+	assert.Equal(t, buf.String(), "hi\U0001F600��")
+
+	buf.Reset()
+	w = DecodeUTF16BE(InvalidDrop)(&buf)
+	w.Write([]byte{0, 'o', 0, 'k', 0xdc, 0x00})
+	w.Close()
+	assert.Equal(t, buf.String(), "ok")
+}
+
+func TestDecodeCharmap(t *testing.T) {
+	cmd := exec.Command("printf", `caf\351 \200 \201|\315`)
+	c := FromCmd(context.Background(), cmd, nil,
+		WithStdoutFilter(DecodeCharmap(Windows1252, InvalidReplace)),
+	)
+	out, err := c.Output(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "café € �|Í")
+
+	var buf bytes.Buffer
+	w := DecodeCharmap(CodePage437, InvalidDrop)(&buf)
+	w.Write([]byte{0xc9, 0xcd, 0xbb, 0xff})
+	assert.Equal(t, buf.String(), "╔═╗\u00a0")
+}
@@ -0,0 +1,81 @@
+package execctx
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// EffectiveEnv returns the environment the process will be started with.
+// This is computed without starting the process.
+//
+// If the wrapped command has no environment set, the current process's
+// environment is used, just like os/exec does. With `Hermetic` it is reduced
+// to `HermeticEnvAllowlist`. Variables injected by the package, such as with
+// `WithDeadlineEnv`, are added on top. Duplicate keys are removed, with the
+// last value winning.
+func (c *Cmd) EffectiveEnv() []string {
+	env := c.cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	if c.hermetic {
+		env = hermeticEnv(env)
+	}
+	env = append(env[:len(env):len(env)], c.injectedEnv()...)
+	return dedupEnv(env)
+}
+
+// EnvMap is like EffectiveEnv but returns the environment as a map.
+func (c *Cmd) EnvMap() map[string]string {
+	env := c.EffectiveEnv()
+	m := make(map[string]string, len(env))
+	for _, kv := range env {
+		k, v := splitEnv(kv)
+		m[k] = v
+	}
+	return m
+}
+
+func splitEnv(kv string) (string, string) {
+	// On windows there are variables like "=C:" which start with an "="
+	var start int
+	if strings.HasPrefix(kv, "=") {
+		start = 1
+	}
+	i := strings.Index(kv[start:], "=")
+	if i < 0 {
+		return kv, ""
+	}
+	i += start
+	return kv[:i], kv[i+1:]
+}
+
+// dedupEnv returns a copy of env with any duplicates removed, in favor of
+// later values.
+// Items not of the normal environment "key=value" form are preserved unchanged.
+//
+// This mirrors what os/exec does with the environment before starting the process.
+func dedupEnv(env []string) []string {
+	caseInsensitive := runtime.GOOS == "windows"
+
+	out := make([]string, 0, len(env))
+	saw := make(map[string]int, len(env)) // key => index into out
+	for _, kv := range env {
+		if !strings.Contains(kv, "=") {
+			out = append(out, kv)
+			continue
+		}
+		k, _ := splitEnv(kv)
+		if caseInsensitive {
+			k = strings.ToLower(k)
+		}
+		if dupIdx, isDup := saw[k]; isDup {
+			out[dupIdx] = kv
+			continue
+		}
+		saw[k] = len(out)
+		out = append(out, kv)
+	}
+	return out
+}
@@ -0,0 +1,34 @@
+package execctx
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestEffectiveEnv(t *testing.T) {
+	cmd := exec.Command("true")
+	cmd.Env = []string{"FOO=bar", "BAZ=qux", "FOO=baz"}
+
+	c := FromCmd(context.Background(), cmd, nil)
+	assert.DeepEqual(t, c.EffectiveEnv(), []string{"FOO=baz", "BAZ=qux"})
+	assert.DeepEqual(t, c.EnvMap(), map[string]string{"FOO": "baz", "BAZ": "qux"})
+}
+
+func TestLocaleEnv(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "date +%Z")
+	cmd.Env = []string{"LANG=de_DE.UTF-8", "LANGUAGE=de", "TZ=Europe/Berlin"}
+	c := FromCmd(context.Background(), cmd, nil, WithLocale("C.UTF-8"), WithTimezone("UTC"))
+
+	env := c.EnvMap()
+	assert.Equal(t, env["LC_ALL"], "C.UTF-8")
+	assert.Equal(t, env["LANG"], "C.UTF-8")
+	assert.Equal(t, env["LANGUAGE"], "")
+	assert.Equal(t, env["TZ"], "UTC")
+
+	out, err := c.Output(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "UTC\n")
+}
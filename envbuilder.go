@@ -0,0 +1,91 @@
+package execctx
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Env builds an environment in "key=value" form without duplicate keys,
+// which is easy to get wrong when appending to os.Environ() by hand:
+//
+//	env := execctx.EnvFrom(os.Environ()).Set("LANG", "C").Unset("DISPLAY")
+//	c := execctx.New(ctx, "make", nil, env.Option())
+//
+// Keys are case-insensitive on Windows, like os/exec treats them. The zero
+// value is an empty environment.
+type Env struct {
+	vars []string
+}
+
+// EnvFrom creates an environment from env, which is typically os.Environ().
+// Duplicate keys are removed, the last value wins.
+func EnvFrom(env []string) *Env {
+	return &Env{vars: dedupEnv(env)}
+}
+
+// Set sets key to value, keeping the position of key if it is already set.
+func (e *Env) Set(key, value string) *Env {
+	kv := key + "=" + value
+	if i := e.index(key); i >= 0 {
+		e.vars[i] = kv
+	} else {
+		e.vars = append(e.vars, kv)
+	}
+	return e
+}
+
+// Unset removes key.
+func (e *Env) Unset(key string) *Env {
+	if i := e.index(key); i >= 0 {
+		e.vars = append(e.vars[:i], e.vars[i+1:]...)
+	}
+	return e
+}
+
+// Merge sets all the variables of env, in "key=value" form, overriding
+// existing values.
+func (e *Env) Merge(env []string) *Env {
+	for _, kv := range env {
+		k, v := splitEnv(kv)
+		e.Set(k, v)
+	}
+	return e
+}
+
+// Get returns the value of key and whether it is set.
+func (e *Env) Get(key string) (string, bool) {
+	if i := e.index(key); i >= 0 {
+		_, v := splitEnv(e.vars[i])
+		return v, true
+	}
+	return "", false
+}
+
+// Environ returns a copy of the environment in "key=value" form.
+func (e *Env) Environ() []string {
+	// A non-nil empty environment, as a nil one means the current process's
+	// environment to os/exec.
+	return append([]string{}, e.vars...)
+}
+
+// Apply sets the environment of cmd.
+func (e *Env) Apply(cmd *exec.Cmd) {
+	cmd.Env = e.Environ()
+}
+
+// Option returns an option setting the environment of the command, see
+// `WithEnv`.
+func (e *Env) Option() Option {
+	return WithEnv(e.Environ())
+}
+
+func (e *Env) index(key string) int {
+	for i, kv := range e.vars {
+		k, _ := splitEnv(kv)
+		if k == key || (runtime.GOOS == "windows" && strings.EqualFold(k, key)) {
+			return i
+		}
+	}
+	return -1
+}
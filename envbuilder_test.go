@@ -0,0 +1,38 @@
+package execctx
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestEnvBuilder(t *testing.T) {
+	env := EnvFrom([]string{"A=1", "B=2", "A=3", "C=4"}).
+		Set("B", "5").
+		Set("D", "6").
+		Unset("C").
+		Merge([]string{"E=7", "A=8"})
+	assert.DeepEqual(t, env.Environ(), []string{"A=8", "B=5", "D=6", "E=7"})
+
+	v, ok := env.Get("D")
+	assert.Assert(t, ok)
+	assert.Equal(t, v, "6")
+	_, ok = env.Get("C")
+	assert.Assert(t, !ok)
+
+	// The zero value is an empty, but set, environment.
+	var empty Env
+	assert.Assert(t, empty.Environ() != nil)
+
+	cmd := exec.Command("sh")
+	env.Apply(cmd)
+	assert.DeepEqual(t, cmd.Env, env.Environ())
+
+	var out strings.Builder
+	c := FromCmd(context.Background(), exec.Command("/bin/sh", "-c", "echo $A$B$D$E"), nil, env.Option(), WithStdout(&out))
+	assert.NilError(t, c.Run())
+	assert.Equal(t, out.String(), "8567\n")
+}
@@ -0,0 +1,53 @@
+package execctx
+
+import (
+	"errors"
+)
+
+// CancelledError is returned by `Wait` when a command which was cancelled
+// exits with an error.
+type CancelledError struct {
+	// Cause is the reason the command was cancelled.
+	// When the context was cancelled this is the context's error, otherwise
+	// it is the value passed to `Cancel`.
+	Cause error
+	// Err is the error from waiting on the process.
+	Err error
+}
+
+func (e *CancelledError) Error() string {
+	return "command cancelled (" + e.Cause.Error() + "): " + e.Err.Error()
+}
+
+// Unwrap returns the error from waiting on the process.
+func (e *CancelledError) Unwrap() error {
+	return e.Err
+}
+
+// Is allows `errors.Is` to match against the cancellation cause.
+func (e *CancelledError) Is(target error) bool {
+	return errors.Is(e.Cause, target)
+}
+
+// As allows `errors.As` to match against the cancellation cause.
+func (e *CancelledError) As(target interface{}) bool {
+	return errors.As(e.Cause, target)
+}
+
+// StartCancelledError is returned by `Start` when the command was cancelled,
+// either through its context or by calling `Cancel`, before it was started.
+// The process is not started in that case, see `WithGraceBudget` to run it
+// anyway.
+type StartCancelledError struct {
+	// Cause is the reason the command was cancelled.
+	Cause error
+}
+
+func (e *StartCancelledError) Error() string {
+	return "command cancelled before start: " + e.Cause.Error()
+}
+
+// Unwrap returns the cancellation cause.
+func (e *StartCancelledError) Unwrap() error {
+	return e.Cause
+}
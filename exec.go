@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"io"
+	"os"
 	"os/exec"
 	"strconv"
+	"sync"
+	"time"
 )
 
 // Cmd wraps an os/exec.Cmd to enable custom handling of context cancellations
@@ -18,62 +22,499 @@ type Cmd struct {
 	cancel   func()
 	cmd      *exec.Cmd
 	waitDone chan struct{}
+	waitOnce sync.Once
+	waitErr  error
+	reapOnce sync.Once
+
+	cancelled  chan struct{}
+	cancelOnce sync.Once
+	cause      error
+
+	mu             sync.Mutex
+	handled        bool
+	escalated      bool
+	escalationStep int
+	frozen         bool
+	snapshot       *Spec
+	// pristine is a copy of the wrapped exec.Cmd as it was before it was
+	// prepared to be started, for `Clone`.
+	pristine         *exec.Cmd
+	elevationFailure *ElevationErrorKind
+	samples          []Sample
+
+	releasers      []func()
+	started        []func()
+	processStarted []func()
+
+	responder     *responder
+	job           uintptr
+	filterClosers []io.Closer
+
+	fileAccesses  []FileAccess
+	traceErr      error
+	programSHA256 string
+	// straced is set when the process is strace, which blocks the
+	// signals meant for the command it runs.
+	straced bool
+
+	// reapMu is held while the process is signalled. The process is only
+	// reaped with it held, once reaped is set.
+	reapMu sync.Mutex
+	reaped bool
+
+	// throttleMu guards the stops and continues of the CPU throttle.
+	throttleMu      sync.Mutex
+	throttleStopped bool
+
+	healthFile  string
+	outputLimit *outputLimit
+	tempDir     string
+	cgroupPath  string
+	// cgroupFromParent is set when the process is started in its cgroup
+	// rather than moved there by the shim.
+	cgroupFromParent bool
+	userCred         credential
+
+	hermeticTmp   string
+	degradations  []Degradation
+	lazyEnvValues []string
+
+	interception *interception
+
+	cancelFDNum   int
+	closeCancelFD func()
+
+	stderrCounts map[Severity]int
+
+	stdoutCapture capture
+	stderrCapture capture
+
+	stdoutRecent *outputTail
+	stderrRecent *outputTail
+
+	startTime time.Time
+	endTime   time.Time
+
+	ownPipes      bool
+	outputReaders []*os.File
+	outputDone    chan struct{}
+
+	config
+}
+
+// config holds the settings applied by options.
+// It is copied as-is when a command is cloned.
+type config struct {
+	cleanup        bool
+	cleanupTimeout time.Duration
+	graceBudget    time.Duration
+
+	cost      int64
+	labels    map[string]string
+	policy    *Policy
+	verifiers []Verifier
+
+	startTimeout time.Duration
+
+	handlerTimeout time.Duration
+	killAfter      time.Duration
+	onHandlerError func(*Cmd, error)
+
+	interceptors []Interceptor
+
+	preStartHooks  []func(*exec.Cmd) error
+	postStartHooks []func(*Cmd)
+	exitHooks      []func(*Cmd, Result)
+
+	onCancelHandler []func(*Cmd, error)
+	onSignal        []func(*Cmd, os.Signal)
+
+	timeoutExitCodes bool
+	exitCodeMap      map[int]int
+	exitCodeErrors   map[int]error
+
+	slowThreshold time.Duration
+	onSlow        func(*Cmd)
+	timeout       time.Duration
+
+	stdoutFilters []OutputFilter
+	stderrFilters []OutputFilter
+
+	stdoutLineFuncs []func([]byte)
+	stderrLineFuncs []func([]byte)
+
+	stdoutTees []io.Writer
+	stderrTees []io.Writer
+	outputTail int
+
+	tempDirPattern *string
+	keepTempDir    bool
+
+	dirLock     bool
+	dirLockFile string
+	dirLockMode LockMode
+
+	deadlineEnv   bool
+	healthTimeout time.Duration
+	cancelFD      bool
+	locale        string
+	timezone      string
+
+	hermetic     bool
+	hermeticPath []string
+
+	lazyArgs []lazyArg
+	lazyEnv  []lazyEnv
+
+	elevation *Elevation
+	tracer    Tracer
+	umask     *os.FileMode
+	cgroup    *CgroupSpec
+	rlimits   []Rlimit
+
+	user       string
+	initGroups bool
+
+	stderrClassifier func(string) Severity
+
+	stdoutLimit captureLimit
+	stderrLimit captureLimit
+	maxOutput   int64
+
+	noStdin     bool
+	stdinChan   <-chan []byte
+	stdinReader io.Reader
+
+	responses      []Response
+	responderLimit int
+	responderTerm  io.ReadWriter
+	responderOut   io.Writer
+
+	cpuThrottle    float64
+	sampleInterval time.Duration
+
+	group *Group
+
+	stopFiles          []string
+	stopChans          []stopChan
+	escalation         EscalationPolicy
+	adaptiveEscalation *AdaptiveEscalation
+
+	stagePolicy StagePolicy
+
+	processGroup bool
+	killTree     bool
+	jobObject    bool
 }
 
 // FromCmd wraps an os/Exec.Cmd with custom handling for when the provided
 // context is cancelled.
 // If the provided cancel function is nil, the process
 // will be killed with SIGKILL
-func FromCmd(ctx context.Context, cmd *exec.Cmd, cancel func()) *Cmd {
-	return &Cmd{ctx: ctx, cmd: cmd, cancel: cancel, waitDone: make(chan struct{})}
+//
+// Options attached to ctx with `WithOptions` are applied before opts.
+func FromCmd(ctx context.Context, cmd *exec.Cmd, cancel func(), opts ...Option) *Cmd {
+	return newCmd(ctx, cmd, cancel, nil, opts)
+}
+
+// newCmd creates the command, applying the defaults, the options from ctx,
+// and then opts.
+func newCmd(ctx context.Context, cmd *exec.Cmd, cancel func(), defaults, opts []Option) *Cmd {
+	c := allocCmd(ctx, cmd, cancel)
+	for _, o := range defaults {
+		o(c)
+	}
+	for _, o := range contextOptions(ctx) {
+		o(c)
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// allocCmd creates the command without applying any options.
+func allocCmd(ctx context.Context, cmd *exec.Cmd, cancel func()) *Cmd {
+	return &Cmd{
+		ctx:       ctx,
+		cmd:       cmd,
+		cancel:    cancel,
+		waitDone:  make(chan struct{}),
+		cancelled: make(chan struct{}),
+	}
 }
 
 // Wait waits for the command to exit
+// It is safe to call Wait multiple times, including concurrently. Every call
+// returns the same result.
+//
+// If the command was cancelled, either through the context or by calling
+// `Cancel`, and exits with an error, the error is a `*CancelledError`.
 func (c *Cmd) Wait() error {
-	err := c.cmd.Wait()
-	close(c.waitDone)
-	return err
+	if ic := c.interception; ic != nil {
+		<-ic.done
+		return ic.err
+	}
+	return c.wait()
+}
+
+func (c *Cmd) wait() error {
+	first := false
+	c.waitOnce.Do(func() {
+		first = true
+		err := c.reap()
+		c.endTime = time.Now()
+		c.waitOutput()
+		c.closeFilters()
+		c.release()
+
+		c.mu.Lock()
+		handled := c.handled
+		c.mu.Unlock()
+		if err != nil && handled {
+			err = &CancelledError{Cause: c.cause, Err: err}
+		} else {
+			err = c.elevationErr(c.mapExitError(err))
+		}
+		if err == nil {
+			err = c.outputLimit.err()
+		}
+
+		c.waitErr = err
+		unregister(c)
+		close(c.waitDone)
+	})
+	if first {
+		// Outside of waitOnce so the hooks can call Wait.
+		c.runExitHooks()
+	}
+	return c.waitErr
+}
+
+// Cancel cancels the command with the provided cause.
+// This triggers the same handling as when the context is cancelled, but the
+// cause is recorded in the error returned by `Wait`.
+// If cause is nil, `context.Canceled` is used.
+//
+// Only the first call to Cancel (or context cancellation) has any effect.
+// Calling Cancel before the command is started causes `Start` to fail with a
+// `*StartCancelledError` holding the cause.
+func (c *Cmd) Cancel(cause error) {
+	c.cancelOnce.Do(func() {
+		if cause == nil {
+			cause = context.Canceled
+		}
+		c.cause = cause
+		close(c.cancelled)
+	})
 }
 
 // Start starts the command
 func (c *Cmd) Start() error {
+	c.freeze()
+	if c.cleanup {
+		c.ctx = detachedContext{c.ctx}
+		if c.cleanupTimeout > 0 {
+			var cancel context.CancelFunc
+			c.ctx, cancel = context.WithTimeout(c.ctx, c.cleanupTimeout)
+			c.onRelease(cancel)
+		}
+	} else if c.graceBudget > 0 && c.ctx.Err() != nil {
+		var cancel context.CancelFunc
+		c.ctx, cancel = context.WithTimeout(detachedContext{c.ctx}, c.graceBudget)
+		c.onRelease(cancel)
+	}
+
+	if len(c.interceptors) > 0 {
+		return c.startIntercepted()
+	}
+	return c.startOrRelease()
+}
+
+func (c *Cmd) startOrRelease() error {
+	if err := c.start(); err != nil {
+		c.release()
+		return err
+	}
+	return nil
+}
+
+// onRelease registers a function to run once the process has exited, or if
+// it fails to start.
+func (c *Cmd) onRelease(f func()) {
+	c.releasers = append(c.releasers, f)
+}
+
+// onStarted registers a function to run right after the process is started,
+// whether that succeeded or not.
+func (c *Cmd) onStarted(f func()) {
+	c.started = append(c.started, f)
+}
+
+// onProcessStart registers a function to run once the process is running.
+// Unlike onStarted it is not called if the process fails to start.
+func (c *Cmd) onProcessStart(f func()) {
+	c.processStarted = append(c.processStarted, f)
+}
+
+func (c *Cmd) release() {
+	for i := len(c.releasers) - 1; i >= 0; i-- {
+		c.releasers[i]()
+	}
+	c.releasers = nil
+}
+
+// cancelledErr returns the error for a command which was cancelled before it
+// was started, or nil if it was not.
+func (c *Cmd) cancelledErr() error {
 	select {
 	case <-c.ctx.Done():
-		return c.ctx.Err()
+		return &StartCancelledError{Cause: c.ctx.Err()}
+	case <-c.cancelled:
+		return &StartCancelledError{Cause: c.cause}
 	default:
+		return nil
 	}
+}
 
-	if err := c.cmd.Start(); err != nil {
+func (c *Cmd) start() error {
+	c.checkStopTriggers()
+	if err := c.cancelledErr(); err != nil {
+		return err
+	}
+
+	if err := c.resolveLazy(); err != nil {
+		return err
+	}
+	if err := c.resolveHermeticPath(); err != nil {
+		return err
+	}
+	if err := c.preStart(); err != nil {
+		return err
+	}
+	if err := c.setupTempDir(); err != nil {
+		return err
+	}
+	if err := c.lockDir(); err != nil {
+		return err
+	}
+	if err := c.runPreStartHooks(); err != nil {
 		return err
 	}
 
+	if err := c.setupHermetic(); err != nil {
+		return err
+	}
+	if err := c.setupCgroup(); err != nil {
+		return err
+	}
+	if err := c.setupShim(); err != nil {
+		return err
+	}
+	if err := c.setupTracer(); err != nil {
+		return err
+	}
+	if err := c.setupElevation(); err != nil {
+		return err
+	}
+	if err := c.setupUser(); err != nil {
+		return err
+	}
+	if err := c.setupHealthFile(); err != nil {
+		return err
+	}
+	if err := c.setupChildEnv(); err != nil {
+		return err
+	}
+	c.setupNoStdin()
+	c.setupSampling()
+	c.setupProcessGroup()
+	if err := c.setupThrottle(); err != nil {
+		return err
+	}
+	if err := c.setupResponder(); err != nil {
+		return err
+	}
+	if err := c.setupStdinFeed(); err != nil {
+		return err
+	}
+	c.applyFilters()
+	c.limitOutput()
+	if err := c.pipeOutput(); err != nil {
+		return err
+	}
+	c.recordSnapshot()
+	err := c.startProcess()
+	for _, f := range c.started {
+		f()
+	}
+	c.started = nil
+	if err != nil {
+		return err
+	}
+	if err := c.assignJob(); err != nil {
+		c.cmd.Process.Kill()
+		c.cmd.Wait()
+		return err
+	}
+	c.startTime = time.Now()
+	register(c)
+	for _, f := range c.processStarted {
+		f()
+	}
+	c.processStarted = nil
+	c.runPostStartHooks()
+	c.watchSlow()
+	c.watchTimeout()
+	c.watchStopTriggers()
+
 	go func() {
 		select {
 		case <-c.ctx.Done():
-			if c.cancel == nil {
-				c.cmd.Process.Kill()
-				return
-			}
-			c.cancel()
+			c.Cancel(c.ctx.Err())
+		case <-c.cancelled:
 		case <-c.waitDone:
+			return
 		}
+
+		c.mu.Lock()
+		c.handled = true
+		c.mu.Unlock()
+
+		if c.closeCancelFD != nil {
+			c.closeCancelFD()
+		}
+		c.runCancelHandler()
 	}()
 
 	return nil
 }
 
+// preStart runs any checks which must pass before the process is started.
+func (c *Cmd) preStart() error {
+	if err := c.checkPolicies(); err != nil {
+		return err
+	}
+	return c.runVerifiers()
+}
+
 // Run starts the command and waits for it to exit
 func (c *Cmd) Run() error {
 	err := c.Start()
 	if err != nil {
-		return nil
+		return err
 	}
 
-	return c.cmd.Wait()
+	return c.Wait()
 }
 
 // CombinedOutput runs the command, waits for it to exit, and returns
 // the combined stdout and stderr of the command.
+//
+// The output is returned even when the command fails, including when it is
+// cancelled or times out. In that case it holds everything the command wrote
+// before it exited, alongside the error from `Wait` (such as a
+// `*CancelledError`).
 func (c *Cmd) CombinedOutput() ([]byte, error) {
 	if c.cmd.Stdout != nil {
 		return nil, errors.New("exec: Stdout already set")
@@ -81,31 +522,59 @@ func (c *Cmd) CombinedOutput() ([]byte, error) {
 	if c.cmd.Stderr != nil {
 		return nil, errors.New("exec: Stderr already set")
 	}
+	c.savePristine()
 	var b bytes.Buffer
 	c.cmd.Stdout = &b
 	c.cmd.Stderr = &b
+	if c.stdoutLimit.limit > 0 || c.stderrLimit.limit > 0 {
+		// The streams are limited separately, so they need their own writers
+		// which means they may write concurrently.
+		w := &lockedWriter{w: &b}
+		c.cmd.Stdout = w
+		c.cmd.Stderr = w
+		if c.stdoutLimit.limit > 0 {
+			c.stdoutCapture = &headCapture{n: c.stdoutLimit.limit, w: w}
+			c.cmd.Stdout = c.stdoutCapture
+		}
+		if c.stderrLimit.limit > 0 {
+			c.stderrCapture = &headCapture{n: c.stderrLimit.limit, w: w}
+			c.cmd.Stderr = c.stderrCapture
+		}
+	}
+	c.ownPipes = true
 	err := c.Run()
 	return b.Bytes(), err
 }
 
 // Output runs the command, waits for it to exit, and returns the
 // stdout of the command.
+//
+// Like `CombinedOutput`, the stdout written so far is returned even when the
+// command fails or is cancelled.
 func (c *Cmd) Output(ctx context.Context) ([]byte, error) {
 	if c.cmd.Stdout != nil {
 		return nil, errors.New("exec: Stdout already set")
 	}
-	var stdout bytes.Buffer
-	c.cmd.Stdout = &stdout
+	c.savePristine()
+	stdout := newCapture(c.stdoutLimit)
+	c.stdoutCapture = stdout
+	c.cmd.Stdout = stdout
 
-	captureErr := c.cmd.Stderr == nil
-	if captureErr {
-		c.cmd.Stderr = &prefixSuffixSaver{N: 32 << 10}
+	if c.cmd.Stderr == nil {
+		if c.stderrLimit.limit > 0 {
+			c.stderrCapture = newCapture(c.stderrLimit)
+		} else {
+			c.stderrCapture = &headTailCapture{prefixSuffixSaver{N: 32 << 10}}
+		}
+		c.cmd.Stderr = c.stderrCapture
 	}
 
+	c.ownPipes = true
 	err := c.Run()
-	if err != nil && captureErr {
-		if ee, ok := err.(*exec.ExitError); ok {
-			ee.Stderr = c.cmd.Stderr.(*prefixSuffixSaver).Bytes()
+	if err != nil && c.stderrCapture != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) {
+			ee.Stderr = c.stderrCapture.Bytes()
 		}
 	}
 	return stdout.Bytes(), err
@@ -2,8 +2,10 @@ package execctx
 
 import (
 	"context"
+	"errors"
 	"io"
 	"os/exec"
+	"strings"
 	"testing"
 	"time"
 
@@ -23,7 +25,7 @@ func TestNilHandler(t *testing.T) {
 }
 
 func TestCustomHandler(t *testing.T) {
-	cmd := exec.Command("/bin/sh", "-c", "cat -; sleep 99999")
+	cmd := exec.Command("/bin/sh", "-c", "cat -; exec sleep 99999")
 
 	stdinR, stdinW := io.Pipe()
 	defer stdinW.Close()
@@ -78,3 +80,80 @@ func TestCustomHandler(t *testing.T) {
 	}
 	<-handlerDone
 }
+
+func TestCancelWithCause(t *testing.T) {
+	cmd := exec.Command("sleep", "99999")
+
+	c := FromCmd(context.Background(), cmd, nil)
+	assert.NilError(t, c.Start())
+
+	cause := errors.New("superseded by newer job")
+	c.Cancel(cause)
+
+	err := c.Wait()
+	assert.Assert(t, errors.Is(err, cause), err)
+
+	var ee *exec.ExitError
+	assert.Assert(t, errors.As(err, &ee), err)
+
+	var ce *CancelledError
+	assert.Assert(t, errors.As(err, &ce), err)
+	assert.Equal(t, ce.Cause, cause)
+
+	c = FromCmd(context.Background(), exec.Command("true"), nil)
+	c.Cancel(cause)
+	err = c.Start()
+	var sce *StartCancelledError
+	assert.Assert(t, errors.As(err, &sce), err)
+	assert.Equal(t, sce.Cause, cause)
+}
+
+func TestLabels(t *testing.T) {
+	c := FromCmd(context.Background(), exec.Command("true"), nil,
+		WithLabels(map[string]string{"job": "build", "team": "a"}),
+		WithLabels(map[string]string{"team": "b"}),
+	)
+
+	labels := c.Labels()
+	assert.DeepEqual(t, labels, map[string]string{"job": "build", "team": "b"})
+
+	labels["job"] = "changed"
+	assert.Equal(t, c.Labels()["job"], "build")
+}
+
+func TestPartialOutputOnCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	c := FromCmd(ctx, exec.Command("sh", "-c", "echo hello; echo world >&2; exec sleep 99999"), nil)
+	out, err := c.CombinedOutput()
+	assert.Assert(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.Assert(t, strings.Contains(string(out), "hello\n"), string(out))
+	assert.Assert(t, strings.Contains(string(out), "world\n"), string(out))
+
+	ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	c = FromCmd(ctx, exec.Command("sh", "-c", "echo hello; echo world >&2; exec sleep 99999"), nil)
+	out, err = c.Output(ctx)
+	assert.Assert(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.Equal(t, string(out), "hello\n")
+
+	var ee *exec.ExitError
+	assert.Assert(t, errors.As(err, &ee), err)
+	assert.Equal(t, string(ee.Stderr), "world\n")
+}
+
+func TestOutputReturnsPromptlyOnCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// The backgrounded sleep keeps stdout open after the shell is killed.
+	c := FromCmd(ctx, exec.Command("sh", "-c", "echo hello; sleep 5 & wait"), nil)
+
+	start := time.Now()
+	out, err := c.Output(ctx)
+	assert.Assert(t, time.Since(start) < 3*time.Second)
+	assert.Assert(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.Equal(t, string(out), "hello\n")
+}
@@ -0,0 +1,158 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// Exit codes used by `WithTimeoutExitCodes`, matching GNU timeout(1).
+const (
+	// ExitCodeTimeout is the exit code of a command stopped because its
+	// deadline expired.
+	ExitCodeTimeout = 124
+	// ExitCodeTimeoutKilled is the exit code of a command killed with
+	// SIGKILL because its deadline expired.
+	ExitCodeTimeoutKilled = 128 + 9
+)
+
+// WithTimeoutExitCodes makes `ExitCode` follow the conventions of GNU
+// timeout(1). This is useful when replacing timeout(1) with execctx in tools
+// whose callers interpret those exit codes.
+//
+// When the command is stopped because the context deadline expired the exit
+// code is `ExitCodeTimeout`, or `ExitCodeTimeoutKilled` if the process was
+// killed with SIGKILL. A process which still exits successfully keeps its
+// exit code of 0, matching the nil error from `Wait`.
+// When the process is terminated by a signal for any other reason the exit
+// code is 128 plus the signal number.
+func WithTimeoutExitCodes() Option {
+	return func(c *Cmd) {
+		c.timeoutExitCodes = true
+	}
+}
+
+// WithExitCodeMap remaps the exit codes of the command.
+// This is useful to normalize tools with unusual exit code conventions, for
+// instance rsync or robocopy, into codes meaningful to the caller.
+//
+// The mapped code is returned by `ExitCode`. If a non-zero exit code is mapped
+// to 0, `Wait` returns nil.
+// The map is applied after `WithTimeoutExitCodes`.
+func WithExitCodeMap(m map[int]int) Option {
+	return func(c *Cmd) {
+		c.exitCodeMap = m
+	}
+}
+
+// WithExitCodeErrors maps exit codes to errors.
+// When the command exits with one of the codes, after any mapping from
+// `WithExitCodeMap`, `Wait` returns an `*ExitCodeError` holding the mapped
+// error.
+func WithExitCodeErrors(m map[int]error) Option {
+	return func(c *Cmd) {
+		c.exitCodeErrors = m
+	}
+}
+
+// ExitCodeError is returned by `Wait` when the exit code of the command is
+// mapped to an error with `WithExitCodeErrors`.
+type ExitCodeError struct {
+	// Code is the exit code, after mapping.
+	Code int
+	// Err is the error the exit code is mapped to.
+	Err error
+	// ExitErr is the original error from waiting on the process, if any.
+	ExitErr error
+}
+
+func (e *ExitCodeError) Error() string {
+	return fmt.Sprintf("%v (exit code %d)", e.Err, e.Code)
+}
+
+// Unwrap returns the original error from waiting on the process.
+func (e *ExitCodeError) Unwrap() error {
+	return e.ExitErr
+}
+
+// Is allows `errors.Is` to match against the mapped error.
+func (e *ExitCodeError) Is(target error) bool {
+	return errors.Is(e.Err, target)
+}
+
+// ExitCode returns the exit code of the exited process.
+// It returns -1 if the process has not exited, or was terminated by a signal
+// and `WithTimeoutExitCodes` is not set.
+func (c *Cmd) ExitCode() int {
+	code := c.exitCode()
+	if mapped, ok := c.exitCodeMap[code]; ok {
+		return mapped
+	}
+	return code
+}
+
+// mapExitError applies the exit code mappings to the error returned from
+// waiting on the process.
+func (c *Cmd) mapExitError(err error) error {
+	if len(c.exitCodeMap) == 0 && len(c.exitCodeErrors) == 0 {
+		return err
+	}
+	if c.cmd.ProcessState == nil {
+		return err
+	}
+	var ee *exec.ExitError
+	if err != nil && !errors.As(err, &ee) {
+		// Not an error about the exit status, for instance copying output
+		// failed.
+		return err
+	}
+
+	code := c.ExitCode()
+	if named, ok := c.exitCodeErrors[code]; ok {
+		return &ExitCodeError{Code: code, Err: named, ExitErr: err}
+	}
+	if code == 0 {
+		return nil
+	}
+	return err
+}
+
+func (c *Cmd) exitCode() int {
+	ps := c.cmd.ProcessState
+	if ps == nil {
+		return -1
+	}
+	if !c.timeoutExitCodes {
+		return ps.ExitCode()
+	}
+
+	c.mu.Lock()
+	handled := c.handled
+	c.mu.Unlock()
+
+	sig, signaled := exitSignal(c)
+	if handled && errors.Is(c.cause, context.DeadlineExceeded) && !ps.Success() {
+		if signaled && sig == syscall.SIGKILL {
+			return ExitCodeTimeoutKilled
+		}
+		return ExitCodeTimeout
+	}
+	if signaled {
+		return 128 + int(sig)
+	}
+	return ps.ExitCode()
+}
+
+// exitSignal returns the signal which terminated the process, if any.
+func exitSignal(c *Cmd) (syscall.Signal, bool) {
+	ws, ok := c.cmd.ProcessState.Sys().(interface {
+		Signaled() bool
+		Signal() syscall.Signal
+	})
+	if !ok || !ws.Signaled() {
+		return 0, false
+	}
+	return ws.Signal(), true
+}
@@ -0,0 +1,70 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestTimeoutExitCodes(t *testing.T) {
+	c := FromCmd(context.Background(), exec.Command("sh", "-c", "exit 3"), nil, WithTimeoutExitCodes())
+	assert.Assert(t, c.ExitCode() == -1)
+	c.Run()
+	assert.Equal(t, c.ExitCode(), 3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	c = FromCmd(ctx, exec.Command("sleep", "99999"), nil, WithTimeoutExitCodes())
+	c.Run()
+	assert.Equal(t, c.ExitCode(), ExitCodeTimeoutKilled)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	cmd := exec.Command("sleep", "99999")
+	c = FromCmd(ctx, cmd, func() { cmd.Process.Signal(os.Interrupt) }, WithTimeoutExitCodes())
+	c.Run()
+	assert.Equal(t, c.ExitCode(), ExitCodeTimeout)
+
+	// A process which exits successfully after the deadline keeps its exit
+	// code.
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	cmd = exec.Command("sh", "-c", `trap "exit 0" INT; sleep 10 & wait`)
+	c = FromCmd(ctx, cmd, func() { cmd.Process.Signal(os.Interrupt) }, WithTimeoutExitCodes())
+	assert.NilError(t, c.Run())
+	assert.Equal(t, c.ExitCode(), 0)
+
+	cmd = exec.Command("sleep", "99999")
+	c = FromCmd(context.Background(), cmd, nil, WithTimeoutExitCodes())
+	assert.NilError(t, c.Start())
+	cmd.Process.Signal(os.Interrupt)
+	c.Wait()
+	assert.Equal(t, c.ExitCode(), 130)
+}
+
+func TestExitCodeMap(t *testing.T) {
+	errPartial := errors.New("partial transfer")
+	opts := []Option{
+		WithExitCodeMap(map[int]int{1: 0, 23: 2}),
+		WithExitCodeErrors(map[int]error{2: errPartial}),
+	}
+
+	c := FromCmd(context.Background(), exec.Command("sh", "-c", "exit 1"), nil, opts...)
+	assert.NilError(t, c.Run())
+	assert.Equal(t, c.ExitCode(), 0)
+
+	c = FromCmd(context.Background(), exec.Command("sh", "-c", "exit 23"), nil, opts...)
+	err := c.Run()
+	assert.Assert(t, errors.Is(err, errPartial), err)
+	var ee *exec.ExitError
+	assert.Assert(t, errors.As(err, &ee), err)
+	assert.Equal(t, c.ExitCode(), 2)
+
+	c = FromCmd(context.Background(), exec.Command("sh", "-c", "exit 4"), nil, opts...)
+	assert.ErrorContains(t, c.Run(), "exit status 4")
+}
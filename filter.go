@@ -0,0 +1,392 @@
+package execctx
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// OutputFilter transforms the output of a command before it is written to
+// the stdout or stderr writer set on the command.
+//
+// The filter is passed the writer to send its output to and returns the
+// writer the command output is written to.
+// The returned writer is closed after the process exits so it can flush any
+// buffered data. Closing it must not close w.
+type OutputFilter func(w io.Writer) io.WriteCloser
+
+// WithStdoutFilter adds filters for the stdout of the command.
+// Filters are applied in the order they are passed, the first filter
+// receives the raw output of the command.
+// Filters are only applied if stdout is set to a non-nil writer.
+func WithStdoutFilter(filters ...OutputFilter) Option {
+	return func(c *Cmd) {
+		c.stdoutFilters = append(c.stdoutFilters, filters...)
+	}
+}
+
+// WithStderrFilter is like `WithStdoutFilter` but for stderr.
+func WithStderrFilter(filters ...OutputFilter) Option {
+	return func(c *Cmd) {
+		c.stderrFilters = append(c.stderrFilters, filters...)
+	}
+}
+
+// OnStdoutLine calls f with every line the command writes to stdout, while
+// the command runs. This works whether or not stdout is set on the command.
+//
+// Lines are passed without the line ending, after any stdout filters are
+// applied. Lines longer than 64KiB are split. The slice is only valid until
+// f returns. f is called from the goroutine copying the output, so a slow f
+// slows down the command once the pipe buffer is full.
+// This may be passed multiple times.
+func OnStdoutLine(f func(line []byte)) Option {
+	return func(c *Cmd) {
+		c.stdoutLineFuncs = append(c.stdoutLineFuncs, f)
+	}
+}
+
+// OnStderrLine is like `OnStdoutLine` but for stderr.
+func OnStderrLine(f func(line []byte)) Option {
+	return func(c *Cmd) {
+		c.stderrLineFuncs = append(c.stderrLineFuncs, f)
+	}
+}
+
+// applyFilters wraps the stdio writers of the command with the configured
+// filters.
+// Line sinks see the output after it went through the filters.
+func (c *Cmd) applyFilters() {
+	stdoutSinks, stderrSinks := c.lineSinks()
+	stdoutTee, stderrTee := c.teeWriters()
+	if len(c.stdoutFilters) == 0 && len(c.stderrFilters) == 0 && len(stdoutSinks) == 0 && len(stderrSinks) == 0 && (c.responder == nil || c.responder.term) && stdoutTee == nil && stderrTee == nil {
+		return
+	}
+
+	stdout, stderr := c.cmd.Stdout, c.cmd.Stderr
+	if stdout != nil && interfaceEqual(stdout, stderr) {
+		// os/exec guarantees only one goroutine writes at a time when stdout
+		// and stderr are the same. Once filtered they are no longer the same,
+		// so keep that guarantee here.
+		stdout = &lockedWriter{w: stdout}
+		stderr = stdout
+	}
+
+	// Line writers must be flushed after the filters in front of them.
+	var lineClosers []io.Closer
+	if len(stdoutSinks) > 0 {
+		lw := &lineWriter{sinks: stdoutSinks}
+		stdout = multiWriter(stdout, lw)
+		lineClosers = append(lineClosers, lw)
+	}
+	if len(stderrSinks) > 0 {
+		lw := &lineWriter{sinks: stderrSinks}
+		stderr = multiWriter(stderr, lw)
+		lineClosers = append(lineClosers, lw)
+	}
+
+	if stdoutTee != nil {
+		stdout = multiWriter(stdout, stdoutTee)
+	}
+	if stderrTee != nil {
+		stderr = multiWriter(stderr, stderrTee)
+	}
+
+	if c.responder != nil && !c.responder.term {
+		stdout = multiWriter(stdout, c.responder)
+		stderr = multiWriter(stderr, c.responder)
+	}
+
+	if stdout != nil {
+		c.cmd.Stdout = c.wrapFilters(stdout, c.stdoutFilters)
+	}
+	if stderr != nil {
+		c.cmd.Stderr = c.wrapFilters(stderr, c.stderrFilters)
+	}
+	c.filterClosers = append(c.filterClosers, lineClosers...)
+}
+
+// lineSinks returns the functions which are passed every line of output of
+// the command.
+func (c *Cmd) lineSinks() (stdout, stderr []func([]byte)) {
+	stdout = append(stdout, c.stdoutLineFuncs...)
+	stderr = append(stderr, c.stderrLineFuncs...)
+	if c.stderrClassifier != nil {
+		stderr = append(stderr, c.classifyStderr)
+	}
+	if c.elevation != nil {
+		stderr = append(stderr, c.classifyElevation)
+	}
+	return stdout, stderr
+}
+
+func multiWriter(w, other io.Writer) io.Writer {
+	if w == nil {
+		return other
+	}
+	return io.MultiWriter(w, other)
+}
+
+// maxLineLength is the size at which lineWriter passes on an incomplete line.
+const maxLineLength = 64 << 10
+
+// lineWriter splits its input into lines and passes them to each sink,
+// without the line ending.
+type lineWriter struct {
+	sinks []func([]byte)
+	buf   []byte
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			l.buf = append(l.buf, p...)
+			if len(l.buf) >= maxLineLength {
+				l.flush()
+			}
+			break
+		}
+		l.buf = append(l.buf, p[:i]...)
+		l.flush()
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+func (l *lineWriter) flush() {
+	line := bytes.TrimSuffix(l.buf, []byte{'\r'})
+	for _, sink := range l.sinks {
+		sink(line)
+	}
+	l.buf = l.buf[:0]
+}
+
+// Close passes on the final line if it did not end with a newline.
+func (l *lineWriter) Close() error {
+	if len(l.buf) > 0 {
+		l.flush()
+	}
+	return nil
+}
+
+func (c *Cmd) wrapFilters(w io.Writer, filters []OutputFilter) io.Writer {
+	closers := make([]io.Closer, len(filters))
+	for i := len(filters) - 1; i >= 0; i-- {
+		wc := filters[i](w)
+		closers[i] = wc
+		w = wc
+	}
+	// Close the outermost filter first so its buffered data is flushed
+	// through the filters after it.
+	c.filterClosers = append(c.filterClosers, closers...)
+	return w
+}
+
+func (c *Cmd) closeFilters() {
+	for _, f := range c.filterClosers {
+		f.Close()
+	}
+	c.filterClosers = nil
+}
+
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// interfaceEqual protects against panics from doing equality tests on
+// two interfaces with non-comparable underlying types.
+//
+// This is copied from stdlib os/exec
+func interfaceEqual(a, b interface{}) bool {
+	defer func() {
+		recover()
+	}()
+	return a == b
+}
+
+// StripANSI returns an output filter which removes ANSI escape sequences,
+// such as colors and cursor movement, from the output.
+func StripANSI() OutputFilter {
+	return func(w io.Writer) io.WriteCloser {
+		return &ansiWriter{w: w}
+	}
+}
+
+const (
+	ansiNormal = iota
+	ansiEscape
+	ansiCharset
+	ansiCSI
+	ansiString
+	ansiStringEscape
+)
+
+type ansiWriter struct {
+	w     io.Writer
+	state int
+	out   []byte
+}
+
+func (a *ansiWriter) Write(p []byte) (int, error) {
+	a.out = a.out[:0]
+	for _, b := range p {
+		switch a.state {
+		case ansiNormal:
+			if b == 0x1b {
+				a.state = ansiEscape
+				continue
+			}
+			a.out = append(a.out, b)
+		case ansiEscape:
+			switch b {
+			case '[':
+				a.state = ansiCSI
+			case ']', 'P', 'X', '^', '_':
+				a.state = ansiString
+			case '(', ')', '*', '+':
+				a.state = ansiCharset
+			default:
+				a.state = ansiNormal
+			}
+		case ansiCharset:
+			a.state = ansiNormal
+		case ansiCSI:
+			if b >= 0x40 && b <= 0x7e {
+				a.state = ansiNormal
+			}
+		case ansiString:
+			switch b {
+			case 0x07:
+				a.state = ansiNormal
+			case 0x1b:
+				a.state = ansiStringEscape
+			}
+		case ansiStringEscape:
+			if b == '\\' {
+				a.state = ansiNormal
+			} else {
+				a.state = ansiString
+			}
+		}
+	}
+
+	if _, err := a.w.Write(a.out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (a *ansiWriter) Close() error {
+	return nil
+}
+
+// NormalizeCRLF returns an output filter which replaces "\r\n" line endings
+// with "\n".
+func NormalizeCRLF() OutputFilter {
+	return func(w io.Writer) io.WriteCloser {
+		return &crlfWriter{w: w}
+	}
+}
+
+type crlfWriter struct {
+	w         io.Writer
+	pendingCR bool
+	out       []byte
+}
+
+func (c *crlfWriter) Write(p []byte) (int, error) {
+	c.out = c.out[:0]
+	for _, b := range p {
+		if c.pendingCR {
+			c.pendingCR = false
+			if b != '\n' {
+				c.out = append(c.out, '\r')
+			}
+		}
+		if b == '\r' {
+			c.pendingCR = true
+			continue
+		}
+		c.out = append(c.out, b)
+	}
+
+	if _, err := c.w.Write(c.out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *crlfWriter) Close() error {
+	if !c.pendingCR {
+		return nil
+	}
+	c.pendingCR = false
+	_, err := c.w.Write([]byte{'\r'})
+	return err
+}
+
+// CollapseCR returns an output filter which collapses lines rewritten with
+// carriage returns, like progress bars, into their final content.
+// "\r\n" line endings are treated as "\n".
+//
+// Output is buffered until the end of each line.
+func CollapseCR() OutputFilter {
+	return func(w io.Writer) io.WriteCloser {
+		return &collapseCRWriter{w: w}
+	}
+}
+
+type collapseCRWriter struct {
+	w         io.Writer
+	line      []byte
+	pendingCR bool
+	out       []byte
+}
+
+func (c *collapseCRWriter) Write(p []byte) (int, error) {
+	c.out = c.out[:0]
+	for _, b := range p {
+		if c.pendingCR {
+			c.pendingCR = false
+			if b != '\n' {
+				c.line = c.line[:0]
+			}
+		}
+
+		switch b {
+		case '\r':
+			c.pendingCR = true
+		case '\n':
+			c.out = append(c.out, c.line...)
+			c.out = append(c.out, '\n')
+			c.line = c.line[:0]
+		default:
+			c.line = append(c.line, b)
+		}
+	}
+
+	if len(c.out) > 0 {
+		if _, err := c.w.Write(c.out); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (c *collapseCRWriter) Close() error {
+	if len(c.line) == 0 {
+		return nil
+	}
+	_, err := c.w.Write(c.line)
+	c.line = c.line[:0]
+	return err
+}
@@ -0,0 +1,62 @@
+package execctx
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func writeEach(t *testing.T, w io.WriteCloser, s string) {
+	t.Helper()
+	for i := 0; i < len(s); i++ {
+		_, err := w.Write([]byte{s[i]})
+		assert.NilError(t, err)
+	}
+	assert.NilError(t, w.Close())
+}
+
+func TestStripANSI(t *testing.T) {
+	var buf bytes.Buffer
+	writeEach(t, StripANSI()(&buf), "\x1b[1;31mred\x1b[0m \x1b]0;title\x07ok\x1b(B\x1b[m\x1b]8;;http://x\x1b\\!")
+	assert.Equal(t, buf.String(), "red ok!")
+}
+
+func TestNormalizeCRLF(t *testing.T) {
+	var buf bytes.Buffer
+	writeEach(t, NormalizeCRLF()(&buf), "a\r\nb\rc\r")
+	assert.Equal(t, buf.String(), "a\nb\rc\r")
+}
+
+func TestCollapseCR(t *testing.T) {
+	var buf bytes.Buffer
+	writeEach(t, CollapseCR()(&buf), "start\r\n10%\r50%\r100%\ndone\r")
+	assert.Equal(t, buf.String(), "start\n100%\ndone")
+}
+
+func TestFiltersCombined(t *testing.T) {
+	cmd := exec.Command("sh", "-c", `printf '\033[32m10%%\r100%%\033[0m\r\n'; printf 'err\r\n' >&2`)
+	c := FromCmd(context.Background(), cmd, nil,
+		WithStdoutFilter(StripANSI(), CollapseCR()),
+		WithStderrFilter(NormalizeCRLF()),
+	)
+
+	out, err := c.CombinedOutput()
+	assert.NilError(t, err)
+	assert.Assert(t, string(out) == "100%\nerr\n" || string(out) == "err\n100%\n", string(out))
+}
+
+func TestOnLine(t *testing.T) {
+	var stdout, stderr []string
+	cmd := exec.Command("sh", "-c", `printf 'one\ntwo\r\n'; echo err >&2; printf three`)
+	c := FromCmd(context.Background(), cmd, nil,
+		OnStdoutLine(func(line []byte) { stdout = append(stdout, string(line)) }),
+		OnStderrLine(func(line []byte) { stderr = append(stderr, string(line)) }),
+	)
+	assert.NilError(t, c.Run())
+	assert.DeepEqual(t, stdout, []string{"one", "two", "three"})
+	assert.DeepEqual(t, stderr, []string{"err"})
+}
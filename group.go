@@ -0,0 +1,178 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrGroupClosed is the cause the commands left in a group are cancelled
+// with when it is closed, see `Group.Close`.
+var ErrGroupClosed = errors.New("execctx: group closed")
+
+// Group is a set of commands, and nested groups, which are cancelled as a
+// unit. Cancelling a group cancels all of its commands and nested groups
+// with the same cause, without affecting other groups which share the same
+// parent context.
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	parent *Group
+
+	mu       sync.Mutex
+	cause    error
+	cmds     map[*Cmd]struct{}
+	children map[*Group]struct{}
+}
+
+// NewGroupContext creates a group which is cancelled when parent is done.
+// In that case the cause is the error of parent.
+//
+// Like a context from context.WithCancel, the group holds resources until it
+// is cancelled or parent is done. Call `Group.Close` once it is no longer
+// needed.
+func NewGroupContext(parent context.Context) *Group {
+	return newGroup(parent, nil)
+}
+
+func newGroup(parent context.Context, pg *Group) *Group {
+	ctx, cancel := context.WithCancel(parent)
+	g := &Group{
+		ctx:      ctx,
+		cancel:   cancel,
+		parent:   pg,
+		cmds:     make(map[*Cmd]struct{}),
+		children: make(map[*Group]struct{}),
+	}
+	go func() {
+		<-ctx.Done()
+		g.Cancel(ctx.Err())
+	}()
+	return g
+}
+
+// NewGroup creates a group nested in g. It is cancelled along with g, but
+// can also be cancelled on its own.
+func (g *Group) NewGroup() *Group {
+	child := newGroup(g.ctx, g)
+
+	g.mu.Lock()
+	cause := g.cause
+	if cause == nil {
+		g.children[child] = struct{}{}
+	}
+	g.mu.Unlock()
+
+	if cause != nil {
+		child.Cancel(cause)
+	}
+	return child
+}
+
+// Context returns a context which is cancelled when the group is cancelled.
+func (g *Group) Context() context.Context {
+	return g.ctx
+}
+
+// Err returns the cause the group was cancelled with, or nil if it was not
+// cancelled.
+func (g *Group) Err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.cause
+}
+
+// Cancel cancels all commands and nested groups in the group with the
+// provided cause. If cause is nil, `context.Canceled` is used.
+// Commands added to the group after it is cancelled are cancelled right away.
+//
+// Only the first call to Cancel has any effect.
+func (g *Group) Cancel(cause error) {
+	if cause == nil {
+		cause = context.Canceled
+	}
+
+	g.mu.Lock()
+	if g.cause != nil {
+		g.mu.Unlock()
+		return
+	}
+	g.cause = cause
+	cmds := g.cmds
+	children := g.children
+	g.cmds = nil
+	g.children = nil
+	g.mu.Unlock()
+
+	for c := range cmds {
+		c.Cancel(cause)
+	}
+	for child := range children {
+		child.Cancel(cause)
+	}
+	// Cancel the context only after the members, so they see the cause
+	// rather than the error of the context.
+	g.cancel()
+
+	if g.parent != nil {
+		g.parent.mu.Lock()
+		delete(g.parent.children, g)
+		g.parent.mu.Unlock()
+	}
+}
+
+// Close releases the resources of the group and its nested groups once they
+// are no longer needed. Commands still in them are cancelled with
+// `ErrGroupClosed`, so the group is best closed once they exited.
+// Closing a group which was already cancelled has no effect.
+func (g *Group) Close() {
+	g.Cancel(ErrGroupClosed)
+}
+
+// WithGroup adds the command to the group, so it is cancelled when the group
+// is cancelled. The command is removed from the group once it has exited.
+// Commands which are never started stay in the group until it is cancelled.
+func WithGroup(g *Group) Option {
+	return func(c *Cmd) {
+		c.group = g
+		g.add(c)
+	}
+}
+
+func (g *Group) add(c *Cmd) {
+	g.mu.Lock()
+	cause := g.cause
+	if cause == nil {
+		g.cmds[c] = struct{}{}
+	}
+	g.mu.Unlock()
+
+	if cause != nil {
+		c.Cancel(cause)
+		return
+	}
+	c.onRelease(func() {
+		g.mu.Lock()
+		delete(g.cmds, c)
+		g.mu.Unlock()
+	})
+}
+
+// members returns the commands in the group and its nested groups.
+func (g *Group) members() []*Cmd {
+	g.mu.Lock()
+	cmds := make([]*Cmd, 0, len(g.cmds))
+	for c := range g.cmds {
+		cmds = append(cmds, c)
+	}
+	children := make([]*Group, 0, len(g.children))
+	for child := range g.children {
+		children = append(children, child)
+	}
+	g.mu.Unlock()
+
+	for _, child := range children {
+		cmds = append(cmds, child.members()...)
+	}
+	return cmds
+}
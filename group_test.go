@@ -0,0 +1,77 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestGroupCancel(t *testing.T) {
+	parent := NewGroupContext(context.Background())
+	g1 := parent.NewGroup()
+	g2 := parent.NewGroup()
+
+	c1 := FromCmd(context.Background(), exec.Command("sleep", "99999"), nil, WithGroup(g1))
+	c2 := FromCmd(context.Background(), exec.Command("sleep", "99999"), nil, WithGroup(g2))
+	assert.NilError(t, c1.Start())
+	assert.NilError(t, c2.Start())
+	defer c2.Cancel(nil)
+
+	cause := errors.New("reload")
+	g1.Cancel(cause)
+	err := c1.Wait()
+	assert.Assert(t, errors.Is(err, cause), err)
+	assert.Assert(t, errors.Is(g1.Err(), cause))
+	assert.NilError(t, g2.Err())
+	assert.NilError(t, parent.Err())
+
+	// Commands added after the group is cancelled never start.
+	c3 := FromCmd(context.Background(), exec.Command("true"), nil, WithGroup(g1))
+	assert.Assert(t, errors.Is(c3.Start(), cause))
+}
+
+func TestGroupCancelNested(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	parent := NewGroupContext(ctx)
+	child := parent.NewGroup().NewGroup()
+
+	c := FromCmd(context.Background(), exec.Command("sleep", "99999"), nil, WithGroup(child))
+	assert.NilError(t, c.Start())
+
+	cancel()
+	err := c.Wait()
+	assert.Assert(t, errors.Is(err, context.Canceled), err)
+	<-child.Context().Done()
+	assert.Assert(t, errors.Is(child.Err(), context.Canceled))
+}
+
+func TestGroupNewGroupAfterCancel(t *testing.T) {
+	g := NewGroupContext(context.Background())
+	cause := errors.New("stop")
+	g.Cancel(cause)
+
+	child := g.NewGroup()
+	assert.Assert(t, errors.Is(child.Err(), cause))
+}
+
+func TestGroupClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		g := NewGroupContext(context.Background())
+		g.NewGroup()
+		g.Close()
+		assert.Assert(t, errors.Is(g.Err(), ErrGroupClosed))
+		assert.Assert(t, g.Context().Err() != nil)
+	}
+
+	// The goroutines watching the groups exit.
+	for i := 0; i < 100 && runtime.NumGoroutine() > before+10; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Assert(t, runtime.NumGoroutine() <= before+10, runtime.NumGoroutine())
+}
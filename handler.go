@@ -0,0 +1,133 @@
+package execctx
+
+import (
+	"fmt"
+	"time"
+)
+
+// HandlerError is reported when a cancel handler panics or does not return
+// within the timeout set with `WithCancelHandlerTimeout`.
+// In both cases the process is killed.
+type HandlerError struct {
+	// Panic is the value the handler panicked with, if it panicked.
+	Panic interface{}
+	// Timeout is set if the handler did not return in time.
+	Timeout time.Duration
+}
+
+func (e *HandlerError) Error() string {
+	if e.Timeout > 0 {
+		return fmt.Sprintf("execctx: cancel handler did not return within %s", e.Timeout)
+	}
+	return fmt.Sprintf("execctx: cancel handler panicked: %v", e.Panic)
+}
+
+// WithCancelHandlerTimeout bounds how long the cancel handler may run.
+// If the handler does not return in time the process is killed. The handler
+// itself keeps running in the background.
+func WithCancelHandlerTimeout(d time.Duration) Option {
+	return func(c *Cmd) {
+		c.handlerTimeout = d
+	}
+}
+
+// OnCancelHandler adds a function which is called with the cancellation
+// cause when the command is cancelled, right before its cancel handler runs.
+func OnCancelHandler(f func(c *Cmd, cause error)) Option {
+	return func(c *Cmd) {
+		c.onCancelHandler = append(c.onCancelHandler, f)
+	}
+}
+
+// WithKillAfter kills the process if it is still running d after the cancel
+// handler returned. Without it, a handler which only asks the process to exit
+// leaves it running for as long as the process likes.
+func WithKillAfter(d time.Duration) Option {
+	return func(c *Cmd) {
+		c.killAfter = d
+	}
+}
+
+// OnCancelHandlerError sets a function which is called when the cancel
+// handler panics or times out, see `HandlerError`.
+func OnCancelHandlerError(f func(*Cmd, error)) Option {
+	return func(c *Cmd) {
+		c.onHandlerError = f
+	}
+}
+
+// runCancelHandler runs the cancel handler, falling back to killing the
+// process if the handler panics or takes too long.
+// A panicking handler never takes down the process.
+func (c *Cmd) runCancelHandler() {
+	for _, f := range c.onCancelHandler {
+		f(c, c.cause)
+	}
+
+	handler := c.cancel
+	if len(c.escalation) > 0 {
+		handler = c.escalate
+	}
+	if handler == nil {
+		c.kill()
+		return
+	}
+
+	done := make(chan interface{}, 1)
+	go func() {
+		panicked := true
+		defer func() {
+			if !panicked {
+				done <- nil
+				return
+			}
+			p := recover()
+			if p == nil {
+				p = "nil"
+			}
+			done <- p
+		}()
+		handler()
+		panicked = false
+	}()
+
+	var timeout <-chan time.Time
+	if c.handlerTimeout > 0 {
+		timer := time.NewTimer(c.handlerTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case p := <-done:
+		if p == nil {
+			c.killAfterHandler()
+			return
+		}
+		err = &HandlerError{Panic: p}
+	case <-timeout:
+		err = &HandlerError{Timeout: c.handlerTimeout}
+	}
+
+	if c.onHandlerError != nil {
+		c.onHandlerError(c, err)
+	}
+	c.kill()
+}
+
+// killAfterHandler kills the process if it does not exit within the time set
+// with `WithKillAfter`.
+func (c *Cmd) killAfterHandler() {
+	if c.killAfter <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(c.killAfter)
+	defer timer.Stop()
+	select {
+	case <-c.waitDone:
+	case <-timer.C:
+		c.kill()
+	}
+}
@@ -0,0 +1,61 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestCancelHandlerPanic(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errCh := make(chan error, 1)
+	c := FromCmd(ctx, exec.Command("sleep", "99999"), func() {
+		panic("oops")
+	}, OnCancelHandlerError(func(_ *Cmd, err error) {
+		errCh <- err
+	}))
+	assert.NilError(t, c.Start())
+	cancel()
+
+	assert.ErrorContains(t, c.Wait(), "killed")
+	var he *HandlerError
+	assert.Assert(t, errors.As(<-errCh, &he))
+	assert.Equal(t, he.Panic, "oops")
+}
+
+func TestCancelHandlerTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	block := make(chan struct{})
+	defer close(block)
+
+	errCh := make(chan error, 1)
+	c := FromCmd(ctx, exec.Command("sleep", "99999"), func() {
+		<-block
+	}, WithCancelHandlerTimeout(10*time.Millisecond), OnCancelHandlerError(func(_ *Cmd, err error) {
+		errCh <- err
+	}))
+	assert.NilError(t, c.Start())
+	cancel()
+
+	assert.ErrorContains(t, c.Wait(), "killed")
+	assert.ErrorContains(t, <-errCh, "did not return within")
+}
+
+func TestKillAfter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// The handler returns without stopping the process.
+	c := FromCmd(ctx, exec.Command("sleep", "99999"), func() {}, WithKillAfter(10*time.Millisecond))
+	assert.NilError(t, c.Start())
+	cancel()
+
+	err := c.Wait()
+	assert.ErrorContains(t, err, "killed")
+	assert.Assert(t, errors.Is(err, context.Canceled))
+}
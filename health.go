@@ -0,0 +1,91 @@
+package execctx
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cpuguy83/execctx/child"
+)
+
+// ErrUnhealthy is the cancellation cause for commands which did not update
+// their health file in time, see `WithHealthFile`.
+var ErrUnhealthy = errors.New("execctx: health file not updated in time")
+
+// WithHealthFile passes the process the path of a health file in the
+// `child.EnvHealthFile` environment variable. The process must keep updating
+// the modification time of the file, with `child.Healthy` or simply
+// `touch "$EXECCTX_HEALTH_FILE"` from a shell script.
+// If the file is not updated for longer than timeout the command is
+// cancelled with `ErrUnhealthy`. The timeout starts once the process is
+// started.
+//
+// This is a liveness check for children which can't easily keep a heartbeat
+// pipe open, combined with a `Supervisor` hung processes are restarted.
+func WithHealthFile(timeout time.Duration) Option {
+	return func(c *Cmd) {
+		c.healthTimeout = timeout
+	}
+}
+
+func (c *Cmd) setupHealthFile() error {
+	if c.healthTimeout <= 0 {
+		return nil
+	}
+
+	dir, err := ioutil.TempDir("", "execctx-health")
+	if err != nil {
+		return fmt.Errorf("execctx: error creating health file: %w", err)
+	}
+	c.onRelease(func() { os.RemoveAll(dir) })
+	c.healthFile = filepath.Join(dir, "health")
+	if err := ioutil.WriteFile(c.healthFile, nil, 0600); err != nil {
+		return fmt.Errorf("execctx: error creating health file: %w", err)
+	}
+	if err := c.chownForUser(dir, c.healthFile); err != nil {
+		return err
+	}
+
+	c.onProcessStart(func() {
+		// The process gets the full timeout from when it started.
+		now := time.Now()
+		os.Chtimes(c.healthFile, now, now)
+		go c.watchHealthFile()
+	})
+	return nil
+}
+
+func (c *Cmd) healthEnv() []string {
+	if c.healthFile == "" {
+		return nil
+	}
+	return []string{child.EnvHealthFile + "=" + c.healthFile}
+}
+
+func (c *Cmd) watchHealthFile() {
+	interval := c.healthTimeout / 4
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.cancelled:
+			return
+		case <-c.waitDone:
+			return
+		}
+
+		fi, err := os.Stat(c.healthFile)
+		if err != nil || time.Since(fi.ModTime()) > c.healthTimeout {
+			c.Cancel(ErrUnhealthy)
+			return
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestHealthFile(t *testing.T) {
+	t.Run("unhealthy", func(t *testing.T) {
+		ctx := context.Background()
+
+		// Touches the file a couple of times, then hangs.
+		cmd := exec.Command("sh", "-c", `touch "$EXECCTX_HEALTH_FILE"; sleep 0.1; touch "$EXECCTX_HEALTH_FILE"; sleep 10`)
+		c := FromCmd(ctx, cmd, nil, WithHealthFile(300*time.Millisecond))
+
+		start := time.Now()
+		err := c.Run()
+		assert.Assert(t, errors.Is(err, ErrUnhealthy), err)
+		assert.Assert(t, time.Since(start) < 5*time.Second)
+
+		// The health file is removed once the command is done.
+		_, err = os.Stat(c.healthFile)
+		assert.Assert(t, os.IsNotExist(err), err)
+	})
+
+	t.Run("healthy", func(t *testing.T) {
+		ctx := context.Background()
+
+		cmd := exec.Command("sh", "-c", `for i in 1 2 3 4 5 6; do touch "$EXECCTX_HEALTH_FILE"; sleep 0.1; done`)
+		c := FromCmd(ctx, cmd, nil, WithHealthFile(500*time.Millisecond))
+		assert.NilError(t, c.Run())
+	})
+}
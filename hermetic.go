@@ -0,0 +1,135 @@
+package execctx
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// HermeticEnvAllowlist holds the variables passed through from the
+// environment of the command by `Hermetic`. All other variables are dropped.
+var HermeticEnvAllowlist = []string{"HOME", "USER", "LOGNAME", "SYSTEMROOT"}
+
+// Degradation describes a part of an option which could not be applied on
+// the current platform.
+type Degradation struct {
+	// Feature is the name of the part which was not applied, e.g. "network".
+	Feature string
+	Reason  string
+}
+
+func (d Degradation) String() string {
+	return d.Feature + ": " + d.Reason
+}
+
+// Hermetic makes the command behave reproducibly, independent of the host
+// it runs on:
+//   - The environment is cleared except for `HermeticEnvAllowlist`.
+//   - The locale is C.UTF-8 and the timezone UTC.
+//   - PATH only holds toolDirs and the program is looked up there.
+//   - The process gets a private temporary directory through TMPDIR (TMP and
+//     TEMP on Windows), which is removed once it exits.
+//   - The umask is 022, see `WithUmask`. This is not supported on Windows.
+//   - The process has no network access. This is only supported on Linux,
+//     with CAP_SYS_ADMIN.
+//
+// `Degradations` reports the parts which were skipped once the command is
+// started.
+func Hermetic(toolDirs ...string) Option {
+	return func(c *Cmd) {
+		c.hermetic = true
+		c.hermeticPath = append([]string(nil), toolDirs...)
+		WithLocale("C.UTF-8")(c)
+		WithTimezone("UTC")(c)
+		WithUmask(0022)(c)
+	}
+}
+
+// Degradations returns the parts of `Hermetic` which could not be applied
+// when the command was started.
+func (c *Cmd) Degradations() []Degradation {
+	return append([]Degradation(nil), c.degradations...)
+}
+
+func (c *Cmd) degrade(feature, reason string) {
+	c.degradations = append(c.degradations, Degradation{Feature: feature, Reason: reason})
+}
+
+// resolveHermeticPath looks the program up in the tool dirs of `Hermetic`.
+// This must happen before the policies and verifiers run, so they check
+// the binary which is actually executed.
+func (c *Cmd) resolveHermeticPath() error {
+	if !c.hermetic {
+		return nil
+	}
+	if name := c.cmd.Args[0]; !strings.ContainsAny(name, `/\`) {
+		path, err := lookPathIn(name, c.hermeticPath)
+		if err != nil {
+			return err
+		}
+		c.cmd.Path = path
+		clearLookPathErr(c.cmd)
+	}
+	return nil
+}
+
+func (c *Cmd) setupHermetic() error {
+	if !c.hermetic {
+		return nil
+	}
+
+	dir, err := ioutil.TempDir("", "execctx-")
+	if err != nil {
+		return fmt.Errorf("execctx: error creating private temp dir: %w", err)
+	}
+	c.hermeticTmp = dir
+	c.onRelease(func() { os.RemoveAll(dir) })
+
+	isolateNetwork(c)
+	return nil
+}
+
+// hermeticEnv returns the variables of env which are in the allowlist.
+func hermeticEnv(env []string) []string {
+	out := make([]string, 0, len(HermeticEnvAllowlist))
+	for _, kv := range env {
+		k, _ := splitEnv(kv)
+		for _, allowed := range HermeticEnvAllowlist {
+			if k == allowed || (runtime.GOOS == "windows" && strings.EqualFold(k, allowed)) {
+				out = append(out, kv)
+				break
+			}
+		}
+	}
+	return out
+}
+
+func (c *Cmd) hermeticInjectedEnv() []string {
+	if !c.hermetic {
+		return nil
+	}
+	env := []string{"PATH=" + strings.Join(c.hermeticPath, string(os.PathListSeparator))}
+	if c.hermeticTmp != "" {
+		if runtime.GOOS == "windows" {
+			env = append(env, "TMP="+c.hermeticTmp, "TEMP="+c.hermeticTmp)
+		} else {
+			env = append(env, "TMPDIR="+c.hermeticTmp)
+		}
+	}
+	return env
+}
+
+// lookPathIn is like exec.LookPath but only searches dirs.
+func lookPathIn(name string, dirs []string) (string, error) {
+	for _, dir := range dirs {
+		path, err := exec.LookPath(filepath.Join(dir, name))
+		if err == nil {
+			return path, nil
+		}
+	}
+	return "", &exec.Error{Name: name, Err: exec.ErrNotFound}
+}
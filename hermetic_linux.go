@@ -0,0 +1,46 @@
+package execctx
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const capSysAdmin = 21
+
+func isolateNetwork(c *Cmd) {
+	if !hasCapability(capSysAdmin) {
+		c.degrade("network", "creating a network namespace requires CAP_SYS_ADMIN")
+		return
+	}
+	if c.cmd.SysProcAttr == nil {
+		c.cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	c.cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNET
+}
+
+// hasCapability reports whether the current process has the capability in
+// its effective set.
+func hasCapability(capability uint) bool {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if !strings.HasPrefix(line, "CapEff:") {
+			continue
+		}
+		caps, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "CapEff:")), 16, 64)
+		if err != nil {
+			return false
+		}
+		return caps&(1<<capability) != 0
+	}
+	return false
+}
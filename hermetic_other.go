@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package execctx
+
+import (
+	"runtime"
+)
+
+func isolateNetwork(c *Cmd) {
+	c.degrade("network", "not supported on "+runtime.GOOS)
+}
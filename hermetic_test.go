@@ -0,0 +1,95 @@
+package execctx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestHermetic(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a posix shell")
+	}
+
+	cmd := exec.Command("sh", "-c", `echo "$PATH|$FOO|$LC_ALL|$TZ|$(umask)"; test -d "$TMPDIR" && echo "$TMPDIR"`)
+	cmd.Env = []string{"FOO=bar", "HOME=/home/test", "PATH=/nonexistent"}
+	c := FromCmd(context.Background(), cmd, nil, Hermetic("/bin", "/usr/bin"))
+	assert.Equal(t, c.EnvMap()["HOME"], "/home/test")
+
+	out, err := c.Output(context.Background())
+	assert.NilError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	assert.Equal(t, len(lines), 2)
+	assert.Equal(t, lines[0], "/bin:/usr/bin||C.UTF-8|UTC|0022")
+
+	// The private temp dir is removed once the command exits.
+	_, err = os.Stat(lines[1])
+	assert.Assert(t, os.IsNotExist(err), err)
+
+	for _, d := range c.Degradations() {
+		assert.Assert(t, d.Feature != "umask", d)
+	}
+}
+
+func TestHermeticProgramNotInToolDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "execctx-hermetic")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := FromCmd(context.Background(), exec.Command("sh", "-c", "true"), nil, Hermetic(dir))
+	assert.Assert(t, errors.Is(c.Start(), exec.ErrNotFound))
+}
+
+func TestHermeticToolNotOnPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a posix shell")
+	}
+
+	dir, err := ioutil.TempDir("", "execctx-hermetic")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "execctx-mytool"), []byte("#!/bin/sh\necho mytool\n"), 0755))
+
+	// The tool is only in the tool dir, not on the host PATH.
+	_, err = exec.LookPath("execctx-mytool")
+	assert.Assert(t, err != nil)
+
+	c := FromCmd(context.Background(), exec.Command("execctx-mytool"), nil, Hermetic(dir))
+	out, err := c.Output(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "mytool\n")
+}
+
+func TestHermeticVerifiesToolPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a posix shell")
+	}
+
+	dir, err := ioutil.TempDir("", "execctx-hermetic")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "echo"), []byte("#!/bin/sh\necho EVIL\n"), 0755))
+
+	// The checksum is the one of the host's echo, the hermetic one differs.
+	host, err := exec.LookPath("echo")
+	assert.NilError(t, err)
+	data, err := ioutil.ReadFile(host)
+	assert.NilError(t, err)
+	sum := sha256.Sum256(data)
+
+	c := FromCmd(context.Background(), exec.Command("echo", "hi"), nil, Hermetic(dir), WithExpectedChecksum(hex.EncodeToString(sum[:])))
+	out, err := c.Output(context.Background())
+	var ce *ChecksumError
+	assert.Assert(t, errors.As(err, &ce), err)
+	assert.Equal(t, string(out), "")
+}
@@ -0,0 +1,73 @@
+package execctx
+
+import (
+	"os/exec"
+)
+
+// OnPreStart adds a hook which is called with the wrapped exec.Cmd before
+// the process is started, after policies and verifiers have passed. The
+// hook may modify the command, for example to add to its environment.
+// If it returns an error the command is not started and `Start` returns the
+// error.
+//
+// Hooks run in the order they were added.
+func OnPreStart(f func(*exec.Cmd) error) Option {
+	return func(c *Cmd) {
+		c.preStartHooks = append(c.preStartHooks, f)
+	}
+}
+
+// OnPostStart adds a hook which is called with the pid of the process once
+// it has started.
+func OnPostStart(f func(pid int)) Option {
+	return OnStarted(func(c *Cmd) { f(c.cmd.Process.Pid) })
+}
+
+// OnStarted is like `OnPostStart` but passes the started command, which may
+// be a clone of the one the option was applied to.
+func OnStarted(f func(*Cmd)) Option {
+	return func(c *Cmd) {
+		c.postStartHooks = append(c.postStartHooks, f)
+	}
+}
+
+// OnExit adds a hook which is called with the `Result` of the command once
+// it has exited and been waited on. Captured output is included when the
+// command was run with `Output` or `RunResult`.
+//
+// The hook is called from the first call to `Wait`, before it returns.
+func OnExit(f func(Result)) Option {
+	return exitHook(func(_ *Cmd, r Result) { f(r) })
+}
+
+// exitHook is like `OnExit` but also passes the command which exited.
+func exitHook(f func(*Cmd, Result)) Option {
+	return func(c *Cmd) {
+		c.exitHooks = append(c.exitHooks, f)
+	}
+}
+
+func (c *Cmd) runPreStartHooks() error {
+	for _, f := range c.preStartHooks {
+		if err := f(c.cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cmd) runPostStartHooks() {
+	for _, f := range c.postStartHooks {
+		f(c)
+	}
+}
+
+func (c *Cmd) runExitHooks() {
+	if len(c.exitHooks) == 0 || c.cmd.Process == nil {
+		return
+	}
+	r := c.Result()
+	for _, f := range c.exitHooks {
+		f(c, r)
+	}
+}
@@ -0,0 +1,49 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestHooks(t *testing.T) {
+	var (
+		events []string
+		pid    int
+		result Result
+	)
+	c := FromCmd(context.Background(), exec.Command("sh", "-c", `echo "$HOOK"; exit 2`), nil,
+		OnPreStart(func(cmd *exec.Cmd) error {
+			events = append(events, "pre")
+			cmd.Env = append(os.Environ(), "HOOK=set")
+			return nil
+		}),
+		OnPostStart(func(p int) {
+			events = append(events, "post")
+			pid = p
+		}),
+		OnExit(func(r Result) {
+			events = append(events, "exit")
+			result = r
+		}),
+	)
+
+	out, err := c.Output(context.Background())
+	assert.ErrorContains(t, err, "exit status 2")
+	assert.Equal(t, string(out), "set\n")
+	assert.DeepEqual(t, events, []string{"pre", "post", "exit"})
+	assert.Equal(t, pid, c.Pid())
+	assert.Equal(t, result.ExitCode, 2)
+	assert.Equal(t, string(result.Stdout), "set\n")
+
+	errHook := errors.New("no")
+	c = FromCmd(context.Background(), exec.Command("true"), nil,
+		OnPreStart(func(*exec.Cmd) error { return errHook }),
+		OnExit(func(Result) { t.Fatal("exit hook called for a command which did not start") }),
+	)
+	assert.Equal(t, c.Run(), errHook)
+}
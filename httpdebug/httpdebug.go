@@ -0,0 +1,130 @@
+// Package httpdebug serves a debug page listing the commands currently run
+// through execctx, in the style of net/http/pprof.
+//
+// Since the page can cancel commands, nothing is registered on import. Mount
+// it explicitly on a mux which is only reachable by operators:
+//
+//	mux := http.NewServeMux()
+//	httpdebug.Register(mux)
+//
+// The page lists every running command with its PID, uptime, arguments,
+// labels, and the end of its output for commands run with
+// `execctx.WithOutputTail`. Posting to "/debug/execctx/cancel?pid=<pid>"
+// cancels a command through its normal cancellation handling. Append
+// "?format=json" to the listing to get it as JSON.
+package httpdebug
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cpuguy83/execctx"
+)
+
+// ErrCancelled is the cause used for commands cancelled from the debug page.
+var ErrCancelled = errors.New("cancelled from debug handler")
+
+type command struct {
+	PID        int               `json:"pid"`
+	Started    time.Time         `json:"started"`
+	Uptime     string            `json:"uptime"`
+	Command    string            `json:"command"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	StdoutTail string            `json:"stdout_tail,omitempty"`
+	StderrTail string            `json:"stderr_tail,omitempty"`
+}
+
+// Register mounts the debug handler on mux under "/debug/execctx/".
+func Register(mux *http.ServeMux) {
+	mux.Handle("/debug/execctx/", Handler())
+}
+
+// Handler returns the debug handler.
+// It expects to be mounted at "/debug/execctx/", see `Register`.
+func Handler() http.Handler {
+	return http.HandlerFunc(serve)
+}
+
+func serve(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/cancel") {
+		cancel(w, r)
+		return
+	}
+
+	now := time.Now()
+	running := execctx.Running()
+	cmds := make([]command, 0, len(running))
+	for _, c := range running {
+		stdout, stderr := c.OutputTail()
+		cmds = append(cmds, command{
+			PID:        c.Pid(),
+			Started:    c.StartTime(),
+			Uptime:     now.Sub(c.StartTime()).Round(time.Second).String(),
+			Command:    c.String(),
+			Labels:     c.Labels(),
+			StdoutTail: string(stdout),
+			StderrTail: string(stderr),
+		})
+	}
+
+	if r.FormValue("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(cmds)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := page.Execute(w, cmds); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func cancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	pid, err := strconv.Atoi(r.FormValue("pid"))
+	if err != nil {
+		http.Error(w, "invalid pid", http.StatusBadRequest)
+		return
+	}
+
+	for _, c := range execctx.Running() {
+		if c.Pid() == pid {
+			c.Cancel(ErrCancelled)
+			http.Redirect(w, r, "./", http.StatusSeeOther)
+			return
+		}
+	}
+	http.Error(w, "no such command", http.StatusNotFound)
+}
+
+var page = template.Must(template.New("").Parse(`<!DOCTYPE html>
+<html>
+<head><title>execctx commands</title></head>
+<body>
+<h1>Running commands</h1>
+<table border="1" cellpadding="4">
+<tr><th>PID</th><th>Uptime</th><th>Command</th><th>Labels</th><th>Stdout</th><th>Stderr</th><th></th></tr>
+{{range .}}<tr>
+<td>{{.PID}}</td>
+<td>{{.Uptime}}</td>
+<td><code>{{.Command}}</code></td>
+<td>{{range $k, $v := .Labels}}{{$k}}={{$v}} {{end}}</td>
+<td>{{with .StdoutTail}}<pre>{{.}}</pre>{{end}}</td>
+<td>{{with .StderrTail}}<pre>{{.}}</pre>{{end}}</td>
+<td><form method="post" action="cancel?pid={{.PID}}"><button>Cancel</button></form></td>
+</tr>
+{{else}}<tr><td colspan="7">No commands running</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
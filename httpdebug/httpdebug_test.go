@@ -0,0 +1,66 @@
+package httpdebug
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cpuguy83/execctx"
+	"gotest.tools/v3/assert"
+)
+
+func TestHandler(t *testing.T) {
+	c := execctx.FromCmd(context.Background(), exec.Command("sh", "-c", "echo ready; echo oops >&2; exec sleep 99999"), nil,
+		execctx.WithLabels(map[string]string{"job": "test"}), execctx.WithOutputTail(1024))
+	assert.NilError(t, c.Start())
+
+	mux := http.NewServeMux()
+	Register(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// Nothing is registered on the default mux.
+	_, pattern := http.DefaultServeMux.Handler(httptest.NewRequest(http.MethodGet, "/debug/execctx/", nil))
+	assert.Equal(t, pattern, "")
+
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if stdout, stderr := c.OutputTail(); len(stdout) > 0 && len(stderr) > 0 {
+			break
+		}
+	}
+
+	resp, err := http.Get(srv.URL + "/debug/execctx/?format=json")
+	assert.NilError(t, err)
+	var cmds []command
+	assert.NilError(t, json.NewDecoder(resp.Body).Decode(&cmds))
+	resp.Body.Close()
+
+	var found bool
+	for _, cmd := range cmds {
+		if cmd.PID == c.Pid() {
+			found = true
+			assert.Equal(t, cmd.Labels["job"], "test")
+			assert.Equal(t, cmd.StdoutTail, "ready\n")
+			assert.Equal(t, cmd.StderrTail, "oops\n")
+		}
+	}
+	assert.Assert(t, found, cmds)
+
+	resp, err = http.Get(srv.URL + "/debug/execctx/")
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+	resp, err = http.Post(srv.URL+"/debug/execctx/cancel?pid="+strconv.Itoa(c.Pid()), "", nil)
+	assert.NilError(t, err)
+	resp.Body.Close()
+
+	err = c.Wait()
+	assert.Assert(t, errors.Is(err, ErrCancelled), err)
+}
@@ -0,0 +1,96 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Interceptor wraps the execution of commands, see `WithInterceptors`.
+type Interceptor interface {
+	// Run is called when the command is started. Calling next starts the
+	// process and waits for it to exit, returning the error `Wait` would
+	// otherwise return. Returning without calling next prevents the process
+	// from starting.
+	//
+	// ctx is the context the command was created with.
+	Run(ctx context.Context, c *Cmd, next func() error) error
+}
+
+// InterceptorFunc adapts a function to an `Interceptor`.
+type InterceptorFunc func(ctx context.Context, c *Cmd, next func() error) error
+
+// Run calls f.
+func (f InterceptorFunc) Run(ctx context.Context, c *Cmd, next func() error) error {
+	return f(ctx, c, next)
+}
+
+// ErrNotRun is returned from `Start`, and then `Wait`, when the interceptors
+// returned without error but none of them called next, so the process was
+// never started.
+var ErrNotRun = errors.New("execctx: interceptor did not run the command")
+
+// WithInterceptors wraps the execution of the command in the interceptors,
+// for cross-cutting concerns like authorization checks, tracing, or rate
+// limiting. The first interceptor is the outermost one.
+//
+// `Start` returns once the process is started, or with the error from the
+// interceptors if they do not start it. `Wait` returns the error from the
+// interceptors once they return.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(c *Cmd) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+type interception struct {
+	done chan struct{}
+	err  error
+}
+
+func (c *Cmd) startIntercepted() error {
+	started := make(chan error, 1)
+	var once sync.Once
+	next := func() error {
+		err := errors.New("execctx: command already run by an interceptor")
+		once.Do(func() {
+			err = c.startOrRelease()
+			started <- err
+			if err == nil {
+				err = c.wait()
+			}
+		})
+		return err
+	}
+
+	ic := &interception{done: make(chan struct{})}
+	c.interception = ic
+	go func() {
+		h := next
+		for i := len(c.interceptors) - 1; i >= 0; i-- {
+			h = intercept(c, c.interceptors[i], h)
+		}
+		err := h()
+		once.Do(func() {
+			if err == nil {
+				err = ErrNotRun
+			}
+			started <- err
+		})
+		ic.err = err
+		close(ic.done)
+	}()
+
+	if err := <-started; err != nil {
+		// Let the interceptors have the final say, they may wrap the error.
+		<-ic.done
+		return ic.err
+	}
+	return nil
+}
+
+func intercept(c *Cmd, i Interceptor, next func() error) func() error {
+	return func() error {
+		return i.Run(c.ctx, c, next)
+	}
+}
@@ -0,0 +1,48 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestInterceptors(t *testing.T) {
+	var events []string
+	trace := func(name string) Interceptor {
+		return InterceptorFunc(func(ctx context.Context, c *Cmd, next func() error) error {
+			events = append(events, name+" before")
+			err := next()
+			events = append(events, name+" after")
+			if err != nil {
+				return errors.New(name + ": " + err.Error())
+			}
+			return nil
+		})
+	}
+
+	c := FromCmd(context.Background(), exec.Command("false"), nil, WithInterceptors(trace("outer"), trace("inner")))
+	assert.NilError(t, c.Start())
+	assert.Error(t, c.Wait(), "outer: inner: exit status 1")
+	assert.DeepEqual(t, events, []string{"outer before", "inner before", "inner after", "outer after"})
+
+	// Interceptors can refuse to run the command.
+	errDenied := errors.New("denied")
+	deny := InterceptorFunc(func(context.Context, *Cmd, func() error) error {
+		return errDenied
+	})
+	c = FromCmd(context.Background(), exec.Command("true"), nil, WithInterceptors(deny))
+	assert.Equal(t, c.Start(), errDenied)
+
+	skip := InterceptorFunc(func(context.Context, *Cmd, func() error) error {
+		return nil
+	})
+	c = FromCmd(context.Background(), exec.Command("true"), nil, WithInterceptors(skip))
+	assert.Assert(t, errors.Is(c.Start(), ErrNotRun))
+
+	// Start errors go through the interceptors.
+	c = FromCmd(context.Background(), exec.Command("/nonexistent"), nil, WithInterceptors(trace("outer")))
+	assert.ErrorContains(t, c.Run(), "outer: ")
+}
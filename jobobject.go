@@ -0,0 +1,16 @@
+package execctx
+
+// WithJobObject assigns the process to a Windows Job Object, so killing the
+// command, such as when it is cancelled, terminates the entire process tree
+// rather than only the process itself. The job is configured to kill any
+// remaining processes in it once the command has exited, so descendants
+// never outlive the command.
+//
+// Processes the child starts before it is assigned to the job, right after
+// it is started, are not part of the job.
+// On other platforms this has no effect, see `WithProcessGroup`.
+func WithJobObject() Option {
+	return func(c *Cmd) {
+		c.jobObject = true
+	}
+}
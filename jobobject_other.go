@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package execctx
+
+func (c *Cmd) assignJob() error {
+	return nil
+}
+
+func (c *Cmd) killJob() (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,107 @@
+package execctx
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	modkernel32                  = syscall.NewLazyDLL("kernel32.dll")
+	procCreateJobObjectW         = modkernel32.NewProc("CreateJobObjectW")
+	procSetInformationJobObject  = modkernel32.NewProc("SetInformationJobObject")
+	procAssignProcessToJobObject = modkernel32.NewProc("AssignProcessToJobObject")
+	procTerminateJobObject       = modkernel32.NewProc("TerminateJobObject")
+)
+
+const (
+	jobObjectExtendedLimitInformationClass = 9
+	jobObjectLimitKillOnJobClose           = 0x2000
+
+	processSetQuota  = 0x0100
+	processTerminate = 0x0001
+)
+
+type jobObjectBasicLimitInformation struct {
+	PerProcessUserTimeLimit int64
+	PerJobUserTimeLimit     int64
+	LimitFlags              uint32
+	MinimumWorkingSetSize   uintptr
+	MaximumWorkingSetSize   uintptr
+	ActiveProcessLimit      uint32
+	Affinity                uintptr
+	PriorityClass           uint32
+	SchedulingClass         uint32
+}
+
+type ioCounters struct {
+	ReadOperationCount  uint64
+	WriteOperationCount uint64
+	OtherOperationCount uint64
+	ReadTransferCount   uint64
+	WriteTransferCount  uint64
+	OtherTransferCount  uint64
+}
+
+type jobObjectExtendedLimitInformation struct {
+	BasicLimitInformation jobObjectBasicLimitInformation
+	IoInfo                ioCounters
+	ProcessMemoryLimit    uintptr
+	JobMemoryLimit        uintptr
+	PeakProcessMemoryUsed uintptr
+	PeakJobMemoryUsed     uintptr
+}
+
+// assignJob creates a job object for the started process.
+func (c *Cmd) assignJob() error {
+	if !c.jobObject {
+		return nil
+	}
+
+	r, _, err := procCreateJobObjectW.Call(0, 0)
+	if r == 0 {
+		return fmt.Errorf("execctx: error creating job object: %w", err)
+	}
+	job := syscall.Handle(r)
+
+	info := jobObjectExtendedLimitInformation{}
+	info.BasicLimitInformation.LimitFlags = jobObjectLimitKillOnJobClose
+	r, _, err = procSetInformationJobObject.Call(uintptr(job), jobObjectExtendedLimitInformationClass,
+		uintptr(unsafe.Pointer(&info)), unsafe.Sizeof(info))
+	if r == 0 {
+		syscall.CloseHandle(job)
+		return fmt.Errorf("execctx: error configuring job object: %w", err)
+	}
+
+	p, err := syscall.OpenProcess(processSetQuota|processTerminate, false, uint32(c.cmd.Process.Pid))
+	if err != nil {
+		syscall.CloseHandle(job)
+		return fmt.Errorf("execctx: error opening process: %w", err)
+	}
+	defer syscall.CloseHandle(p)
+
+	r, _, err = procAssignProcessToJobObject.Call(uintptr(job), uintptr(p))
+	if r == 0 {
+		syscall.CloseHandle(job)
+		return fmt.Errorf("execctx: error assigning process to job object: %w", err)
+	}
+
+	c.job = uintptr(job)
+	c.onRelease(func() {
+		syscall.CloseHandle(job)
+	})
+	return nil
+}
+
+// killJob terminates all processes in the job of the command.
+// It reports false if the command has no job.
+func (c *Cmd) killJob() (bool, error) {
+	if c.job == 0 {
+		return false, nil
+	}
+	r, _, err := procTerminateJobObject.Call(c.job, 1)
+	if r == 0 {
+		return true, err
+	}
+	return true, nil
+}
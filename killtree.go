@@ -0,0 +1,72 @@
+package execctx
+
+import (
+	"os"
+	"time"
+)
+
+// killTreeAttempts and killTreeInterval bound how long killing a process
+// tree is retried for processes which were forked while it was killed.
+const (
+	killTreeAttempts = 50
+	killTreeInterval = 10 * time.Millisecond
+)
+
+// WithKillTree signals the process along with all its descendants, found by
+// walking /proc, when the command is cancelled. Descendants are signalled
+// before their parents so they are found before they are orphaned.
+// When killing, the descendants are stopped first and the process is killed
+// last, and the walk is repeated until no descendant is left, to catch
+// processes forked in the meantime.
+//
+// This is for processes which escape their process group, for instance by
+// calling setsid, so `WithProcessGroup` doesn't reach them. Descendants
+// which were orphaned before the command is cancelled, such as daemons, are
+// not found.
+//
+// This applies to killing the process, `WithEscalation`, and `Signal`.
+// It is only supported on Linux, elsewhere only the process, or its group,
+// is signalled.
+func WithKillTree() Option {
+	return func(c *Cmd) {
+		c.killTree = true
+	}
+}
+
+// signalTree sends sig to the process and its descendants, deepest first.
+// It reports false if the tree can't be walked on this platform.
+func (c *Cmd) signalTree(sig os.Signal) (bool, error) {
+	pids, err := processTree(c.cmd.Process.Pid)
+	if err != nil {
+		return false, nil
+	}
+	for i := len(pids) - 1; i > 0; i-- {
+		signalPid(pids[i], sig)
+	}
+	return true, c.cmd.Process.Signal(sig)
+}
+
+// killProcessTree kills the descendants of the process, retrying until none
+// are left, and then the process itself. The descendants are stopped before
+// they are killed so none of them forks, or is orphaned, in the meantime.
+// The process is killed last so its descendants can still be found from
+// it. It reports false if the tree can't be walked on this platform.
+func (c *Cmd) killProcessTree() (bool, error) {
+	for i := 0; i < killTreeAttempts; i++ {
+		pids, err := stopTree(c.cmd.Process.Pid)
+		if err != nil {
+			if i == 0 {
+				return false, nil
+			}
+			break
+		}
+		if len(pids) <= 1 {
+			break
+		}
+		for j := len(pids) - 1; j > 0; j-- {
+			signalPid(pids[j], os.Kill)
+		}
+		time.Sleep(killTreeInterval)
+	}
+	return true, c.cmd.Process.Kill()
+}
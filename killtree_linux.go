@@ -0,0 +1,80 @@
+package execctx
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// processTree returns root and its living descendants, parents before
+// their children.
+func processTree(root int) ([]int, error) {
+	children, err := listChildren()
+	if err != nil {
+		return nil, err
+	}
+	tree := []int{root}
+	for i := 0; i < len(tree); i++ {
+		tree = append(tree, children[tree[i]]...)
+	}
+	return tree, nil
+}
+
+// processChildren returns the living children of pid.
+func processChildren(pid int) ([]int, error) {
+	children, err := listChildren()
+	if err != nil {
+		return nil, err
+	}
+	return children[pid], nil
+}
+
+// listChildren maps the pids of the living processes to their children.
+func listChildren() (map[int][]int, error) {
+	dirs, err := filepath.Glob("/proc/[0-9]*")
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[int][]int)
+	for _, dir := range dirs {
+		st, err := readProcStat(filepath.Join(dir, "stat"))
+		if err != nil || st.state == 'Z' {
+			// The process may have exited since listing the directory.
+			continue
+		}
+		pid, _ := strconv.Atoi(filepath.Base(dir))
+		children[st.ppid] = append(children[st.ppid], pid)
+	}
+	return children, nil
+}
+
+// stopTree stops the descendants of root with SIGSTOP, walking the tree
+// again until no new descendant shows up. It returns root and its
+// descendants, parents before their children.
+func stopTree(root int) ([]int, error) {
+	stopped := make(map[int]bool)
+	for i := 0; ; i++ {
+		pids, err := processTree(root)
+		if err != nil {
+			return nil, err
+		}
+		n := 0
+		for _, pid := range pids[1:] {
+			if !stopped[pid] {
+				syscall.Kill(pid, syscall.SIGSTOP)
+				stopped[pid] = true
+				n++
+			}
+		}
+		if n == 0 || i == killTreeAttempts {
+			return pids, nil
+		}
+	}
+}
+
+func signalPid(pid int, sig os.Signal) {
+	if s, ok := sig.(syscall.Signal); ok {
+		syscall.Kill(pid, s)
+	}
+}
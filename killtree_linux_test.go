@@ -0,0 +1,90 @@
+package execctx
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestKillTree(t *testing.T) {
+	// Both descendants escape the process group of the shell with setsid,
+	// one of them two levels down.
+	cmd := exec.Command("sh", "-c", `setsid sleep 99999 & echo $!; setsid sh -c 'sleep 99998 & echo $!; wait' & wait`)
+	stdout, err := cmd.StdoutPipe()
+	assert.NilError(t, err)
+
+	c := FromCmd(context.Background(), cmd, nil, WithProcessGroup(), WithKillTree())
+	assert.NilError(t, c.Start())
+
+	var pids []int
+	r := bufio.NewReader(stdout)
+	for i := 0; i < 2; i++ {
+		line, err := r.ReadString('\n')
+		assert.NilError(t, err)
+		pid, err := strconv.Atoi(strings.TrimSpace(line))
+		assert.NilError(t, err)
+		pids = append(pids, pid)
+	}
+
+	tree, err := processTree(c.Pid())
+	assert.NilError(t, err)
+	assert.Equal(t, len(tree), 4, tree)
+
+	c.Cancel(nil)
+	c.Wait()
+
+	for _, pid := range pids {
+		gone := false
+		for i := 0; i < 100 && !gone; i++ {
+			st, err := readProcStat("/proc/" + strconv.Itoa(pid) + "/stat")
+			gone = err != nil || st.state == 'Z'
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.Assert(t, gone, "process %d still running", pid)
+	}
+}
+
+func TestKillTreeForking(t *testing.T) {
+	// The descendant keeps forking processes which escape its session.
+	cmd := exec.Command("sh", "-c", `setsid sh -c 'while :; do setsid sleep 99997 & sleep 0.01; done' & wait`)
+	c := FromCmd(context.Background(), cmd, nil, WithKillTree())
+	assert.NilError(t, c.Start())
+	time.Sleep(200 * time.Millisecond)
+
+	c.Cancel(nil)
+	c.Wait()
+
+	var left []string
+	for i := 0; i < 100; i++ {
+		left = left[:0]
+		dirs, err := filepath.Glob("/proc/[0-9]*")
+		assert.NilError(t, err)
+		for _, dir := range dirs {
+			cmdline, _ := ioutil.ReadFile(filepath.Join(dir, "cmdline"))
+			if string(cmdline) != "sleep\x0099997\x00" {
+				continue
+			}
+			if st, err := readProcStat(filepath.Join(dir, "stat")); err == nil && st.state != 'Z' {
+				left = append(left, dir)
+			}
+		}
+		if len(left) == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, dir := range left {
+		pid, _ := strconv.Atoi(filepath.Base(dir))
+		syscall.Kill(pid, syscall.SIGKILL)
+	}
+	assert.Equal(t, len(left), 0, left)
+}
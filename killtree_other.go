@@ -0,0 +1,24 @@
+//go:build !linux
+// +build !linux
+
+package execctx
+
+import (
+	"errors"
+	"os"
+	"runtime"
+)
+
+func processTree(root int) ([]int, error) {
+	return nil, errors.New("execctx: walking the process tree is not supported on " + runtime.GOOS)
+}
+
+func processChildren(pid int) ([]int, error) {
+	return processTree(pid)
+}
+
+func stopTree(root int) ([]int, error) {
+	return processTree(root)
+}
+
+func signalPid(pid int, sig os.Signal) {}
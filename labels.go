@@ -0,0 +1,10 @@
+package execctx
+
+// Labels returns a copy of the labels set on the command with `WithLabels`.
+func (c *Cmd) Labels() map[string]string {
+	labels := make(map[string]string, len(c.labels))
+	for k, v := range c.labels {
+		labels[k] = v
+	}
+	return labels
+}
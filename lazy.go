@@ -0,0 +1,80 @@
+package execctx
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// ValueFunc computes a value when the command is started, such as a fresh
+// token or a temporary path. It is passed the context of the command.
+type ValueFunc func(ctx context.Context) (string, error)
+
+// StartError is returned by `Start` when a value set with `WithLazyArg` or
+// `WithLazyEnv` could not be resolved.
+type StartError struct {
+	// Field is the value which failed, as "args[i]" or "env.KEY".
+	Field string
+	Err   error
+}
+
+func (e *StartError) Error() string {
+	return fmt.Sprintf("execctx: error resolving %s: %v", e.Field, e.Err)
+}
+
+func (e *StartError) Unwrap() error {
+	return e.Err
+}
+
+// WithLazyArg sets Args[i] of the command to the value returned by f when
+// the command is started. The argument must already exist, for example as a
+// placeholder.
+// Every start resolves the value again, including for clones of the command.
+func WithLazyArg(i int, f ValueFunc) Option {
+	return func(c *Cmd) {
+		c.lazyArgs = append(c.lazyArgs, lazyArg{index: i, f: f})
+	}
+}
+
+// WithLazyEnv sets the environment variable key to the value returned by f
+// when the command is started.
+// Every start resolves the value again, including for clones of the command.
+func WithLazyEnv(key string, f ValueFunc) Option {
+	return func(c *Cmd) {
+		c.lazyEnv = append(c.lazyEnv, lazyEnv{key: key, f: f})
+	}
+}
+
+type lazyArg struct {
+	index int
+	f     ValueFunc
+}
+
+type lazyEnv struct {
+	key string
+	f   ValueFunc
+}
+
+// resolveLazy resolves the lazy arguments and environment variables.
+func (c *Cmd) resolveLazy() error {
+	for _, a := range c.lazyArgs {
+		field := "args[" + strconv.Itoa(a.index) + "]"
+		if a.index < 0 || a.index >= len(c.cmd.Args) {
+			return &StartError{Field: field, Err: fmt.Errorf("index out of range with %d args", len(c.cmd.Args))}
+		}
+		v, err := a.f(c.ctx)
+		if err != nil {
+			return &StartError{Field: field, Err: err}
+		}
+		c.cmd.Args[a.index] = v
+	}
+
+	for _, e := range c.lazyEnv {
+		v, err := e.f(c.ctx)
+		if err != nil {
+			return &StartError{Field: "env." + e.key, Err: err}
+		}
+		c.lazyEnvValues = append(c.lazyEnvValues, e.key+"="+v)
+	}
+	return nil
+}
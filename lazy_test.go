@@ -0,0 +1,47 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestLazyValues(t *testing.T) {
+	var n int
+	token := func(context.Context) (string, error) {
+		n++
+		return "token-" + strconv.Itoa(n), nil
+	}
+
+	cmd := exec.Command("sh", "-c", `echo "$1 $TOKEN"`, "sh", "placeholder")
+	var out strings.Builder
+	cmd.Stdout = &out
+	c := FromCmd(context.Background(), cmd, nil,
+		WithLazyArg(4, token),
+		WithLazyEnv("TOKEN", token),
+	)
+	assert.NilError(t, c.Run())
+	assert.Equal(t, out.String(), "token-1 token-2\n")
+
+	// Clones resolve the values again.
+	assert.NilError(t, c.Clone(context.Background()).Run())
+	assert.Equal(t, out.String(), "token-1 token-2\ntoken-3 token-4\n")
+}
+
+func TestLazyValueError(t *testing.T) {
+	expected := errors.New("vault unavailable")
+	c := FromCmd(context.Background(), exec.Command("true"), nil, WithLazyEnv("TOKEN", func(context.Context) (string, error) {
+		return "", expected
+	}))
+
+	err := c.Start()
+	var se *StartError
+	assert.Assert(t, errors.As(err, &se), err)
+	assert.Equal(t, se.Field, "env.TOKEN")
+	assert.Assert(t, errors.Is(err, expected))
+}
@@ -0,0 +1,29 @@
+package execctx
+
+// WithLocale sets the locale of the process, e.g. "C.UTF-8", so the output
+// of tools which are parsed doesn't depend on the locale of the host.
+// LC_ALL and LANG are set to the locale, and LANGUAGE is cleared since it
+// takes precedence over them for translated messages.
+func WithLocale(locale string) Option {
+	return func(c *Cmd) {
+		c.locale = locale
+	}
+}
+
+// WithTimezone sets the timezone of the process through TZ, e.g. "UTC".
+func WithTimezone(tz string) Option {
+	return func(c *Cmd) {
+		c.timezone = tz
+	}
+}
+
+func (c *Cmd) localeEnv() []string {
+	var env []string
+	if c.locale != "" {
+		env = append(env, "LC_ALL="+c.locale, "LANG="+c.locale, "LANGUAGE=")
+	}
+	if c.timezone != "" {
+		env = append(env, "TZ="+c.timezone)
+	}
+	return env
+}
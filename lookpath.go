@@ -0,0 +1,12 @@
+//go:build go1.19
+// +build go1.19
+
+package execctx
+
+import "os/exec"
+
+// clearLookPathErr drops the error exec.Command records when the program is
+// not found on PATH, once it has been found elsewhere.
+func clearLookPathErr(cmd *exec.Cmd) {
+	cmd.Err = nil
+}
@@ -0,0 +1,25 @@
+//go:build !go1.19
+// +build !go1.19
+
+package execctx
+
+import "os/exec"
+
+// clearLookPathErr drops the error exec.Command records when the program is
+// not found on PATH, once it has been found elsewhere.
+//
+// Before Go 1.19 the error is unexported, so the command is rebuilt from its
+// exported fields. It is not started yet, so nothing else is set.
+func clearLookPathErr(cmd *exec.Cmd) {
+	*cmd = exec.Cmd{
+		Path:        cmd.Path,
+		Args:        cmd.Args,
+		Env:         cmd.Env,
+		Dir:         cmd.Dir,
+		Stdin:       cmd.Stdin,
+		Stdout:      cmd.Stdout,
+		Stderr:      cmd.Stderr,
+		ExtraFiles:  cmd.ExtraFiles,
+		SysProcAttr: cmd.SysProcAttr,
+	}
+}
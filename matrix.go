@@ -0,0 +1,157 @@
+package execctx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// KV is a single named parameter value.
+type KV struct {
+	Key   string
+	Value string
+}
+
+// MatrixResult is the result of one combination of parameters run by
+// `Matrix`.
+type MatrixResult struct {
+	// Params is the combination of parameters used for this run.
+	Params []KV
+	// Spec is the expanded spec which was run.
+	Spec Spec
+	// Output is the combined stdout and stderr of the command.
+	Output []byte
+	// Err is the error from running the command, if any.
+	Err error
+}
+
+// Key returns the parameters of the result formatted as "k1=v1,k2=v2".
+func (r *MatrixResult) Key() string {
+	parts := make([]string, 0, len(r.Params))
+	for _, kv := range r.Params {
+		parts = append(parts, kv.Key+"="+kv.Value)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Matrix runs the spec template once for every combination of parameters.
+//
+// Each entry of params is one axis of the matrix holding all the values of a
+// parameter, the template is run for the cartesian product of all axes.
+// Occurrences of "{{key}}" in the args, env, and dir of the template are
+// replaced with the value of the parameter. Values are substituted as-is,
+// without any shell interpretation.
+//
+// The commands are run concurrently through the pool, if pool is nil they
+// are all run at once. The results are returned in expansion order, with the
+// first axis varying slowest.
+// An error is only returned if the template could not be expanded.
+func Matrix(ctx context.Context, pool *Pool, tmpl Spec, params [][]KV) ([]MatrixResult, error) {
+	combos := [][]KV{nil}
+	for _, axis := range params {
+		next := make([][]KV, 0, len(combos)*len(axis))
+		for _, combo := range combos {
+			for _, kv := range axis {
+				c := append(append([]KV(nil), combo...), kv)
+				next = append(next, c)
+			}
+		}
+		combos = next
+	}
+
+	results := make([]MatrixResult, len(combos))
+	for i, combo := range combos {
+		spec, err := expandSpec(tmpl, combo)
+		if err != nil {
+			return nil, err
+		}
+		spec.setDefaults()
+		if err := spec.Validate(); err != nil {
+			return nil, err
+		}
+		results[i] = MatrixResult{Params: combo, Spec: spec}
+	}
+
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(r *MatrixResult) {
+			defer wg.Done()
+
+			c := r.Spec.command(ctx, nil)
+			var out bytes.Buffer
+			c.cmd.Stdout = &out
+			c.cmd.Stderr = &out
+
+			if pool != nil {
+				r.Err = pool.Run(ctx, c)
+			} else {
+				r.Err = c.Run()
+			}
+			r.Output = out.Bytes()
+		}(&results[i])
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+func expandSpec(tmpl Spec, params []KV) (Spec, error) {
+	vars := make(map[string]string, len(params))
+	for _, kv := range params {
+		vars[kv.Key] = kv.Value
+	}
+
+	var err error
+	spec := tmpl
+	spec.Args = make([]string, len(tmpl.Args))
+	for i, arg := range tmpl.Args {
+		if spec.Args[i], err = expandTemplate(arg, vars); err != nil {
+			return Spec{}, err
+		}
+	}
+	if tmpl.Env != nil {
+		spec.Env = make([]string, len(tmpl.Env))
+		for i, kv := range tmpl.Env {
+			if spec.Env[i], err = expandTemplate(kv, vars); err != nil {
+				return Spec{}, err
+			}
+		}
+	}
+	if spec.Dir, err = expandTemplate(tmpl.Dir, vars); err != nil {
+		return Spec{}, err
+	}
+	return spec, nil
+}
+
+// expandTemplate replaces "{{key}}" in s with the value of key in vars.
+func expandTemplate(s string, vars map[string]string) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "{{")
+		if start < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		end := strings.Index(s[start:], "}}")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated parameter in %q", s)
+		}
+		end += start
+
+		key := strings.TrimSpace(s[start+2 : end])
+		v, ok := vars[key]
+		if !ok {
+			return "", fmt.Errorf("unknown parameter %q", key)
+		}
+		b.WriteString(s[:start])
+		b.WriteString(v)
+		s = s[end+2:]
+	}
+}
@@ -0,0 +1,34 @@
+package execctx
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestMatrix(t *testing.T) {
+	tmpl := Spec{Args: []string{"echo", "{{os}}/{{ arch }}"}}
+	params := [][]KV{
+		{{"os", "linux"}, {"os", "darwin"}},
+		{{"arch", "amd64"}, {"arch", "arm64"}},
+	}
+
+	results, err := Matrix(context.Background(), NewPool(2), tmpl, params)
+	assert.NilError(t, err)
+	assert.Equal(t, len(results), 4)
+
+	expected := map[string]string{
+		"os=linux,arch=amd64":  "linux/amd64\n",
+		"os=linux,arch=arm64":  "linux/arm64\n",
+		"os=darwin,arch=amd64": "darwin/amd64\n",
+		"os=darwin,arch=arm64": "darwin/arm64\n",
+	}
+	for _, r := range results {
+		assert.NilError(t, r.Err)
+		assert.Equal(t, string(r.Output), expected[r.Key()])
+	}
+
+	_, err = Matrix(context.Background(), nil, Spec{Args: []string{"echo", "{{nope}}"}}, params)
+	assert.ErrorContains(t, err, `unknown parameter "nope"`)
+}
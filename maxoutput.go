@@ -0,0 +1,100 @@
+package execctx
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrOutputLimit is the cancellation cause for commands which wrote more
+// output than allowed by `WithMaxOutput`.
+var ErrOutputLimit = errors.New("execctx: output limit exceeded")
+
+// WithMaxOutput cancels the command with `ErrOutputLimit` once it has written
+// more than n bytes to stdout and stderr combined, so the process is stopped
+// with its cancel handler. Output past the limit is discarded.
+// If the process still exits successfully `Wait` returns `ErrOutputLimit`,
+// since its output is incomplete.
+//
+// Only output copied by the package or os/exec is counted, output written
+// directly to a file set as stdout or stderr is not.
+func WithMaxOutput(n int64) Option {
+	return func(c *Cmd) {
+		c.maxOutput = n
+	}
+}
+
+// limitOutput wraps stdout and stderr to enforce `WithMaxOutput`.
+// This wraps the writers receiving the output of the process as is, so it
+// must be called after the filters are applied.
+func (c *Cmd) limitOutput() {
+	if c.maxOutput <= 0 {
+		return
+	}
+	l := &outputLimit{c: c, remaining: c.maxOutput}
+	c.outputLimit = l
+	stdout, stderr := c.cmd.Stdout, c.cmd.Stderr
+	if stdout != nil && !isFile(stdout) {
+		c.cmd.Stdout = &limitedWriter{l: l, w: stdout}
+		if interfaceEqual(stdout, stderr) {
+			// Keep them the same so os/exec still serializes the writes.
+			c.cmd.Stderr = c.cmd.Stdout
+			return
+		}
+	}
+	if stderr != nil && !isFile(stderr) {
+		c.cmd.Stderr = &limitedWriter{l: l, w: stderr}
+	}
+}
+
+type outputLimit struct {
+	c *Cmd
+
+	mu        sync.Mutex
+	remaining int64
+	exceeded  bool
+}
+
+func (l *outputLimit) err() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.exceeded {
+		return ErrOutputLimit
+	}
+	return nil
+}
+
+// take returns how much of n bytes may still be written.
+func (l *outputLimit) take(n int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	allowed := int64(n)
+	if allowed > l.remaining {
+		allowed = l.remaining
+		l.exceeded = true
+		l.c.Cancel(ErrOutputLimit)
+	}
+	l.remaining -= allowed
+	return int(allowed)
+}
+
+type limitedWriter struct {
+	l *outputLimit
+	w io.Writer
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	n := w.l.take(len(p))
+	if n > 0 {
+		if _, err := w.w.Write(p[:n]); err != nil {
+			return 0, err
+		}
+	}
+	// Pretend the excess was written so the process is not blocked until
+	// the cancel handler stops it.
+	return len(p), nil
+}
@@ -0,0 +1,35 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestWithMaxOutput(t *testing.T) {
+	ctx := context.Background()
+
+	c := FromCmd(ctx, exec.Command("yes"), nil, WithMaxOutput(1000))
+	start := time.Now()
+	out, err := c.Output(ctx)
+	assert.Assert(t, errors.Is(err, ErrOutputLimit), err)
+	assert.Equal(t, len(out), 1000)
+	assert.Assert(t, time.Since(start) < 5*time.Second)
+
+	// Both streams count towards the limit.
+	var stdout, stderr strings.Builder
+	cmd := exec.Command("sh", "-c", "echo 12345; echo 67890 >&2")
+	c = FromCmd(ctx, cmd, nil, WithStdout(&stdout), WithStderr(&stderr), WithMaxOutput(12))
+	assert.NilError(t, c.Run())
+	assert.Equal(t, stdout.String()+stderr.String(), "12345\n67890\n")
+
+	stdout.Reset()
+	c = FromCmd(ctx, exec.Command("sh", "-c", "echo 12345; echo 67890"), nil, WithStdout(&stdout), WithMaxOutput(8))
+	assert.Assert(t, errors.Is(c.Run(), ErrOutputLimit))
+	assert.Equal(t, stdout.String(), "12345\n67")
+}
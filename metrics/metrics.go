@@ -0,0 +1,147 @@
+// Package metrics exposes Prometheus metrics for execctx commands.
+package metrics
+
+import (
+	"context"
+	"os"
+
+	"github.com/cpuguy83/execctx"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector collects metrics about the commands it is attached to with
+// `Option`, keyed by the labels of the commands. It implements
+// `prometheus.Collector`, register it with a registry to export them:
+//
+//	m := metrics.NewCollector("myapp", "tool")
+//	prometheus.MustRegister(m)
+//	c := execctx.FromCmd(ctx, exec.Command("make"), nil,
+//		execctx.WithLabels(map[string]string{"tool": "make"}), m.Option())
+type Collector struct {
+	labelKeys []string
+
+	executions    *prometheus.CounterVec
+	failures      *prometheus.CounterVec
+	cancellations *prometheus.CounterVec
+	escalations   *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+}
+
+// NewCollector creates a collector whose metrics are prefixed with namespace,
+// "execctx" if empty, and labeled with the values of the command labels
+// with the given keys, see `execctx.WithLabels`. A command without one of
+// the labels has it empty.
+//
+// Prometheus needs the set of labels up front, so only the given keys are
+// used rather than all labels of a command. They should be low cardinality,
+// like the name of the tool, not its full command line. Characters which
+// are not allowed in Prometheus label names are replaced with "_".
+//
+// The metrics are:
+//
+//   - executions_total: commands started, or which failed to start.
+//   - failures_total: commands which failed to start or returned an error.
+//   - cancellations_total: commands which were cancelled while running.
+//   - escalations_total: signals sent to stop cancelled commands, by signal.
+//   - duration_seconds: how long processes ran.
+func NewCollector(namespace string, labelKeys ...string) *Collector {
+	if namespace == "" {
+		namespace = "execctx"
+	}
+	labels := make([]string, len(labelKeys))
+	for i, k := range labelKeys {
+		labels[i] = labelName(k)
+	}
+	return &Collector{
+		labelKeys: append([]string(nil), labelKeys...),
+		executions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "executions_total",
+			Help:      "Number of command executions.",
+		}, labels),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "failures_total",
+			Help:      "Number of command executions which failed.",
+		}, labels),
+		cancellations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cancellations_total",
+			Help:      "Number of running commands which were cancelled.",
+		}, labels),
+		escalations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "escalations_total",
+			Help:      "Number of signals sent to stop cancelled commands.",
+		}, append(labels[:len(labels):len(labels)], "signal")),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "duration_seconds",
+			Help:      "Run time of command processes.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 4, 10),
+		}, labels),
+	}
+}
+
+// Option returns an option which records the runs of a command in the
+// collector.
+func (m *Collector) Option() execctx.Option {
+	return func(c *execctx.Cmd) {
+		execctx.WithInterceptors(execctx.InterceptorFunc(func(ctx context.Context, c *execctx.Cmd, next func() error) error {
+			labels := m.labelValues(c)
+			m.executions.WithLabelValues(labels...).Inc()
+			err := next()
+			if err != nil {
+				m.failures.WithLabelValues(labels...).Inc()
+			}
+			if c.Pid() != 0 {
+				m.duration.WithLabelValues(labels...).Observe(c.Result().Duration.Seconds())
+			}
+			return err
+		}))(c)
+		execctx.OnCancelHandler(func(c *execctx.Cmd, _ error) {
+			m.cancellations.WithLabelValues(m.labelValues(c)...).Inc()
+		})(c)
+		execctx.OnSignal(func(c *execctx.Cmd, sig os.Signal) {
+			m.escalations.WithLabelValues(append(m.labelValues(c), sig.String())...).Inc()
+		})(c)
+	}
+}
+
+func (m *Collector) labelValues(c *execctx.Cmd) []string {
+	labels := c.Labels()
+	values := make([]string, len(m.labelKeys))
+	for i, k := range m.labelKeys {
+		values[i] = labels[k]
+	}
+	return values
+}
+
+// labelName turns a command label key into a valid Prometheus label name.
+func labelName(key string) string {
+	name := []byte(key)
+	for i, ch := range name {
+		if !(ch == '_' || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || i > 0 && '0' <= ch && ch <= '9') {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
+
+// Describe implements `prometheus.Collector`.
+func (m *Collector) Describe(ch chan<- *prometheus.Desc) {
+	m.executions.Describe(ch)
+	m.failures.Describe(ch)
+	m.cancellations.Describe(ch)
+	m.escalations.Describe(ch)
+	m.duration.Describe(ch)
+}
+
+// Collect implements `prometheus.Collector`.
+func (m *Collector) Collect(ch chan<- prometheus.Metric) {
+	m.executions.Collect(ch)
+	m.failures.Collect(ch)
+	m.cancellations.Collect(ch)
+	m.escalations.Collect(ch)
+	m.duration.Collect(ch)
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/cpuguy83/execctx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gotest.tools/v3/assert"
+)
+
+func TestCollector(t *testing.T) {
+	m := NewCollector("", "tool")
+	reg := prometheus.NewPedanticRegistry()
+	assert.NilError(t, reg.Register(m))
+	tool := func(name string) execctx.Option {
+		return execctx.WithLabels(map[string]string{"tool": name})
+	}
+
+	assert.NilError(t, execctx.FromCmd(context.Background(), exec.Command("true"), nil, tool("true"), m.Option()).Run())
+	assert.ErrorContains(t, execctx.FromCmd(context.Background(), exec.Command("false"), nil, tool("false"), m.Option()).Run(), "exit status 1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := execctx.FromCmd(ctx, exec.Command("sleep", "99999"), nil, tool("sleep"), m.Option())
+	assert.NilError(t, c.Start())
+	cancel()
+	assert.Assert(t, c.Wait() != nil)
+
+	expected := `
+# HELP execctx_cancellations_total Number of running commands which were cancelled.
+# TYPE execctx_cancellations_total counter
+execctx_cancellations_total{tool="sleep"} 1
+# HELP execctx_escalations_total Number of signals sent to stop cancelled commands.
+# TYPE execctx_escalations_total counter
+execctx_escalations_total{signal="killed",tool="sleep"} 1
+# HELP execctx_executions_total Number of command executions.
+# TYPE execctx_executions_total counter
+execctx_executions_total{tool="false"} 1
+execctx_executions_total{tool="sleep"} 1
+execctx_executions_total{tool="true"} 1
+# HELP execctx_failures_total Number of command executions which failed.
+# TYPE execctx_failures_total counter
+execctx_failures_total{tool="false"} 1
+execctx_failures_total{tool="sleep"} 1
+`
+	assert.NilError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"execctx_cancellations_total", "execctx_escalations_total", "execctx_executions_total", "execctx_failures_total"))
+	assert.Equal(t, testutil.CollectAndCount(m, "execctx_duration_seconds"), 3)
+}
+
+func TestLabelName(t *testing.T) {
+	assert.Equal(t, labelName("execctx.template"), "execctx_template")
+	assert.Equal(t, labelName("1st"), "_st")
+	assert.Equal(t, labelName("job_2"), "job_2")
+}
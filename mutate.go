@@ -0,0 +1,134 @@
+package execctx
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+)
+
+// ErrAlreadyStarted is matched by the error returned when trying to change a
+// command which was already started.
+var ErrAlreadyStarted = errors.New("execctx: command already started")
+
+// MutationError is returned by the setters of `Cmd`, like `SetEnv`, when
+// the command was already started. The change has no effect in that case.
+type MutationError struct {
+	// Field is the name of the field which was to be changed.
+	Field string
+}
+
+func (e *MutationError) Error() string {
+	return "execctx: cannot set " + e.Field + ": command already started"
+}
+
+// Is allows `errors.Is` to match `ErrAlreadyStarted`.
+func (e *MutationError) Is(target error) bool {
+	return target == ErrAlreadyStarted
+}
+
+// mutate applies f to the command unless it was already started.
+// Changing the wrapped exec.Cmd directly is not guarded this way.
+func (c *Cmd) mutate(field string, f func()) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.frozen {
+		return &MutationError{Field: field}
+	}
+	f()
+	return nil
+}
+
+// SetArgs sets the arguments of the command, including the program name as
+// args[0]. The program which is run is not changed.
+func (c *Cmd) SetArgs(args ...string) error {
+	return c.mutate("args", func() { c.cmd.Args = append([]string(nil), args...) })
+}
+
+// SetEnv sets the environment of the command.
+func (c *Cmd) SetEnv(env []string) error {
+	return c.mutate("env", func() { c.cmd.Env = append([]string(nil), env...) })
+}
+
+// SetDir sets the working directory of the command.
+func (c *Cmd) SetDir(dir string) error {
+	return c.mutate("dir", func() { c.cmd.Dir = dir })
+}
+
+// SetStdin sets the stdin of the command.
+func (c *Cmd) SetStdin(r io.Reader) error {
+	return c.mutate("stdin", func() { c.cmd.Stdin = r })
+}
+
+// SetStdout sets the stdout of the command.
+func (c *Cmd) SetStdout(w io.Writer) error {
+	return c.mutate("stdout", func() { c.cmd.Stdout = w })
+}
+
+// SetStderr sets the stderr of the command.
+func (c *Cmd) SetStderr(w io.Writer) error {
+	return c.mutate("stderr", func() { c.cmd.Stderr = w })
+}
+
+// Snapshot returns the spec the command was started with, including the
+// environment the process got. Before the command is started it describes
+// the command as currently configured.
+func (c *Cmd) Snapshot() Spec {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.snapshot != nil {
+		return copySpec(*c.snapshot)
+	}
+	return c.spec()
+}
+
+// freeze prevents further changes through the setters, and keeps a copy of
+// the wrapped exec.Cmd before it is prepared to be started.
+func (c *Cmd) freeze() {
+	c.savePristine()
+	c.mu.Lock()
+	c.frozen = true
+	c.mu.Unlock()
+}
+
+// savePristine keeps a copy of the wrapped exec.Cmd for `Clone`, before the
+// package attaches its own writers or rewrites it to be started.
+func (c *Cmd) savePristine() {
+	c.mu.Lock()
+	if c.pristine == nil {
+		c.pristine = copyExecCmd(c.cmd)
+	}
+	c.mu.Unlock()
+}
+
+// recordSnapshot records the spec the process is started with.
+func (c *Cmd) recordSnapshot() {
+	c.mu.Lock()
+	s := c.spec()
+	c.snapshot = &s
+	c.mu.Unlock()
+}
+
+func (c *Cmd) spec() Spec {
+	s := Spec{
+		Args:   append([]string(nil), c.cmd.Args...),
+		Env:    c.EffectiveEnv(),
+		Dir:    c.cmd.Dir,
+		Labels: c.Labels(),
+		Cost:   c.poolCost(),
+	}
+	if len(s.Args) > 0 {
+		s.Name = filepath.Base(s.Args[0])
+	}
+	return s
+}
+
+func copySpec(s Spec) Spec {
+	s.Args = append([]string(nil), s.Args...)
+	s.Env = append([]string(nil), s.Env...)
+	labels := make(map[string]string, len(s.Labels))
+	for k, v := range s.Labels {
+		labels[k] = v
+	}
+	s.Labels = labels
+	return s
+}
@@ -0,0 +1,34 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestMutateAfterStart(t *testing.T) {
+	c := FromCmd(context.Background(), exec.Command("true"), nil, WithLabels(map[string]string{"job": "a"}))
+	assert.NilError(t, c.SetEnv([]string{"A=1"}))
+	assert.NilError(t, c.SetDir("/"))
+	assert.DeepEqual(t, c.Snapshot().Env, []string{"A=1"})
+
+	assert.NilError(t, c.Run())
+
+	err := c.SetEnv([]string{"A=2"})
+	assert.Assert(t, errors.Is(err, ErrAlreadyStarted), err)
+	var me *MutationError
+	assert.Assert(t, errors.As(err, &me))
+	assert.Equal(t, me.Field, "env")
+
+	assert.DeepEqual(t, c.Snapshot(), Spec{
+		Name:   "true",
+		Args:   []string{"true"},
+		Env:    []string{"A=1"},
+		Dir:    "/",
+		Labels: map[string]string{"job": "a"},
+		Cost:   1,
+	})
+}
@@ -0,0 +1,112 @@
+package execctx
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"sync"
+)
+
+var (
+	defaultOptionsMu sync.Mutex
+	defaultOptions   []Option
+)
+
+// SetDefaultOptions sets the options applied to every command created with
+// `CommandContext`, before any options passed to it. This is typically used
+// to set the cancellation behavior for a whole program, e.g. with
+// `GracefulKill`. Calling it again replaces the previous defaults.
+func SetDefaultOptions(opts ...Option) {
+	defaultOptionsMu.Lock()
+	defaultOptions = append([]Option(nil), opts...)
+	defaultOptionsMu.Unlock()
+}
+
+type optionsKey struct{}
+
+// WithOptions returns a copy of ctx carrying opts. Every command created
+// from the returned context, or any context derived from it, gets those
+// options before the ones passed to its constructor. Options added to a
+// derived context are applied after the ones of its parents.
+//
+// This lets middleware set the logger, environment, or cancellation
+// behavior for everything run on behalf of a request without passing the
+// options down explicitly.
+func WithOptions(ctx context.Context, opts ...Option) context.Context {
+	parent := contextOptions(ctx)
+	merged := make([]Option, 0, len(parent)+len(opts))
+	merged = append(merged, parent...)
+	merged = append(merged, opts...)
+	return context.WithValue(ctx, optionsKey{}, merged)
+}
+
+func contextOptions(ctx context.Context) []Option {
+	opts, _ := ctx.Value(optionsKey{}).([]Option)
+	return opts
+}
+
+// CommandContext is a drop-in replacement for `exec.CommandContext`.
+// Like the stdlib version the process is killed when ctx is done, unless a
+// different behavior is set with `SetDefaultOptions` or `WithOptions`.
+// Options from ctx take precedence over the defaults.
+func CommandContext(ctx context.Context, name string, args ...string) *Cmd {
+	defaultOptionsMu.Lock()
+	opts := defaultOptions
+	defaultOptionsMu.Unlock()
+	return newCmd(ctx, exec.Command(name, args...), nil, opts, nil)
+}
+
+// New creates a command to run the named program with the given arguments,
+// without having to build an exec.Cmd first. The program is looked up like
+// `exec.Command` does.
+//
+// Options such as `WithEnv`, `WithDir`, `WithStdout`, `GracefulKill`, and
+// `WithCancelFunc` configure what would otherwise be set on the exec.Cmd or
+// passed to `FromCmd`. When cancelled, the process is killed unless another
+// strategy is set.
+func New(ctx context.Context, name string, args []string, opts ...Option) *Cmd {
+	return FromCmd(ctx, exec.Command(name, args...), nil, opts...)
+}
+
+// WithEnv sets the environment of the command in "key=value" form.
+func WithEnv(env []string) Option {
+	return func(c *Cmd) {
+		c.cmd.Env = append([]string(nil), env...)
+	}
+}
+
+// WithDir sets the working directory of the command.
+func WithDir(dir string) Option {
+	return func(c *Cmd) {
+		c.cmd.Dir = dir
+	}
+}
+
+// WithStdin sets the stdin of the command.
+func WithStdin(r io.Reader) Option {
+	return func(c *Cmd) {
+		c.cmd.Stdin = r
+	}
+}
+
+// WithStdout sets the stdout of the command.
+func WithStdout(w io.Writer) Option {
+	return func(c *Cmd) {
+		c.cmd.Stdout = w
+	}
+}
+
+// WithStderr sets the stderr of the command.
+func WithStderr(w io.Writer) Option {
+	return func(c *Cmd) {
+		c.cmd.Stderr = w
+	}
+}
+
+// WithCancelFunc sets the function which is called when the command is
+// cancelled, like the cancel function passed to `FromCmd`.
+func WithCancelFunc(f func()) Option {
+	return func(c *Cmd) {
+		c.cancel = f
+	}
+}
@@ -0,0 +1,52 @@
+package execctx
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestNew(t *testing.T) {
+	var stdout strings.Builder
+	c := New(context.Background(), "sh", []string{"-c", `echo "$FOO $(pwd)"; cat`},
+		WithEnv([]string{"FOO=bar"}),
+		WithDir("/"),
+		WithStdin(strings.NewReader("input\n")),
+		WithStdout(&stdout),
+	)
+	assert.NilError(t, c.Run())
+	assert.Equal(t, stdout.String(), "bar /\ninput\n")
+}
+
+func TestCommandContext(t *testing.T) {
+	SetDefaultOptions(WithLabels(map[string]string{"default": "true"}))
+	defer SetDefaultOptions()
+
+	c := CommandContext(context.Background(), "true")
+	assert.Equal(t, c.Labels()["default"], "true")
+	assert.NilError(t, c.Run())
+}
+
+func TestWithOptions(t *testing.T) {
+	SetDefaultOptions(WithLabels(map[string]string{"default": "true", "scope": "default"}))
+	defer SetDefaultOptions()
+
+	ctx := WithOptions(context.Background(), WithLabels(map[string]string{"scope": "request"}))
+	ctx = WithOptions(ctx, WithDir("/"))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	c := CommandContext(ctx, "pwd")
+	assert.Equal(t, c.Labels()["default"], "true")
+	assert.Equal(t, c.Labels()["scope"], "request")
+	out, err := c.Output(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "/\n")
+
+	// Options passed explicitly are applied last.
+	c = New(ctx, "true", nil, WithLabels(map[string]string{"scope": "call"}))
+	assert.Equal(t, c.Labels()["scope"], "call")
+	assert.Equal(t, c.cmd.Dir, "/")
+}
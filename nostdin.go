@@ -0,0 +1,34 @@
+package execctx
+
+import (
+	"errors"
+)
+
+// ErrInteractive is the cancellation cause for commands started with
+// `WithNoStdin` which tried to read from the terminal.
+var ErrInteractive = errors.New("execctx: process tried to read from the terminal")
+
+// WithNoStdin connects the stdin of the command to the null device,
+// overriding any stdin set on the command, so a command which unexpectedly
+// prompts for input doesn't hang forever waiting for it.
+//
+// Programs may still try to prompt on the controlling terminal directly.
+// On Linux the process is put in its own process group, which the kernel
+// stops if it reads from the terminal. Such a stopped process (or any other
+// process in its group) is detected, killed along with its group, and the
+// command is cancelled with `ErrInteractive`.
+// On other Unix platforms the process is started in a new session without a
+// controlling terminal, so opening the terminal fails right away.
+func WithNoStdin() Option {
+	return func(c *Cmd) {
+		c.noStdin = true
+	}
+}
+
+func (c *Cmd) setupNoStdin() {
+	if !c.noStdin {
+		return
+	}
+	c.cmd.Stdin = nil
+	detachTerminal(c)
+}
@@ -0,0 +1,73 @@
+package execctx
+
+import (
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const stoppedPollInterval = 200 * time.Millisecond
+
+func detachTerminal(c *Cmd) {
+	if c.cmd.SysProcAttr == nil {
+		c.cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	// A new session is also a new process group.
+	if !c.cmd.SysProcAttr.Setsid {
+		c.cmd.SysProcAttr.Setpgid = true
+	}
+	c.onProcessStart(func() {
+		go c.watchStopped()
+	})
+}
+
+// watchStopped kills the process group of the command if any of its
+// processes are stopped, which happens when they read from the terminal.
+func (c *Cmd) watchStopped() {
+	pgid := c.cmd.Process.Pid
+	ticker := time.NewTicker(stoppedPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.waitDone:
+			return
+		case <-ticker.C:
+		}
+
+		// The process itself is expected to be stopped while the throttle
+		// holds it. If it reads from the terminal it is stopped again
+		// once the throttle continues it.
+		c.throttleMu.Lock()
+		ignore := 0
+		if c.throttleStopped {
+			ignore = pgid
+		}
+		stopped := groupStopped(pgid, ignore)
+		c.throttleMu.Unlock()
+
+		if stopped {
+			c.Cancel(ErrInteractive)
+			c.whileRunning(func() { syscall.Kill(-pgid, syscall.SIGKILL) })
+			return
+		}
+	}
+}
+
+// groupStopped reports whether any process in the process group, other than
+// ignore, is in a job control stop. Tracing stops, like those of a process
+// run under `WithTracer`, are not interactive and don't count.
+func groupStopped(pgid, ignore int) bool {
+	stats, _ := filepath.Glob("/proc/[0-9]*/stat")
+	for _, p := range stats {
+		if ignore != 0 && p == "/proc/"+strconv.Itoa(ignore)+"/stat" {
+			continue
+		}
+		st, err := readProcStat(p)
+		if err == nil && st.pgid == pgid && st.state == 'T' {
+			return true
+		}
+	}
+	return false
+}
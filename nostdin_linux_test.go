@@ -0,0 +1,56 @@
+package execctx
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestNoStdinSetsid(t *testing.T) {
+	cmd := exec.Command("true")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+
+	c := FromCmd(context.Background(), cmd, nil, WithNoStdin())
+	assert.NilError(t, c.Run())
+}
+
+func TestGroupStoppedTraced(t *testing.T) {
+	// The ptrace requests have to come from the thread which started the
+	// tracee.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	cmd := exec.Command("sleep", "10")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Ptrace: true, Setpgid: true}
+	assert.NilError(t, cmd.Start())
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	// The tracee enters a tracing stop once it execs.
+	pid := cmd.Process.Pid
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		st, err := readProcStat("/proc/" + strconv.Itoa(pid) + "/stat")
+		assert.NilError(t, err)
+		if st.state == 't' {
+			break
+		}
+		assert.Assert(t, time.Now().Before(deadline), "not traced")
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Assert(t, !groupStopped(pid, 0))
+
+	assert.NilError(t, syscall.Kill(pid, syscall.SIGSTOP))
+	assert.NilError(t, syscall.PtraceDetach(pid))
+	deadline = time.Now().Add(5 * time.Second)
+	for !groupStopped(pid, 0) {
+		assert.Assert(t, time.Now().Before(deadline), "not stopped")
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly && !solaris && !aix
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly,!solaris,!aix
+
+package execctx
+
+func detachTerminal(c *Cmd) {}
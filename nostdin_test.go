@@ -0,0 +1,50 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestNoStdin(t *testing.T) {
+	cmd := exec.Command("cat")
+	cmd.Stdin = strings.NewReader("should not be read")
+
+	c := FromCmd(context.Background(), cmd, nil, WithNoStdin())
+	out, err := c.Output(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "")
+}
+
+func TestNoStdinStopped(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("stopped process detection is only supported on linux")
+	}
+
+	// Simulate the kernel stopping the process for reading from the terminal.
+	c := FromCmd(context.Background(), exec.Command("sh", "-c", "kill -STOP $$"), func() {}, WithNoStdin())
+	err := c.Run()
+	assert.Assert(t, errors.Is(err, ErrInteractive), err)
+}
+
+func TestNoStdinThrottled(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("stopped process detection is only supported on linux")
+	}
+
+	// The throttle stops the process most of the time, which is not a read
+	// from the terminal.
+	c := FromCmd(context.Background(), exec.Command("sleep", "1"), nil, WithNoStdin(), WithCPUThrottle(0.3))
+	assert.NilError(t, c.Run())
+
+	// A read from the terminal is retried, and the process stopped again,
+	// whenever the throttle continues it. That is still detected.
+	c = FromCmd(context.Background(), exec.Command("sh", "-c", "while :; do kill -STOP $$; done"), func() {}, WithNoStdin(), WithCPUThrottle(0.3))
+	err := c.Run()
+	assert.Assert(t, errors.Is(err, ErrInteractive), err)
+}
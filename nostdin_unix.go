@@ -0,0 +1,18 @@
+//go:build darwin || freebsd || netbsd || openbsd || dragonfly || solaris || aix
+// +build darwin freebsd netbsd openbsd dragonfly solaris aix
+
+package execctx
+
+import (
+	"syscall"
+)
+
+func detachTerminal(c *Cmd) {
+	if c.cmd.SysProcAttr == nil {
+		c.cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	c.cmd.SysProcAttr.Setsid = true
+	// The session leader already leads a new process group, it cannot be
+	// moved to another one.
+	c.cmd.SysProcAttr.Setpgid = false
+}
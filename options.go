@@ -0,0 +1,66 @@
+package execctx
+
+import (
+	"time"
+)
+
+// Option is used to configure a `Cmd`.
+type Option func(*Cmd)
+
+// WithCleanup marks the command as a cleanup command.
+//
+// Cleanup commands are not cancelled when the context passed to `FromCmd` is
+// cancelled, including if it is already cancelled when the command is
+// started. Instead the command is bounded by the provided timeout, starting
+// from when the command is started. Values from the context are still
+// available.
+// A timeout <= 0 means the command is never cancelled.
+//
+// This is useful for things like unmounting filesystems or releasing locks
+// which must still happen after a job has been cancelled.
+func WithCleanup(timeout time.Duration) Option {
+	return func(c *Cmd) {
+		c.cleanup = true
+		c.cleanupTimeout = timeout
+	}
+}
+
+// WithGraceBudget lets the command run even if its context is already done
+// when it is started, bounded by the provided budget. This is meant for
+// commands which must still run during shutdown, once the context which
+// governs them is gone.
+//
+// Without it, starting a command with a done context fails with a
+// `*StartCancelledError`. Calling `Cancel` before the command is started
+// still prevents it from starting. If the context is done only after the
+// command is started, it is cancelled as usual.
+// A budget <= 0 disables it, which is the default.
+func WithGraceBudget(budget time.Duration) Option {
+	return func(c *Cmd) {
+		c.graceBudget = budget
+	}
+}
+
+// WithCost sets the cost of the command when run through a `Pool`.
+// The cost is a relative weight of the resources the command is expected to
+// use. The default cost is 1.
+func WithCost(cost int64) Option {
+	return func(c *Cmd) {
+		c.cost = cost
+	}
+}
+
+// WithLabels adds labels to the command.
+// Labels are arbitrary metadata used to identify a command, for instance in
+// logs or metrics.
+// This may be passed multiple times, later values for the same key win.
+func WithLabels(labels map[string]string) Option {
+	return func(c *Cmd) {
+		if c.labels == nil {
+			c.labels = make(map[string]string, len(labels))
+		}
+		for k, v := range labels {
+			c.labels[k] = v
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Package otel instruments execctx commands with OpenTelemetry tracing.
+package otel
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cpuguy83/execctx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/cpuguy83/execctx/otel"
+
+// Span attribute keys set by `WithTracing`, besides the semantic convention
+// ones for the command line, pid, and exit code.
+const (
+	AttrSignal      = attribute.Key("execctx.signal")
+	AttrCancelCause = attribute.Key("execctx.cancel_cause")
+)
+
+// AttrLabelPrefix prefixes the keys of the labels of the command, set with
+// `execctx.WithLabels`, which are recorded as span attributes.
+const AttrLabelPrefix = "execctx.label."
+
+// WithTracing wraps every run of the command in a span from tp, a child of
+// the span in the context of the command, if any. If tp is nil the global
+// tracer provider is used.
+//
+// The span records the command line, pid, exit code, the signal which
+// terminated the process, the cancellation cause, and the labels of the
+// command. When the command is
+// cancelled, the start of the cancel handler and the signals sent to stop
+// the process are recorded as span events.
+func WithTracing(tp trace.TracerProvider) execctx.Option {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	t := &tracer{
+		tracer: tp.Tracer(instrumentationName),
+		runs:   make(map[*execctx.Cmd]trace.Span),
+	}
+
+	return func(c *execctx.Cmd) {
+		execctx.WithInterceptors(t)(c)
+		execctx.OnCancelHandler(func(c *execctx.Cmd, cause error) {
+			t.event(c, "cancel handler", AttrCancelCause.String(cause.Error()))
+		})(c)
+		execctx.OnSignal(func(c *execctx.Cmd, sig os.Signal) {
+			t.event(c, "signal", AttrSignal.String(sig.String()))
+		})(c)
+	}
+}
+
+type tracer struct {
+	tracer trace.Tracer
+
+	mu   sync.Mutex
+	runs map[*execctx.Cmd]trace.Span
+}
+
+func (t *tracer) Run(ctx context.Context, c *execctx.Cmd, next func() error) error {
+	args := c.Snapshot().Args
+	attrs := []attribute.KeyValue{attribute.StringSlice("process.command_args", args)}
+	for k, v := range c.Labels() {
+		attrs = append(attrs, attribute.String(AttrLabelPrefix+k, v))
+	}
+	_, span := t.tracer.Start(ctx, "exec "+filepath.Base(args[0]),
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attrs...),
+	)
+	defer span.End()
+
+	t.mu.Lock()
+	t.runs[c] = span
+	t.mu.Unlock()
+
+	err := next()
+
+	t.mu.Lock()
+	delete(t.runs, c)
+	t.mu.Unlock()
+
+	if pid := c.Pid(); pid != 0 {
+		res := c.Result()
+		span.SetAttributes(
+			attribute.Int("process.pid", pid),
+			attribute.Int("process.exit.code", res.ExitCode),
+		)
+		if res.Signal != nil {
+			span.SetAttributes(AttrSignal.String(res.Signal.String()))
+		}
+		if res.Cause != nil {
+			span.SetAttributes(AttrCancelCause.String(res.Cause.Error()))
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+func (t *tracer) event(c *execctx.Cmd, name string, attrs ...attribute.KeyValue) {
+	t.mu.Lock()
+	span := t.runs[c]
+	t.mu.Unlock()
+	if span != nil {
+		span.AddEvent(name, trace.WithAttributes(attrs...))
+	}
+}
@@ -0,0 +1,58 @@
+package otel
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/cpuguy83/execctx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gotest.tools/v3/assert"
+)
+
+func TestWithTracing(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+
+	c := execctx.FromCmd(context.Background(), exec.Command("sh", "-c", "exit 3"), nil, WithTracing(tp), execctx.WithLabels(map[string]string{"job": "build"}))
+	assert.ErrorContains(t, c.Run(), "exit status 3")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c2 := execctx.FromCmd(ctx, exec.Command("sleep", "99999"), nil, WithTracing(tp))
+	assert.NilError(t, c2.Start())
+	cancel()
+	c2.Wait()
+
+	spans := rec.Ended()
+	assert.Equal(t, len(spans), 2)
+
+	attrs := func(s sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+		m := make(map[attribute.Key]attribute.Value)
+		for _, kv := range s.Attributes() {
+			m[kv.Key] = kv.Value
+		}
+		return m
+	}
+
+	s := spans[0]
+	assert.Equal(t, s.Name(), "exec sh")
+	assert.Equal(t, s.Status().Code, codes.Error)
+	a := attrs(s)
+	assert.DeepEqual(t, a["process.command_args"].AsStringSlice(), []string{"sh", "-c", "exit 3"})
+	assert.Equal(t, a["process.exit.code"].AsInt64(), int64(3))
+	assert.Equal(t, a["process.pid"].AsInt64(), int64(c.Pid()))
+	assert.Equal(t, a[AttrLabelPrefix+"job"].AsString(), "build")
+
+	s = spans[1]
+	a = attrs(s)
+	assert.Equal(t, a[AttrSignal].AsString(), "killed")
+	assert.Equal(t, a[AttrCancelCause].AsString(), context.Canceled.Error())
+	var events []string
+	for _, e := range s.Events() {
+		events = append(events, e.Name)
+	}
+	assert.DeepEqual(t, events, []string{"cancel handler", "signal", "exception"})
+}
@@ -0,0 +1,120 @@
+package execctx
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// cancelDrainTimeout is how long output of a cancelled command is still
+// collected after the process exits.
+// Descendants of the process may hold on to its stdout or stderr for much
+// longer, there is no point waiting for them once the command is cancelled.
+const cancelDrainTimeout = 100 * time.Millisecond
+
+// pipeOutput makes the package, rather than os/exec, responsible for copying
+// the output of the process into the stdout and stderr writers.
+// os/exec waits until all output is copied before Wait returns, which can
+// take forever when the process leaves behind descendants which hold on to
+// the pipes. This lets `Wait` stop collecting output once the command is
+// cancelled.
+//
+// This is only used when the package owns the writers, as for `Output`,
+// since writes may still happen in the background after `Wait` returns.
+func (c *Cmd) pipeOutput() error {
+	if !c.ownPipes {
+		return nil
+	}
+
+	var copies []func()
+	pipe := func(w io.Writer) (*os.File, error) {
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			return nil, err
+		}
+		c.outputReaders = append(c.outputReaders, pr)
+		c.onStarted(func() { pw.Close() })
+		c.onRelease(func() { pr.Close() })
+		copies = append(copies, func() {
+			io.Copy(w, pr)
+			// Keep the pipe drained if w fails so the process doesn't block.
+			io.Copy(ioutil.Discard, pr)
+		})
+		return pw, nil
+	}
+
+	shared := interfaceEqual(c.cmd.Stdout, c.cmd.Stderr)
+	if w := c.cmd.Stdout; w != nil && !isFile(w) {
+		pw, err := pipe(w)
+		if err != nil {
+			return err
+		}
+		c.cmd.Stdout = pw
+		if shared {
+			c.cmd.Stderr = pw
+		}
+	}
+	if w := c.cmd.Stderr; w != nil && !isFile(w) {
+		pw, err := pipe(w)
+		if err != nil {
+			return err
+		}
+		c.cmd.Stderr = pw
+	}
+
+	if len(copies) == 0 {
+		return nil
+	}
+
+	done := make(chan struct{})
+	c.outputDone = done
+	var wg sync.WaitGroup
+	for _, f := range copies {
+		wg.Add(1)
+		go func(f func()) {
+			defer wg.Done()
+			f()
+		}(f)
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return nil
+}
+
+func isFile(w io.Writer) bool {
+	_, ok := w.(*os.File)
+	return ok
+}
+
+// waitOutput waits for the output of the process to be copied.
+// If the command is cancelled, output is only collected for a short while
+// longer.
+func (c *Cmd) waitOutput() {
+	if c.outputDone == nil {
+		return
+	}
+
+	select {
+	case <-c.outputDone:
+		return
+	case <-c.cancelled:
+	}
+
+	timer := time.NewTimer(cancelDrainTimeout)
+	defer timer.Stop()
+	select {
+	case <-c.outputDone:
+		return
+	case <-timer.C:
+	}
+
+	// Closing the read side makes the copies return.
+	for _, r := range c.outputReaders {
+		r.Close()
+	}
+	<-c.outputDone
+}
@@ -0,0 +1,47 @@
+package execctx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// stderrTailSize is how much of the end of stderr is included in the errors
+// of `OutputJSON`.
+const stderrTailSize = 1024
+
+// OutputJSON runs the command like `Output` and decodes its stdout as JSON
+// into v.
+//
+// If the command fails, or its output can't be decoded, the error includes
+// the end of stderr, as captured by `Output`, which is usually where tools
+// explain what went wrong. The original error is still available with
+// errors.Is and errors.As.
+func (c *Cmd) OutputJSON(ctx context.Context, v interface{}) error {
+	out, err := c.Output(ctx)
+	if err == nil {
+		if jerr := json.Unmarshal(out, v); jerr != nil {
+			err = fmt.Errorf("execctx: error decoding output of %s: %w", c, jerr)
+		}
+	}
+	if err != nil {
+		if tail := c.stderrTail(); tail != "" {
+			return fmt.Errorf("%w; stderr: %s", err, tail)
+		}
+		return err
+	}
+	return nil
+}
+
+// stderrTail returns the end of the captured stderr of the command.
+func (c *Cmd) stderrTail() string {
+	if c.stderrCapture == nil {
+		return ""
+	}
+	stderr := bytes.TrimSpace(c.stderrCapture.Bytes())
+	if len(stderr) > stderrTailSize {
+		return "..." + string(stderr[len(stderr)-stderrTailSize:])
+	}
+	return string(stderr)
+}
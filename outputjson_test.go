@@ -0,0 +1,34 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestOutputJSON(t *testing.T) {
+	ctx := context.Background()
+
+	var v struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	c := FromCmd(ctx, exec.Command("echo", `{"name": "a", "count": 2}`), nil)
+	assert.NilError(t, c.OutputJSON(ctx, &v))
+	assert.Equal(t, v.Name, "a")
+	assert.Equal(t, v.Count, 2)
+
+	c = FromCmd(ctx, exec.Command("sh", "-c", "echo not json; echo warning >&2"), nil)
+	err := c.OutputJSON(ctx, &v)
+	assert.ErrorContains(t, err, "error decoding output")
+	assert.ErrorContains(t, err, "stderr: warning")
+
+	c = FromCmd(ctx, exec.Command("sh", "-c", "echo 'fatal: no such thing' >&2; exit 2"), nil)
+	err = c.OutputJSON(ctx, &v)
+	var ee *exec.ExitError
+	assert.Assert(t, errors.As(err, &ee), err)
+	assert.Equal(t, err.Error(), "exit status 2; stderr: fatal: no such thing")
+}
@@ -0,0 +1,44 @@
+package execctx
+
+import "sync"
+
+// WithOutputTail keeps the last n bytes of stdout and of stderr of the
+// command while it runs, see `OutputTail`. This is meant for tools which
+// show what running commands are doing, like the httpdebug package.
+// n <= 0 disables it, which is the default.
+func WithOutputTail(n int) Option {
+	return func(c *Cmd) {
+		c.outputTail = n
+	}
+}
+
+// OutputTail returns the last bytes of stdout and stderr written by the
+// command so far, after any filters are applied, as kept with
+// `WithOutputTail`. Both are nil if it is not set or the command was not
+// started.
+//
+// It is safe to call while the command is running.
+func (c *Cmd) OutputTail() (stdout, stderr []byte) {
+	return c.stdoutRecent.bytes(), c.stderrRecent.bytes()
+}
+
+// outputTail is a tailCapture which can be read while it is written to.
+type outputTail struct {
+	mu sync.Mutex
+	t  tailCapture
+}
+
+func (o *outputTail) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.t.Write(p)
+}
+
+func (o *outputTail) bytes() []byte {
+	if o == nil {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]byte(nil), o.t.Bytes()...)
+}
@@ -0,0 +1,31 @@
+package execctx
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestOutputTail(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "echo hello; echo world; echo oops >&2")
+	c := FromCmd(context.Background(), cmd, nil, WithOutputTail(6))
+	clone := c.Clone(context.Background())
+
+	stdout, stderr := c.OutputTail()
+	assert.Assert(t, stdout == nil && stderr == nil)
+
+	assert.NilError(t, c.Run())
+	stdout, stderr = c.OutputTail()
+	assert.Equal(t, string(stdout), "world\n")
+	assert.Equal(t, string(stderr), "oops\n")
+
+	// The clone keeps its own tail.
+	clone.cmd.Args = []string{"sh", "-c", "echo again"}
+	assert.NilError(t, clone.Run())
+	stdout, _ = clone.OutputTail()
+	assert.Equal(t, string(stdout), "again\n")
+	stdout, _ = c.OutputTail()
+	assert.Equal(t, string(stdout), "world\n")
+}
@@ -0,0 +1,91 @@
+package execctx
+
+import (
+	"os"
+)
+
+// WithProcessGroup starts the process in its own process group and signals
+// the whole group, rather than just the process, when the command is
+// cancelled. This way shells and the processes they start are stopped too.
+//
+// This applies to killing the process, `WithEscalation`, and `Signal`.
+// Custom cancel handlers can use `Signal` to do the same.
+//
+// On Windows the process is started in a new console process group instead,
+// and `os.Interrupt` is delivered to it as a CTRL_BREAK_EVENT. Windows does
+// not deliver CTRL_C_EVENT to a specific group, and console tools treat both
+// alike. This gives Windows commands a graceful shutdown through
+// `GracefulKill(os.Interrupt, grace)`, other signals only reach the process
+// itself. On platforms without process groups only the process itself is
+// signalled.
+func WithProcessGroup() Option {
+	return func(c *Cmd) {
+		c.processGroup = true
+	}
+}
+
+// OnSignal adds a function which is called right before the package signals
+// the process to stop it: for the steps of `WithEscalation` and when killing
+// it. Signals sent through `Signal` are not reported.
+func OnSignal(f func(c *Cmd, sig os.Signal)) Option {
+	return func(c *Cmd) {
+		c.onSignal = append(c.onSignal, f)
+	}
+}
+
+// Signal sends sig to the process, or to its process group when
+// `WithProcessGroup` is used, or to the process and its descendants with
+// `WithKillTree`.
+func (c *Cmd) Signal(sig os.Signal) error {
+	err := errProcessDone
+	c.whileRunning(func() { err = c.signal(sig) })
+	return err
+}
+
+func (c *Cmd) signal(sig os.Signal) error {
+	if c.killTree {
+		if ok, err := c.signalTree(sig); ok {
+			return err
+		}
+	}
+	if c.processGroup {
+		if ok, err := signalGroup(c.cmd.Process, sig); ok {
+			return err
+		}
+	}
+	if c.straced && sig != os.Kill {
+		if ok, err := c.signalTracee(sig); ok {
+			return err
+		}
+	}
+	return c.cmd.Process.Signal(sig)
+}
+
+// kill kills the process, or its process group when `WithProcessGroup` is
+// used.
+// With `WithJobObject` the whole job is terminated instead, and with
+// `WithKillTree` the process and all its descendants.
+func (c *Cmd) kill() error {
+	c.notifySignal(os.Kill)
+	err := errProcessDone
+	c.whileRunning(func() {
+		if ok, jerr := c.killJob(); ok {
+			err = jerr
+			return
+		}
+		if c.killTree {
+			if ok, terr := c.killProcessTree(); ok {
+				err = terr
+				return
+			}
+		}
+		err = c.signal(os.Kill)
+	})
+	return err
+}
+
+func (c *Cmd) notifySignal(sig os.Signal) {
+	for _, f := range c.onSignal {
+		f(c, sig)
+	}
+}
@@ -0,0 +1,54 @@
+package execctx
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestProcessGroupKill(t *testing.T) {
+	// The shell forks sleep and reports its pid.
+	cmd := exec.Command("sh", "-c", "sleep 99999 & echo $!; wait")
+	stdout, err := cmd.StdoutPipe()
+	assert.NilError(t, err)
+
+	c := FromCmd(context.Background(), cmd, nil, WithProcessGroup())
+	assert.NilError(t, c.Start())
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	assert.NilError(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(line))
+	assert.NilError(t, err)
+
+	c.Cancel(nil)
+	c.Wait()
+
+	// The grandchild was killed with the group. It is reparented, so it may
+	// linger as a zombie for a bit.
+	for i := 0; i < 100; i++ {
+		st, err := readProcStat("/proc/" + strconv.Itoa(pid) + "/stat")
+		if err != nil || st.state == 'Z' {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("process %d still running", pid)
+}
+
+func TestSignalReaped(t *testing.T) {
+	// Once the process is reaped its pid, and process group, may belong to
+	// another process, so nothing is signalled.
+	for _, opt := range []Option{WithProcessGroup(), WithKillTree()} {
+		c := FromCmd(context.Background(), exec.Command("true"), nil, opt)
+		assert.NilError(t, c.Run())
+		assert.Equal(t, c.Signal(syscall.SIGTERM), errProcessDone)
+		assert.Equal(t, c.kill(), errProcessDone)
+	}
+}
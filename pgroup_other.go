@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly && !solaris && !aix && !windows
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly,!solaris,!aix,!windows
+
+package execctx
+
+import (
+	"os"
+)
+
+func (c *Cmd) setupProcessGroup() {}
+
+func signalGroup(p *os.Process, sig os.Signal) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,33 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly || solaris || aix
+// +build linux darwin freebsd netbsd openbsd dragonfly solaris aix
+
+package execctx
+
+import (
+	"os"
+	"syscall"
+)
+
+func (c *Cmd) setupProcessGroup() {
+	if !c.processGroup {
+		return
+	}
+	if c.cmd.SysProcAttr == nil {
+		c.cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	// A new session is also a new process group.
+	if !c.cmd.SysProcAttr.Setsid {
+		c.cmd.SysProcAttr.Setpgid = true
+		c.cmd.SysProcAttr.Pgid = 0
+	}
+}
+
+// signalGroup signals the process group led by p.
+// It reports false if the signal cannot be sent to a group.
+func signalGroup(p *os.Process, sig os.Signal) (bool, error) {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return false, nil
+	}
+	return true, syscall.Kill(-p.Pid, s)
+}
@@ -0,0 +1,33 @@
+package execctx
+
+import (
+	"os"
+	"syscall"
+)
+
+var procGenerateConsoleCtrlEvent = modkernel32.NewProc("GenerateConsoleCtrlEvent")
+
+const ctrlBreakEvent = 1
+
+func (c *Cmd) setupProcessGroup() {
+	if !c.processGroup {
+		return
+	}
+	if c.cmd.SysProcAttr == nil {
+		c.cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	c.cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+}
+
+// signalGroup sends a CTRL_BREAK_EVENT to the console process group led by p
+// for `os.Interrupt`. It reports false for any other signal.
+func signalGroup(p *os.Process, sig os.Signal) (bool, error) {
+	if sig != os.Interrupt {
+		return false, nil
+	}
+	r, _, err := procGenerateConsoleCtrlEvent.Call(ctrlBreakEvent, uintptr(p.Pid))
+	if r == 0 {
+		return true, err
+	}
+	return true, nil
+}
@@ -0,0 +1,238 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// errPipelineStart is the cancellation cause for the stages of a pipeline
+// which were started before another stage failed to start.
+var errPipelineStart = errors.New("execctx: pipeline stage failed to start")
+
+var (
+	// ErrUpstreamExited is the cancellation cause for pipeline stages which
+	// are cancelled because a stage before them exited, see `StagePolicy`.
+	ErrUpstreamExited = errors.New("execctx: upstream pipeline stage exited")
+	// ErrDownstreamExited is the cancellation cause for pipeline stages which
+	// are cancelled because a stage after them exited, see `StagePolicy`.
+	ErrDownstreamExited = errors.New("execctx: downstream pipeline stage exited")
+)
+
+// StageTrigger selects which exits of a pipeline stage cancel other stages.
+type StageTrigger int
+
+const (
+	// StageNever leaves the other stages running, which is the default.
+	StageNever StageTrigger = iota
+	// StageOnFailure cancels the other stages when the stage fails.
+	StageOnFailure
+	// StageOnExit cancels the other stages whenever the stage exits.
+	StageOnExit
+)
+
+// StagePolicy controls how the exit of a command run as a stage of a
+// `Pipeline` affects the other stages, see `WithStagePolicy`.
+//
+// By default a stage whose upstream exited reads EOF once it drained the
+// pipe, and a stage whose downstream exited gets SIGPIPE, or EPIPE if it
+// ignores SIGPIPE, the next time it writes to the pipe. Until then both keep
+// running, so a stage which never reads or writes again must be cancelled
+// through the policy of the stage which exited.
+type StagePolicy struct {
+	// CancelDownstream cancels all the stages after this one when it
+	// exits, with `ErrUpstreamExited` as the cause.
+	CancelDownstream StageTrigger
+	// CancelUpstream cancels all the stages before this one when it exits,
+	// with `ErrDownstreamExited` as the cause, instead of letting them
+	// finish flushing their output into the closed pipe.
+	CancelUpstream StageTrigger
+	// IgnoreSIGPIPE counts the stage as successful when it was killed by
+	// SIGPIPE, which happens when it writes after all the stages after it
+	// stopped reading, like `yes` in `yes | head -n 1`.
+	IgnoreSIGPIPE bool
+}
+
+// WithStagePolicy sets how the exit of the command affects the other stages
+// when it is run in a `Pipeline`. It has no effect otherwise.
+func WithStagePolicy(p StagePolicy) Option {
+	return func(c *Cmd) {
+		c.stagePolicy = p
+	}
+}
+
+// Pipeline runs the commands as a pipeline, connecting the stdout of each
+// command to the stdin of the next, and waits for all of them to exit.
+// The stdin of the first command and the stdout of the last one are left as
+// configured, all the other stdin and stdout must be unset.
+//
+// When ctx is done every stage is cancelled, from first to last, so each
+// is stopped with its own cancel handler. If a stage fails to start the
+// stages started before it are cancelled the same way. How the exit of a
+// stage affects the others is set per stage with `WithStagePolicy`.
+//
+// Like a shell with pipefail set, the error from the last stage which failed
+// is returned.
+func Pipeline(ctx context.Context, cmds ...*Cmd) error {
+	return PipelineEvents(ctx, nil, cmds...)
+}
+
+// StageEvent reports that a stage of a pipeline exited, see `PipelineEvents`.
+type StageEvent struct {
+	// Stage is the index of the stage in the pipeline.
+	Stage int
+	// Name is the command line of the stage.
+	Name string
+	// ExitCode is the exit code of the stage, as reported by `Cmd.ExitCode`.
+	ExitCode int
+	// Duration is how long the stage ran.
+	Duration time.Duration
+	// Err is the error returned by `Cmd.Wait` for the stage.
+	Err error
+}
+
+// PipelineEvents is like `Pipeline`, but sends an event on events as each
+// stage exits, in the order they exit, so the progress of long pipelines can
+// be followed. Events are sent for the stages which were started, all of them
+// are sent before PipelineEvents returns and events is not closed.
+//
+// Stages are only reaped as events are received, events must be consumed
+// while the pipeline runs or be buffered for the number of stages.
+func PipelineEvents(ctx context.Context, events chan<- StageEvent, cmds ...*Cmd) error {
+	for i := 0; i < len(cmds)-1; i++ {
+		if cmds[i].cmd.Stdout != nil {
+			return fmt.Errorf("execctx: pipeline stage %d: stdout already set", i)
+		}
+		if cmds[i+1].cmd.Stdin != nil {
+			return fmt.Errorf("execctx: pipeline stage %d: stdin already set", i+1)
+		}
+	}
+
+	type pipe struct{ r, w *os.File }
+	var pipes []pipe
+	defer func() {
+		for _, p := range pipes {
+			p.r.Close()
+			p.w.Close()
+		}
+	}()
+	for i := 0; i < len(cmds)-1; i++ {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return err
+		}
+		pipes = append(pipes, pipe{r, w})
+		cmds[i].cmd.Stdout = w
+		cmds[i+1].cmd.Stdin = r
+	}
+
+	var started []*Cmd
+	var startErr error
+	for i, c := range cmds {
+		if err := c.Start(); err != nil {
+			startErr = fmt.Errorf("pipeline stage %d (%s): %w", i, c, err)
+			break
+		}
+		started = append(started, c)
+	}
+
+	// A stage which was passed its end of a pipe holds its own copy of it,
+	// ours must be closed so the reader sees EOF once the writer exits. If
+	// the stdio of the stage is wrapped, e.g. by filters or line callbacks,
+	// the pipe end is copied from or to by the package instead and is only
+	// closed once the stage exits.
+	held := make([][]*os.File, len(cmds))
+	hold := func(i int, f *os.File, stdio interface{}) {
+		if i < len(started) && !interfaceEqual(stdio, f) {
+			held[i] = append(held[i], f)
+			return
+		}
+		f.Close()
+	}
+	for i, p := range pipes {
+		hold(i, p.w, cmds[i].cmd.Stdout)
+		hold(i+1, p.r, cmds[i+1].cmd.Stdin)
+	}
+	pipes = nil
+
+	if startErr != nil {
+		for _, c := range started {
+			c.Cancel(errPipelineStart)
+		}
+		waitStages(started, held, events)
+		return startErr
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			for _, c := range cmds {
+				c.Cancel(ctx.Err())
+			}
+		case <-done:
+		}
+	}()
+
+	var err error
+	for i, werr := range waitStages(cmds, held, events) {
+		if werr != nil {
+			err = fmt.Errorf("pipeline stage %d (%s): %w", i, cmds[i], werr)
+		}
+	}
+	return err
+}
+
+func (t StageTrigger) triggered(err error) bool {
+	return t == StageOnExit || t == StageOnFailure && err != nil
+}
+
+// waitStages waits for all the stages and returns their errors, reporting
+// each one to events, if set, as it exits. The files held for a stage are
+// closed once it exits.
+func waitStages(cmds []*Cmd, held [][]*os.File, events chan<- StageEvent) []error {
+	errs := make([]error, len(cmds))
+	var wg sync.WaitGroup
+	for i, c := range cmds {
+		wg.Add(1)
+		go func(i int, c *Cmd) {
+			defer wg.Done()
+			errs[i] = c.Wait()
+			for _, f := range held[i] {
+				f.Close()
+			}
+			p := c.stagePolicy
+			if errs[i] != nil && p.IgnoreSIGPIPE && c.cmd.ProcessState != nil {
+				if sig, ok := exitSignal(c); ok && sig == syscall.SIGPIPE {
+					errs[i] = nil
+				}
+			}
+			if p.CancelDownstream.triggered(errs[i]) {
+				for _, d := range cmds[i+1:] {
+					d.Cancel(ErrUpstreamExited)
+				}
+			}
+			if p.CancelUpstream.triggered(errs[i]) {
+				for _, u := range cmds[:i] {
+					u.Cancel(ErrDownstreamExited)
+				}
+			}
+			if events != nil {
+				events <- StageEvent{
+					Stage:    i,
+					Name:     c.String(),
+					ExitCode: c.ExitCode(),
+					Duration: c.Result().Duration,
+					Err:      errs[i],
+				}
+			}
+		}(i, c)
+	}
+	wg.Wait()
+	return errs
+}
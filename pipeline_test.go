@@ -0,0 +1,140 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestPipeline(t *testing.T) {
+	ctx := context.Background()
+	var out strings.Builder
+	err := Pipeline(ctx,
+		FromCmd(ctx, exec.Command("printf", "b\\na\\nc\\n"), nil),
+		FromCmd(ctx, exec.Command("sort"), nil),
+		FromCmd(ctx, exec.Command("head", "-n", "2"), nil, WithStdout(&out)),
+	)
+	assert.NilError(t, err)
+	assert.Equal(t, out.String(), "a\nb\n")
+
+	// The error of the last failing stage is returned.
+	err = Pipeline(ctx,
+		FromCmd(ctx, exec.Command("false"), nil),
+		FromCmd(ctx, exec.Command("sh", "-c", "cat; exit 3"), nil),
+	)
+	assert.ErrorContains(t, err, "pipeline stage 1")
+	assert.ErrorContains(t, err, "exit status 3")
+
+	err = Pipeline(ctx,
+		FromCmd(ctx, exec.Command("echo"), nil),
+		FromCmd(ctx, exec.Command("/nonexistent"), nil),
+	)
+	assert.ErrorContains(t, err, "pipeline stage 1")
+}
+
+func TestPipelineCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := Pipeline(ctx,
+		FromCmd(context.Background(), exec.Command("sleep", "99999"), nil),
+		FromCmd(context.Background(), exec.Command("cat"), nil),
+	)
+	var cancelled *CancelledError
+	assert.Assert(t, errors.As(err, &cancelled), err)
+	assert.Assert(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestPipelineEvents(t *testing.T) {
+	ctx := context.Background()
+	events := make(chan StageEvent, 3)
+	err := PipelineEvents(ctx, events,
+		FromCmd(ctx, exec.Command("sh", "-c", "sleep 0.2; echo a"), nil),
+		FromCmd(ctx, exec.Command("sh", "-c", "exit 3"), nil),
+		FromCmd(ctx, exec.Command("cat"), nil, WithStdout(&strings.Builder{})),
+	)
+	assert.ErrorContains(t, err, "pipeline stage 1")
+	close(events)
+
+	got := make(map[int]StageEvent)
+	var order []int
+	for e := range events {
+		got[e.Stage] = e
+		order = append(order, e.Stage)
+	}
+	assert.Equal(t, len(order), 3)
+
+	// The stages are reported as they exit, the first one is still sleeping
+	// long after the others exited.
+	assert.Equal(t, order[2], 0)
+	assert.Assert(t, got[0].Duration >= 200*time.Millisecond, got[0].Duration)
+
+	assert.Assert(t, strings.HasSuffix(got[1].Name, "sh -c exit 3"), got[1].Name)
+	assert.Equal(t, got[1].ExitCode, 3)
+	assert.ErrorContains(t, got[1].Err, "exit status 3")
+	assert.NilError(t, got[2].Err)
+}
+
+func TestPipelineWrappedStdio(t *testing.T) {
+	ctx := context.Background()
+	var out strings.Builder
+	var tee strings.Builder
+	var lines []string
+	err := Pipeline(ctx,
+		FromCmd(ctx, exec.Command("printf", "b\\na\\n"), nil, OnStdoutLine(func(line []byte) {
+			lines = append(lines, string(line))
+		})),
+		FromCmd(ctx, exec.Command("sort"), nil, TeeStdout(&tee)),
+		FromCmd(ctx, exec.Command("cat"), nil, WithStdout(&out)),
+	)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, lines, []string{"b", "a"})
+	assert.Equal(t, out.String(), "a\nb\n")
+	assert.Equal(t, tee.String(), "a\nb\n")
+}
+
+func TestPipelineStagePolicy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a posix shell")
+	}
+	ctx := context.Background()
+
+	// The downstream stage never exits on its own.
+	err := Pipeline(ctx,
+		FromCmd(ctx, exec.Command("echo", "a"), nil, WithStagePolicy(StagePolicy{CancelDownstream: StageOnExit})),
+		FromCmd(ctx, exec.Command("sh", "-c", "cat; exec sleep 99999"), nil, WithStdout(&strings.Builder{})),
+	)
+	assert.Assert(t, errors.Is(err, ErrUpstreamExited), err)
+
+	// Only a failure cancels the downstream stage.
+	var out strings.Builder
+	err = Pipeline(ctx,
+		FromCmd(ctx, exec.Command("echo", "a"), nil, WithStagePolicy(StagePolicy{CancelDownstream: StageOnFailure})),
+		FromCmd(ctx, exec.Command("cat"), nil, WithStdout(&out)),
+	)
+	assert.NilError(t, err)
+	assert.Equal(t, out.String(), "a\n")
+
+	// The upstream stage ignores SIGPIPE and would write forever.
+	err = Pipeline(ctx,
+		FromCmd(ctx, exec.Command("sh", "-c", "trap '' PIPE; while :; do echo y; sleep 0.01; done 2>/dev/null"), nil),
+		FromCmd(ctx, exec.Command("head", "-n", "1"), nil, WithStdout(&strings.Builder{}), WithStagePolicy(StagePolicy{CancelUpstream: StageOnExit})),
+	)
+	assert.Assert(t, errors.Is(err, ErrDownstreamExited), err)
+
+	// SIGPIPE counts as a failure unless it is ignored.
+	yes := func(p StagePolicy) error {
+		return Pipeline(ctx,
+			FromCmd(ctx, exec.Command("yes"), nil, WithStagePolicy(p)),
+			FromCmd(ctx, exec.Command("head", "-n", "1"), nil, WithStdout(&strings.Builder{})),
+		)
+	}
+	assert.ErrorContains(t, yes(StagePolicy{}), "broken pipe")
+	assert.NilError(t, yes(StagePolicy{IgnoreSIGPIPE: true}))
+}
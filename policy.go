@@ -0,0 +1,153 @@
+package execctx
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Policy restricts which programs may be executed.
+//
+// Program patterns use `filepath.Match` syntax. Patterns containing a path
+// separator are matched against the full path of the program, other patterns
+// are matched against its base name.
+type Policy struct {
+	// Allow lists the programs which may be executed.
+	// If empty, all programs not matching Deny are allowed.
+	Allow []string
+	// Deny lists the programs which may not be executed.
+	// Deny takes precedence over Allow.
+	Deny []string
+	// Args restricts the arguments which may be passed to programs.
+	Args []ArgRule
+	// OnViolation, if set, is called for every command rejected by the policy.
+	// This can be used to record violations in an audit log.
+	OnViolation func(*PolicyError)
+}
+
+// ArgRule restricts the arguments of the programs matching Program.
+// Each argument, not including the program itself, is checked against the
+// rule.
+type ArgRule struct {
+	// Program is the pattern of programs the rule applies to.
+	Program string
+	// Allow, if set, must match every argument.
+	Allow *regexp.Regexp
+	// Deny must not match any argument.
+	Deny *regexp.Regexp
+}
+
+// PolicyError is returned by `Start` when a command is rejected by a policy.
+type PolicyError struct {
+	Path   string
+	Args   []string
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("execctx: policy violation: %s: %s", e.Path, e.Reason)
+}
+
+var (
+	defaultPolicyMu sync.Mutex
+	defaultPolicy   *Policy
+)
+
+// SetDefaultPolicy sets a policy which is checked for every command
+// started by this package, in addition to any policy set with `WithPolicy`.
+// Pass nil to remove the default policy.
+func SetDefaultPolicy(p *Policy) {
+	defaultPolicyMu.Lock()
+	defaultPolicy = p
+	defaultPolicyMu.Unlock()
+}
+
+// WithPolicy sets a policy which is checked when the command is started.
+func WithPolicy(p *Policy) Option {
+	return func(c *Cmd) {
+		c.policy = p
+	}
+}
+
+func (c *Cmd) checkPolicies() error {
+	defaultPolicyMu.Lock()
+	dp := defaultPolicy
+	defaultPolicyMu.Unlock()
+
+	for _, p := range []*Policy{dp, c.policy} {
+		if p == nil {
+			continue
+		}
+		if err := p.Check(c.cmd.Path, c.cmd.Args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check checks if the program at path may be executed with the provided
+// arguments, where args[0] is the program as passed to the command.
+// A `*PolicyError` is returned if it may not.
+func (p *Policy) Check(path string, args []string) error {
+	reason := p.check(path, args)
+	if reason == "" {
+		return nil
+	}
+
+	err := &PolicyError{Path: path, Args: args, Reason: reason}
+	if p.OnViolation != nil {
+		p.OnViolation(err)
+	}
+	return err
+}
+
+func (p *Policy) check(path string, args []string) string {
+	for _, pattern := range p.Deny {
+		if matchProgram(pattern, path) {
+			return "program matches deny pattern " + pattern
+		}
+	}
+
+	if len(p.Allow) > 0 {
+		var allowed bool
+		for _, pattern := range p.Allow {
+			if matchProgram(pattern, path) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return "program is not in the allow list"
+		}
+	}
+
+	if len(args) > 0 {
+		args = args[1:]
+	}
+	for _, rule := range p.Args {
+		if !matchProgram(rule.Program, path) {
+			continue
+		}
+		for _, arg := range args {
+			if rule.Allow != nil && !rule.Allow.MatchString(arg) {
+				return fmt.Sprintf("argument %q does not match %s", arg, rule.Allow)
+			}
+			if rule.Deny != nil && rule.Deny.MatchString(arg) {
+				return fmt.Sprintf("argument %q matches %s", arg, rule.Deny)
+			}
+		}
+	}
+
+	return ""
+}
+
+func matchProgram(pattern, path string) bool {
+	name := path
+	if !strings.ContainsAny(pattern, `/\`) {
+		name = filepath.Base(path)
+	}
+	ok, _ := filepath.Match(pattern, name)
+	return ok
+}
@@ -0,0 +1,38 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"regexp"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestPolicy(t *testing.T) {
+	var violations []*PolicyError
+	p := &Policy{
+		Allow: []string{"true", "echo"},
+		Deny:  []string{"/usr/local/bin/*"},
+		Args: []ArgRule{
+			{Program: "echo", Deny: regexp.MustCompile(`^--`)},
+		},
+		OnViolation: func(err *PolicyError) {
+			violations = append(violations, err)
+		},
+	}
+
+	assert.NilError(t, FromCmd(context.Background(), exec.Command("true"), nil, WithPolicy(p)).Run())
+
+	err := FromCmd(context.Background(), exec.Command("false"), nil, WithPolicy(p)).Run()
+	var pe *PolicyError
+	assert.Assert(t, errors.As(err, &pe), err)
+	assert.ErrorContains(t, err, "not in the allow list")
+
+	err = FromCmd(context.Background(), exec.Command("echo", "--help"), nil, WithPolicy(p)).Run()
+	assert.ErrorContains(t, err, `argument "--help"`)
+
+	assert.ErrorContains(t, p.Check("/usr/local/bin/true", []string{"true"}), "deny pattern")
+	assert.Equal(t, len(violations), 3)
+}
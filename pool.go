@@ -0,0 +1,166 @@
+package execctx
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Pool limits the number of commands which may run at the same time.
+//
+// Each command has a cost, set with `WithCost`, which defaults to 1.
+// A command is only started once the pool has enough free capacity to cover
+// its cost. This allows a few expensive commands to take up the same room as
+// many cheap ones.
+// Commands are admitted in the order they were submitted.
+type Pool struct {
+	mu      sync.Mutex
+	size    int64
+	cur     int64
+	waiters list.List
+}
+
+type poolWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+// NewPool creates a pool with the provided total capacity.
+func NewPool(size int64) *Pool {
+	return &Pool{size: size}
+}
+
+// Start waits for enough capacity in the pool to start the command and then
+// starts it.
+// The capacity is returned to the pool once `Wait` on the command returns.
+//
+// Two contexts are involved:
+//   - queueCtx is the submission context. It bounds how long the caller is
+//     willing to wait for the command to be admitted, if it is done first
+//     Start returns its error. It has no effect once the command is running.
+//   - The context the command was created with is the execution context. It
+//     governs the running command as usual. If it is done, or the command is
+//     cancelled, while the command is still queued, the command is dropped
+//     from the queue and Start returns the same error `Cmd.Start` would.
+func (p *Pool) Start(queueCtx context.Context, c *Cmd) error {
+	n := c.poolCost()
+
+	var execDone <-chan struct{}
+	if !c.cleanup && c.graceBudget <= 0 {
+		// Cleanup commands, and commands with a grace budget, still run
+		// once their context is done.
+		execDone = c.ctx.Done()
+	}
+	if err := p.acquire(queueCtx, execDone, c.cancelled, n); err != nil {
+		if err == errExecDone {
+			return c.cancelledErr()
+		}
+		return err
+	}
+
+	if err := c.Start(); err != nil {
+		p.release(n)
+		return err
+	}
+
+	go func() {
+		<-c.waitDone
+		p.release(n)
+	}()
+	return nil
+}
+
+// Run starts the command using the pool and waits for it to exit.
+// See `Start` for more details.
+func (p *Pool) Run(queueCtx context.Context, c *Cmd) error {
+	if err := p.Start(queueCtx, c); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+func (c *Cmd) poolCost() int64 {
+	if c.cost <= 0 {
+		return 1
+	}
+	return c.cost
+}
+
+// errExecDone is returned from acquire when the command was cancelled while
+// queued.
+var errExecDone = errors.New("command cancelled while queued")
+
+func (p *Pool) acquire(ctx context.Context, execDone, cancelled <-chan struct{}, n int64) error {
+	p.mu.Lock()
+	if n > p.size {
+		p.mu.Unlock()
+		return fmt.Errorf("execctx: command cost %d exceeds pool size %d", n, p.size)
+	}
+	if p.size-p.cur >= n && p.waiters.Len() == 0 {
+		p.cur += n
+		p.mu.Unlock()
+		return nil
+	}
+
+	ready := make(chan struct{})
+	elem := p.waiters.PushBack(poolWaiter{n: n, ready: ready})
+	p.mu.Unlock()
+
+	var err error
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-execDone:
+		err = errExecDone
+	case <-cancelled:
+		err = errExecDone
+	}
+
+	p.mu.Lock()
+	select {
+	case <-ready:
+		// Capacity was handed to us after we gave up.
+		p.cur -= n
+		p.notifyWaiters()
+	default:
+		isFront := p.waiters.Front() == elem
+		p.waiters.Remove(elem)
+		if isFront {
+			p.notifyWaiters()
+		}
+	}
+	p.mu.Unlock()
+	return err
+}
+
+func (p *Pool) release(n int64) {
+	p.mu.Lock()
+	p.cur -= n
+	p.notifyWaiters()
+	p.mu.Unlock()
+}
+
+// notifyWaiters must be called with the lock held.
+func (p *Pool) notifyWaiters() {
+	for {
+		next := p.waiters.Front()
+		if next == nil {
+			return
+		}
+
+		w := next.Value.(poolWaiter)
+		if p.size-p.cur < w.n {
+			// Don't let smaller commands jump the queue, otherwise big ones
+			// may never get to run.
+			return
+		}
+
+		p.cur += w.n
+		p.waiters.Remove(next)
+		close(w.ready)
+	}
+}
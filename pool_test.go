@@ -0,0 +1,63 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestPoolCost(t *testing.T) {
+	p := NewPool(2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	heavy := FromCmd(ctx, exec.Command("sleep", "99999"), nil, WithCost(2))
+	assert.NilError(t, p.Start(context.Background(), heavy))
+
+	light := FromCmd(context.Background(), exec.Command("true"), nil)
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer waitCancel()
+	assert.Equal(t, p.Run(waitCtx, light), context.DeadlineExceeded)
+
+	cancel()
+	heavy.Wait()
+
+	light = FromCmd(context.Background(), exec.Command("true"), nil)
+	assert.NilError(t, p.Run(context.Background(), light))
+
+	tooBig := FromCmd(context.Background(), exec.Command("true"), nil, WithCost(3))
+	assert.ErrorContains(t, p.Run(context.Background(), tooBig), "exceeds pool size")
+}
+
+func TestPoolExecutionContextWhileQueued(t *testing.T) {
+	p := NewPool(1)
+
+	holdCtx, holdCancel := context.WithCancel(context.Background())
+	defer holdCancel()
+	holder := FromCmd(holdCtx, exec.Command("sleep", "99999"), nil)
+	assert.NilError(t, p.Start(context.Background(), holder))
+
+	execCtx, execCancel := context.WithCancel(context.Background())
+	queued := FromCmd(execCtx, exec.Command("true"), nil)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- p.Run(context.Background(), queued)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	execCancel()
+	err := <-errCh
+	var sce *StartCancelledError
+	assert.Assert(t, errors.As(err, &sce), err)
+	assert.Equal(t, sce.Cause, context.Canceled)
+
+	holdCancel()
+	holder.Wait()
+	assert.NilError(t, p.Run(context.Background(), FromCmd(context.Background(), exec.Command("true"), nil)))
+}
@@ -0,0 +1,68 @@
+package execctx
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strconv"
+)
+
+// procStat holds the fields of /proc/<pid>/stat used by the package.
+type procStat struct {
+	state byte
+	ppid  int
+	pgid  int
+	// cpu is the user and system time used by the process, in clock ticks.
+	cpu uint64
+	// start is the time the process started after boot, in clock ticks.
+	start uint64
+}
+
+var errProcStat = errors.New("execctx: malformed /proc stat")
+
+func readProcStat(path string) (procStat, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return procStat{}, err
+	}
+	return parseProcStat(data)
+}
+
+// parseProcStat parses the contents of /proc/<pid>/stat.
+func parseProcStat(data []byte) (procStat, error) {
+	// The command name is in parens and may itself contain spaces or parens.
+	i := bytes.LastIndexByte(data, ')')
+	if i < 0 {
+		return procStat{}, errProcStat
+	}
+	// Fields after the command name, starting with the state, which is the
+	// third field of the file.
+	fields := bytes.Fields(data[i+1:])
+	if len(fields) < 20 || len(fields[0]) != 1 {
+		return procStat{}, errProcStat
+	}
+
+	var (
+		st  = procStat{state: fields[0][0]}
+		err error
+	)
+	if st.ppid, err = strconv.Atoi(string(fields[1])); err != nil {
+		return procStat{}, errProcStat
+	}
+	if st.pgid, err = strconv.Atoi(string(fields[2])); err != nil {
+		return procStat{}, errProcStat
+	}
+	utime, err := strconv.ParseUint(string(fields[11]), 10, 64)
+	if err != nil {
+		return procStat{}, errProcStat
+	}
+	stime, err := strconv.ParseUint(string(fields[12]), 10, 64)
+	if err != nil {
+		return procStat{}, errProcStat
+	}
+	st.cpu = utime + stime
+	if st.start, err = strconv.ParseUint(string(fields[19]), 10, 64); err != nil {
+		return procStat{}, errProcStat
+	}
+	return st, nil
+}
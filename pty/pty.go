@@ -0,0 +1,17 @@
+// Package pty runs commands on a pseudo-terminal, for interactive tools such
+// as ssh, sudo, or top which behave differently, or refuse to run, without
+// one. Sessions can be recorded for audit with a `Recorder`.
+package pty
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrUnsupported is returned on platforms without pseudo-terminal support.
+// Only Linux is supported.
+var ErrUnsupported = errors.New("pty: not supported on this platform")
+
+// HangupGrace is how long a cancelled command has to exit after it got
+// SIGHUP before it is killed.
+const HangupGrace = 5 * time.Second
@@ -0,0 +1,155 @@
+package pty
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"unsafe"
+
+	"github.com/cpuguy83/execctx"
+)
+
+// Start starts cmd on a new pseudo-terminal and returns the command along
+// with the master side of the terminal, which is used to read the output of
+// the command and to write its input.
+// Stdin, stdout, and stderr of cmd are connected to the terminal if they are
+// not set. The process is started in a new session with the terminal as its
+// controlling terminal.
+//
+// When the command is cancelled its session gets SIGHUP, as if the terminal
+// was closed, and it is killed if it is still running after `HangupGrace`.
+// Options are applied after these defaults, so they can be overridden.
+//
+// The caller must close the master once done with it.
+func Start(ctx context.Context, cmd *exec.Cmd, opts ...execctx.Option) (*execctx.Cmd, *os.File, error) {
+	master, tty, err := open()
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := start(ctx, cmd, master, tty, opts)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	return c, master, nil
+}
+
+// StartResponder is like `Start`, but answers the prompts of the command
+// with responses, like `execctx.WithResponder` does for a command without a
+// terminal, see `execctx.WithResponderTerminal`. The output of the command
+// is copied to out, which may be nil. The terminal is closed once the
+// command exited.
+//
+// The answered prompts are recorded in the transcript of the command.
+func StartResponder(ctx context.Context, cmd *exec.Cmd, out io.Writer, responses []execctx.Response, opts ...execctx.Option) (*execctx.Cmd, error) {
+	master, tty, err := open()
+	if err != nil {
+		return nil, err
+	}
+	opts = append([]execctx.Option{
+		execctx.WithResponder(responses...),
+		execctx.WithResponderTerminal(master, out),
+		execctx.OnExit(func(execctx.Result) { master.Close() }),
+	}, opts...)
+	c, err := start(ctx, cmd, master, tty, opts)
+	if err != nil {
+		master.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func start(ctx context.Context, cmd *exec.Cmd, master, tty *os.File, opts []execctx.Option) (*execctx.Cmd, error) {
+	defer tty.Close()
+
+	if cmd.Stdin == nil {
+		cmd.Stdin = tty
+	}
+	if cmd.Stdout == nil {
+		cmd.Stdout = tty
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = tty
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = ttyFD(cmd, tty)
+
+	defaults := []execctx.Option{
+		execctx.WithProcessGroup(),
+		execctx.WithEscalation(execctx.EscalationPolicy{{Signal: syscall.SIGHUP, Wait: HangupGrace}}),
+	}
+	c := execctx.FromCmd(ctx, cmd, nil, append(defaults, opts...)...)
+	if err := c.Start(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ttyFD returns the descriptor number of the terminal in the child.
+func ttyFD(cmd *exec.Cmd, tty *os.File) int {
+	switch tty {
+	case cmd.Stdin:
+		return 0
+	case cmd.Stdout:
+		return 1
+	case cmd.Stderr:
+		return 2
+	}
+	for i, f := range cmd.ExtraFiles {
+		if f == tty {
+			return 3 + i
+		}
+	}
+	cmd.ExtraFiles = append(cmd.ExtraFiles, tty)
+	return 3 + len(cmd.ExtraFiles) - 1
+}
+
+// open opens a new pseudo-terminal pair.
+func open() (master, tty *os.File, err error) {
+	master, err = os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var unlock int32
+	if err := ioctl(master, syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("pty: error unlocking terminal: %w", err)
+	}
+	var n uint32
+	if err := ioctl(master, syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("pty: error getting terminal number: %w", err)
+	}
+
+	tty, err = os.OpenFile("/dev/pts/"+strconv.FormatUint(uint64(n), 10), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	return master, tty, nil
+}
+
+// Setsize sets the size of the terminal, in characters.
+func Setsize(master *os.File, rows, cols uint16) error {
+	ws := struct {
+		Row, Col, X, Y uint16
+	}{Row: rows, Col: cols}
+	return ioctl(master, syscall.TIOCSWINSZ, uintptr(unsafe.Pointer(&ws)))
+}
+
+func ioctl(f *os.File, req, arg uintptr) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, arg)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package pty
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/cpuguy83/execctx"
+	"gotest.tools/v3/assert"
+)
+
+func TestStart(t *testing.T) {
+	c, master, err := Start(context.Background(), exec.Command("sh", "-c", "test -t 0 && test -t 1 && echo tty"))
+	assert.NilError(t, err)
+	defer master.Close()
+
+	line, err := bufio.NewReader(master).ReadString('\n')
+	assert.NilError(t, err)
+	assert.Equal(t, line, "tty\r\n")
+	assert.NilError(t, c.Wait())
+}
+
+func TestStartCancel(t *testing.T) {
+	script := `trap 'exit 3' HUP; echo ready; while :; do sleep 0.01; done`
+	c, master, err := Start(context.Background(), exec.Command("sh", "-c", script))
+	assert.NilError(t, err)
+	defer master.Close()
+
+	r := bufio.NewReader(master)
+	line, err := r.ReadString('\n')
+	assert.NilError(t, err)
+	assert.Equal(t, strings.TrimSpace(line), "ready")
+
+	c.Cancel(nil)
+	err = c.Wait()
+	assert.Assert(t, errors.Is(err, context.Canceled), err)
+	assert.Equal(t, c.ExitCode(), 3)
+	assert.Equal(t, c.EscalationStep(), 0)
+}
+
+func TestStartResponder(t *testing.T) {
+	script := `test -t 0 || exit 2; printf 'Are you sure? [y/N] '; read a; echo "got $a"`
+	var out bytes.Buffer
+	c, err := StartResponder(context.Background(), exec.Command("sh", "-c", script), &out, []execctx.Response{
+		{Pattern: regexp.MustCompile(`\[y/N\] $`), Reply: "y\n"},
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, c.Wait())
+
+	// The terminal echoes the reply.
+	assert.Equal(t, out.String(), "Are you sure? [y/N] y\r\ngot y\r\n")
+	transcript := c.Result().Transcript
+	assert.Equal(t, len(transcript), 1)
+	assert.Equal(t, transcript[0].Prompt, "Are you sure? [y/N] ")
+}
+
+func TestStartResponderRecorded(t *testing.T) {
+	var rec, out bytes.Buffer
+	r := NewRecorder(&rec, "alice")
+	script := `printf 'Continue? '; read a; echo "got $a"; exit 4`
+	c, err := StartResponder(context.Background(), exec.Command("sh", "-c", script), r.Output(&out), []execctx.Response{
+		{Pattern: regexp.MustCompile(`Continue\? $`), Reply: "yes\n"},
+	}, r.Option(), execctx.WithLabels(map[string]string{"ticket": "OPS-1"}))
+	assert.NilError(t, err)
+	assert.ErrorContains(t, c.Wait(), "exit status 4")
+
+	m := r.Manifest()
+	assert.DeepEqual(t, m.Command, []string{"sh", "-c", script})
+	assert.DeepEqual(t, m.Labels, map[string]string{"ticket": "OPS-1"})
+	assert.Equal(t, m.User, "alice")
+	assert.Equal(t, m.PID, c.Pid())
+	assert.Equal(t, m.ExitCode, 4)
+	assert.Assert(t, !m.Start.IsZero() && !m.End.Before(m.Start), m)
+	assert.Assert(t, m.Chunks > 0)
+	assert.NilError(t, Verify(bytes.NewReader(rec.Bytes()), m))
+}
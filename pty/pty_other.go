@@ -0,0 +1,30 @@
+//go:build !linux
+// +build !linux
+
+package pty
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/cpuguy83/execctx"
+)
+
+// Start starts cmd on a new pseudo-terminal. It is only supported on Linux.
+func Start(ctx context.Context, cmd *exec.Cmd, opts ...execctx.Option) (*execctx.Cmd, *os.File, error) {
+	return nil, nil, ErrUnsupported
+}
+
+// StartResponder is like `Start`, but answers the prompts of the command
+// with responses. It is only supported on Linux.
+func StartResponder(ctx context.Context, cmd *exec.Cmd, out io.Writer, responses []execctx.Response, opts ...execctx.Option) (*execctx.Cmd, error) {
+	return nil, ErrUnsupported
+}
+
+// Setsize sets the size of the terminal, in characters. It is only supported
+// on Linux.
+func Setsize(master *os.File, rows, cols uint16) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,257 @@
+package pty
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cpuguy83/execctx"
+)
+
+// Streams of a `Chunk`.
+const (
+	StreamOutput = "o"
+	StreamInput  = "i"
+)
+
+// Chunk is a piece of a recorded session. A recording is a sequence of
+// chunks, one JSON object per line.
+type Chunk struct {
+	// Seq is the position of the chunk in the recording, starting at 1.
+	Seq int `json:"seq"`
+	// Time is when the chunk was recorded.
+	Time time.Time `json:"time"`
+	// Stream is `StreamOutput` for output of the session and `StreamInput`
+	// for input to it.
+	Stream string `json:"stream"`
+	Data   []byte `json:"data"`
+	// Hash is the hex SHA-256 of the hash of the previous chunk, or 32 zero
+	// bytes for the first chunk, followed by the other fields of the chunk.
+	Hash string `json:"hash"`
+}
+
+// Manifest describes a recorded session, see `Recorder`.
+type Manifest struct {
+	// User identifies who ran the session, as passed to `NewRecorder`.
+	User string `json:"user,omitempty"`
+	// Command is the command line of the session.
+	Command []string `json:"command"`
+	// Labels are the labels of the command, see `execctx.WithLabels`.
+	Labels map[string]string `json:"labels,omitempty"`
+	PID    int               `json:"pid"`
+	Start  time.Time         `json:"start"`
+	// End is the zero time while the command is running.
+	End      time.Time `json:"end"`
+	ExitCode int       `json:"exit_code"`
+	// Chunks is the number of chunks in the recording.
+	Chunks int `json:"chunks"`
+	// Head is the hash of the last chunk, which covers all the chunks
+	// before it.
+	Head string `json:"head"`
+	// RecordingSHA256 is the hex SHA-256 of the recording as written.
+	RecordingSHA256 string `json:"recording_sha256"`
+}
+
+// Recorder records a terminal session for audit: the output of the command
+// and the input sent to it are written as timed `Chunk`s, each chained to
+// the one before it by its hash, and a `Manifest` ties the recording to the
+// command, the user, and how the session ended.
+//
+// Changing, dropping, or reordering chunks breaks the chain, which `Verify`
+// detects against the manifest. The manifest itself must be kept where it
+// can't be altered, or be signed, for the recording to be tamper-evident.
+//
+// A Recorder records a single session. It is safe for concurrent use.
+type Recorder struct {
+	mu       sync.Mutex
+	w        io.Writer
+	digest   hash.Hash
+	head     [sha256.Size]byte
+	err      error
+	manifest Manifest
+}
+
+// NewRecorder returns a recorder which writes the recording to w. user
+// identifies who runs the session, it is recorded in the manifest.
+func NewRecorder(w io.Writer, user string) *Recorder {
+	d := sha256.New()
+	return &Recorder{
+		w:        io.MultiWriter(w, d),
+		digest:   d,
+		manifest: Manifest{User: user},
+	}
+}
+
+// Option returns an option which records the start and the end of the
+// command in the manifest.
+func (r *Recorder) Option() execctx.Option {
+	return func(c *execctx.Cmd) {
+		execctx.OnStarted(func(c *execctx.Cmd) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.manifest.Command = c.Snapshot().Args
+			r.manifest.Labels = c.Labels()
+			r.manifest.PID = c.Pid()
+			r.manifest.Start = c.StartTime().UTC()
+		})(c)
+		execctx.OnExit(func(res execctx.Result) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.manifest.End = time.Now().UTC()
+			r.manifest.ExitCode = res.ExitCode
+		})(c)
+	}
+}
+
+// Output returns a writer which records what is written to it as output of
+// the session, and passes it on to w, if it is not nil. Writes fail once the
+// recording can't be written, so the session doesn't go on unrecorded.
+func (r *Recorder) Output(w io.Writer) io.Writer {
+	return &recordWriter{r: r, stream: StreamOutput, w: w}
+}
+
+// Input is like `Output` but records input sent to the session, w is
+// usually the master of the terminal.
+func (r *Recorder) Input(w io.Writer) io.Writer {
+	return &recordWriter{r: r, stream: StreamInput, w: w}
+}
+
+// Manifest returns the manifest of the session. It is complete once the
+// command exited and nothing more is written to the recorder.
+func (r *Recorder) Manifest() Manifest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m := r.manifest
+	m.Head = hex.EncodeToString(r.head[:])
+	m.RecordingSHA256 = hex.EncodeToString(r.digest.Sum(nil))
+	return m
+}
+
+// Err returns the error which stopped the recording, if any.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *Recorder) record(stream string, p []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+
+	ch := Chunk{
+		Seq:    r.manifest.Chunks + 1,
+		Time:   time.Now().UTC(),
+		Stream: stream,
+		Data:   p,
+	}
+	head := chainHash(r.head, ch)
+	ch.Hash = hex.EncodeToString(head[:])
+	line, err := json.Marshal(ch)
+	if err == nil {
+		_, err = r.w.Write(append(line, '\n'))
+	}
+	if err != nil {
+		r.err = fmt.Errorf("pty: error writing recording: %w", err)
+		return r.err
+	}
+	r.head = head
+	r.manifest.Chunks++
+	return nil
+}
+
+// chainHash returns the hash of ch chained to prev.
+func chainHash(prev [sha256.Size]byte, ch Chunk) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write(prev[:])
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(ch.Seq))
+	h.Write(buf[:])
+	binary.BigEndian.PutUint64(buf[:], uint64(ch.Time.UnixNano()))
+	h.Write(buf[:])
+	h.Write([]byte(ch.Stream))
+	binary.BigEndian.PutUint64(buf[:], uint64(len(ch.Data)))
+	h.Write(buf[:])
+	h.Write(ch.Data)
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+type recordWriter struct {
+	r      *Recorder
+	stream string
+	w      io.Writer
+}
+
+func (w *recordWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := w.r.record(w.stream, p); err != nil {
+		return 0, err
+	}
+	if w.w == nil {
+		return len(p), nil
+	}
+	return w.w.Write(p)
+}
+
+// ErrRecordingMismatch is returned by `Verify` when a recording does not
+// match its manifest.
+var ErrRecordingMismatch = errors.New("pty: recording does not match manifest")
+
+// Verify checks that the recording read from rec is the one described by m:
+// that every chunk is chained to the one before it, and that the number of
+// chunks, the last hash, and the digest of the recording match the manifest.
+func Verify(rec io.Reader, m Manifest) error {
+	digest := sha256.New()
+	br := bufio.NewReader(io.TeeReader(rec, digest))
+
+	var head [sha256.Size]byte
+	var n int
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			var ch Chunk
+			if jerr := json.Unmarshal(line, &ch); jerr != nil {
+				return fmt.Errorf("%w: chunk %d: %v", ErrRecordingMismatch, n+1, jerr)
+			}
+			n++
+			if ch.Seq != n {
+				return fmt.Errorf("%w: chunk %d has sequence number %d", ErrRecordingMismatch, n, ch.Seq)
+			}
+			head = chainHash(head, ch)
+			if hex.EncodeToString(head[:]) != ch.Hash {
+				return fmt.Errorf("%w: chunk %d does not match its hash", ErrRecordingMismatch, n)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case n != m.Chunks:
+		return fmt.Errorf("%w: %d chunks, manifest has %d", ErrRecordingMismatch, n, m.Chunks)
+	case hex.EncodeToString(head[:]) != m.Head:
+		return fmt.Errorf("%w: last hash differs", ErrRecordingMismatch)
+	case hex.EncodeToString(digest.Sum(nil)) != m.RecordingSHA256:
+		return fmt.Errorf("%w: recording digest differs", ErrRecordingMismatch)
+	}
+	return nil
+}
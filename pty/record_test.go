@@ -0,0 +1,56 @@
+package pty
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	var rec, out, in bytes.Buffer
+	r := NewRecorder(&rec, "alice")
+	_, err := r.Output(&out).Write([]byte("$ "))
+	assert.NilError(t, err)
+	_, err = r.Input(&in).Write([]byte("ls\n"))
+	assert.NilError(t, err)
+	_, err = r.Output(nil).Write([]byte("file\n"))
+	assert.NilError(t, err)
+
+	assert.Equal(t, out.String(), "$ ")
+	assert.Equal(t, in.String(), "ls\n")
+
+	m := r.Manifest()
+	assert.Equal(t, m.User, "alice")
+	assert.Equal(t, m.Chunks, 3)
+	assert.NilError(t, Verify(bytes.NewReader(rec.Bytes()), m))
+
+	// Any change to the recording is detected.
+	lines := strings.SplitAfter(rec.String(), "\n")
+	for name, tampered := range map[string]string{
+		"changed":   strings.Replace(rec.String(), "bHMK", "cm0K", 1), // "ls\n" to "rm\n"
+		"dropped":   lines[0] + lines[2],
+		"reordered": lines[1] + lines[0] + lines[2],
+		"truncated": lines[0] + lines[1],
+	} {
+		err := Verify(strings.NewReader(tampered), m)
+		assert.Assert(t, errors.Is(err, ErrRecordingMismatch), "%s: %v", name, err)
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRecorderWriteError(t *testing.T) {
+	var out bytes.Buffer
+	r := NewRecorder(failWriter{}, "")
+	_, err := r.Output(&out).Write([]byte("secret"))
+	assert.ErrorContains(t, err, "disk full")
+	assert.Equal(t, out.Len(), 0)
+	assert.ErrorContains(t, r.Err(), "disk full")
+}
@@ -0,0 +1,41 @@
+package execctx
+
+import "errors"
+
+// errProcessDone is returned when signalling a process which was already
+// reaped. It matches the error of os.Process.
+var errProcessDone = errors.New("os: process already finished")
+
+// whileRunning calls f unless the process was reaped, and keeps the process
+// from being reaped until f returns, so the pid, or process group, f signals
+// can't have been reused by another process. It reports whether f was
+// called.
+func (c *Cmd) whileRunning(f func()) bool {
+	c.reapMu.Lock()
+	defer c.reapMu.Unlock()
+	if c.reaped {
+		return false
+	}
+	f()
+	return true
+}
+
+// reap waits for the process like exec.Cmd.Wait, marking it as reaped
+// before its pid is released.
+func (c *Cmd) reap() error {
+	if c.cmd.Process != nil && waitExited(c.cmd.Process.Pid) {
+		c.setReaped()
+		return c.cmd.Wait()
+	}
+	// The exit can't be waited for without reaping the process on this
+	// platform, only signals sent after Wait returned are prevented.
+	err := c.cmd.Wait()
+	c.setReaped()
+	return err
+}
+
+func (c *Cmd) setReaped() {
+	c.reapMu.Lock()
+	c.reaped = true
+	c.reapMu.Unlock()
+}
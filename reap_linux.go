@@ -0,0 +1,32 @@
+package execctx
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+const (
+	_P_PID    = 1
+	_WNOWAIT  = 0x1000000
+	siginfoSz = 128
+)
+
+// waitExited blocks until the process exited, without reaping it. It
+// reports false if that is not supported.
+func waitExited(pid int) bool {
+	var siginfo [siginfoSz]byte
+	for {
+		_, _, errno := syscall.Syscall6(syscall.SYS_WAITID, _P_PID, uintptr(pid), uintptr(unsafe.Pointer(&siginfo)), syscall.WEXITED|_WNOWAIT, 0, 0)
+		switch errno {
+		case 0:
+			return true
+		case syscall.EINTR:
+			continue
+		case syscall.ECHILD:
+			// Already reaped, Wait reports the error.
+			return true
+		default:
+			return false
+		}
+	}
+}
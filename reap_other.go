@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package execctx
+
+// waitExited blocks until the process exited, without reaping it. It
+// reports false if that is not supported.
+func waitExited(pid int) bool {
+	return false
+}
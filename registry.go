@@ -0,0 +1,65 @@
+package execctx
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+var registry = struct {
+	mu   sync.Mutex
+	cmds map[*Cmd]struct{}
+}{cmds: make(map[*Cmd]struct{})}
+
+func register(c *Cmd) {
+	registry.mu.Lock()
+	registry.cmds[c] = struct{}{}
+	registry.mu.Unlock()
+}
+
+func unregister(c *Cmd) {
+	registry.mu.Lock()
+	delete(registry.cmds, c)
+	registry.mu.Unlock()
+}
+
+// running reports whether c was started and `Wait` has not returned yet.
+func running(c *Cmd) bool {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	_, ok := registry.cmds[c]
+	return ok
+}
+
+// Running returns all commands which have been started and for which `Wait`
+// has not returned yet, ordered by start time.
+//
+// Commands which are never waited on stay in the list even after the process
+// has exited.
+func Running() []*Cmd {
+	registry.mu.Lock()
+	cmds := make([]*Cmd, 0, len(registry.cmds))
+	for c := range registry.cmds {
+		cmds = append(cmds, c)
+	}
+	registry.mu.Unlock()
+
+	sort.Slice(cmds, func(i, j int) bool {
+		return cmds[i].startTime.Before(cmds[j].startTime)
+	})
+	return cmds
+}
+
+// Pid returns the process ID of the command, or 0 if it was not started.
+func (c *Cmd) Pid() int {
+	if c.cmd.Process == nil {
+		return 0
+	}
+	return c.cmd.Process.Pid
+}
+
+// StartTime returns the time the command was started, or the zero time if it
+// was not started.
+func (c *Cmd) StartTime() time.Time {
+	return c.startTime
+}
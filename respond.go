@@ -0,0 +1,246 @@
+package execctx
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Response is a reply to send to the stdin of a command when its output
+// matches a pattern.
+type Response struct {
+	// Pattern is matched against the output of the command since the
+	// last reply was sent.
+	Pattern *regexp.Regexp
+	// Reply is written to stdin when Pattern matches, e.g. "y\n".
+	Reply string
+	// Max is how many times this reply may be sent. 0 means once.
+	Max int
+}
+
+// Exchange is an entry in the transcript of a responder.
+type Exchange struct {
+	Time time.Time `json:"time"`
+	// Prompt is the output of the command which matched, limited to the
+	// last 4KiB.
+	Prompt string `json:"prompt"`
+	Reply  string `json:"reply"`
+}
+
+const (
+	defaultResponderLimit = 100
+	maxPromptBuffer       = 64 << 10
+	maxTranscriptPrompt   = 4 << 10
+)
+
+// WithResponder answers prompts from the command. The stdout and stderr of
+// the command are matched against the responses in order and the reply of
+// the first match is written to stdin.
+// This replaces any stdin set on the command. Stdin is closed once the reply
+// limit is reached or every response was sent its maximum number of times,
+// so a later prompt gets EOF rather than waiting forever.
+//
+// Prompts are matched on the output after any filters are applied.
+// The exchanges are recorded and can be inspected with `Transcript`, or in
+// the `Result` of the command.
+//
+// For a command run on a pseudo-terminal, see `WithResponderTerminal`.
+func WithResponder(responses ...Response) Option {
+	return func(c *Cmd) {
+		c.responses = append(c.responses, responses...)
+	}
+}
+
+// WithResponderLimit sets how many replies are sent in total before stdin is
+// closed. The default is 100.
+func WithResponderLimit(n int) Option {
+	return func(c *Cmd) {
+		c.responderLimit = n
+	}
+}
+
+// WithResponderTerminal makes the responder set with `WithResponder` match
+// the output read from term and write its replies to term, rather than
+// matching the output of the command and replying on its stdin. This is the
+// variant for commands run on a pseudo-terminal, with term the master side
+// of the terminal, see `pty.StartResponder`. The stdio of the command are
+// left alone.
+//
+// The output read from term is copied to out, if it is not nil. Once the
+// reply limit is reached no more replies are sent, but term is not closed.
+// `Wait` waits for reading term to end, which happens once every process
+// holding the terminal exited, or for a short while if the command was
+// cancelled.
+func WithResponderTerminal(term io.ReadWriter, out io.Writer) Option {
+	return func(c *Cmd) {
+		c.responderTerm = term
+		c.responderOut = out
+	}
+}
+
+// Transcript returns the prompts answered by the responder so far.
+func (c *Cmd) Transcript() []Exchange {
+	if c.responder == nil {
+		return nil
+	}
+	r := c.responder
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Exchange(nil), r.transcript...)
+}
+
+type responder struct {
+	mu        sync.Mutex
+	responses []Response
+	counts    []int
+	limit     int
+	sent      int
+	buf       []byte
+	stdin     io.Writer
+	// term is set if stdin is a terminal shared with the output.
+	term       bool
+	transcript []Exchange
+}
+
+func (c *Cmd) setupResponder() error {
+	if len(c.responses) == 0 {
+		return nil
+	}
+
+	limit := c.responderLimit
+	if limit <= 0 {
+		limit = defaultResponderLimit
+	}
+	c.responder = &responder{
+		responses: c.responses,
+		counts:    make([]int, len(c.responses)),
+		limit:     limit,
+	}
+
+	if c.responderTerm != nil {
+		c.setupTerminalResponder()
+		return nil
+	}
+
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	c.responder.stdin = pw
+	c.cmd.Stdin = pr
+
+	c.onStarted(func() { pr.Close() })
+	c.onRelease(c.responder.close)
+	return nil
+}
+
+// setupTerminalResponder feeds the responder the output read from the
+// terminal, see `WithResponderTerminal`.
+func (c *Cmd) setupTerminalResponder() {
+	c.responder.stdin = c.responderTerm
+	c.responder.term = true
+	out := c.responderOut
+	if out == nil {
+		out = ioutil.Discard
+	}
+
+	done := make(chan struct{})
+	c.onProcessStart(func() {
+		go func() {
+			defer close(done)
+			io.Copy(io.MultiWriter(out, c.responder), c.responderTerm)
+		}()
+	})
+	c.onRelease(func() {
+		if c.cmd.Process == nil {
+			return
+		}
+		select {
+		case <-done:
+			return
+		case <-c.cancelled:
+		}
+		timer := time.NewTimer(cancelDrainTimeout)
+		defer timer.Stop()
+		select {
+		case <-done:
+		case <-timer.C:
+		}
+	})
+	c.onRelease(c.responder.stop)
+}
+
+func (r *responder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stdin == nil {
+		return len(p), nil
+	}
+
+	r.buf = append(r.buf, p...)
+	if len(r.buf) > maxPromptBuffer {
+		r.buf = append(r.buf[:0], r.buf[len(r.buf)-maxPromptBuffer:]...)
+	}
+
+	for i, resp := range r.responses {
+		max := resp.Max
+		if max <= 0 {
+			max = 1
+		}
+		if r.counts[i] >= max || !resp.Pattern.Match(r.buf) {
+			continue
+		}
+		r.counts[i]++
+		r.sent++
+
+		prompt := r.buf
+		if len(prompt) > maxTranscriptPrompt {
+			prompt = prompt[len(prompt)-maxTranscriptPrompt:]
+		}
+		r.transcript = append(r.transcript, Exchange{Time: time.Now(), Prompt: string(prompt), Reply: resp.Reply})
+		r.buf = r.buf[:0]
+
+		if _, err := io.WriteString(r.stdin, resp.Reply); err != nil || r.sent >= r.limit || r.exhausted() {
+			r.closeLocked()
+		}
+		break
+	}
+	return len(p), nil
+}
+
+// exhausted reports whether every response was sent its maximum number of
+// times.
+func (r *responder) exhausted() bool {
+	for i, resp := range r.responses {
+		if r.counts[i] == 0 || r.counts[i] < resp.Max {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *responder) close() {
+	r.mu.Lock()
+	r.closeLocked()
+	r.mu.Unlock()
+}
+
+// stop stops replying, without closing stdin.
+func (r *responder) stop() {
+	r.mu.Lock()
+	r.stdin = nil
+	r.mu.Unlock()
+}
+
+// closeLocked stops replying. Stdin is closed unless it is a terminal,
+// which the command may still use.
+func (r *responder) closeLocked() {
+	if c, ok := r.stdin.(io.Closer); ok && !r.term {
+		c.Close()
+	}
+	r.stdin = nil
+}
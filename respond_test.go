@@ -0,0 +1,39 @@
+package execctx
+
+import (
+	"context"
+	"os/exec"
+	"regexp"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestResponder(t *testing.T) {
+	script := `printf 'Are you sure? [y/N] '; read a; echo "got $a"; printf 'Again? '; read b; echo "got $b"`
+	c := FromCmd(context.Background(), exec.Command("sh", "-c", script), nil,
+		WithResponder(Response{Pattern: regexp.MustCompile(`\[y/N\] $`), Reply: "y\n"}),
+	)
+	out, err := c.Output(context.Background())
+	assert.NilError(t, err)
+	// The second prompt has no response so it reads EOF.
+	assert.Equal(t, string(out), "Are you sure? [y/N] got y\nAgain? got \n")
+
+	transcript := c.Transcript()
+	assert.Equal(t, len(transcript), 1)
+	assert.DeepEqual(t, c.Result().Transcript, transcript)
+	assert.Equal(t, transcript[0].Prompt, "Are you sure? [y/N] ")
+	assert.Equal(t, transcript[0].Reply, "y\n")
+}
+
+func TestResponderLimit(t *testing.T) {
+	script := `for i in 1 2 3; do echo "continue?" >&2; read a || exit 3; done`
+	c := FromCmd(context.Background(), exec.Command("sh", "-c", script), nil,
+		WithResponder(Response{Pattern: regexp.MustCompile(`continue\?`), Reply: "y\n", Max: 10}),
+		WithResponderLimit(2),
+	)
+	err := c.Run()
+	assert.ErrorContains(t, err, "exit status 3")
+	assert.Equal(t, c.ExitCode(), 3)
+	assert.Equal(t, len(c.Transcript()), 2)
+}
@@ -0,0 +1,104 @@
+package execctx
+
+import (
+	"os"
+	"time"
+)
+
+// Result describes a finished run of a command, see `RunResult`.
+type Result struct {
+	// ExitCode is the exit code of the process, as returned by `ExitCode`.
+	ExitCode int
+	// Signal is the signal which terminated the process, nil if it exited
+	// on its own.
+	Signal os.Signal
+	// Cause is the reason the command was cancelled, nil if it was not.
+	Cause error
+
+	StartTime time.Time
+	EndTime   time.Time
+	Duration  time.Duration
+
+	// Stdout and Stderr hold the captured output. Only streams which were
+	// not set on the command are captured, subject to `WithStdoutLimit` and
+	// `WithStderrLimit`.
+	Stdout []byte
+	Stderr []byte
+	// StdoutTruncated and StderrTruncated report whether captured output was
+	// cut short because it exceeded its limit.
+	StdoutTruncated bool
+	StderrTruncated bool
+
+	// Files are the files opened by the command when it was run with
+	// `WithTracer`.
+	Files []FileAccess
+
+	// ProgramSHA256 is the digest of the program verified with
+	// `WithExpectedChecksum`, see `Cmd.ProgramSHA256`.
+	ProgramSHA256 string
+
+	// Samples are the samples of the process state taken with
+	// `WithSampling`.
+	Samples []Sample
+
+	// Transcript holds the prompts answered by the responder set with
+	// `WithResponder`.
+	Transcript []Exchange
+}
+
+// RunResult runs the command, waits for it to exit, and returns a `Result`
+// describing the run along with the error from `Run`.
+// The result is filled in as far as possible even when the command fails,
+// if it could not be started only ExitCode, which is -1, is set.
+func (c *Cmd) RunResult() (Result, error) {
+	c.savePristine()
+	if c.cmd.Stdout == nil {
+		c.stdoutCapture = newCapture(c.stdoutLimit)
+		c.cmd.Stdout = c.stdoutCapture
+		c.ownPipes = true
+	}
+	if c.cmd.Stderr == nil {
+		c.stderrCapture = newCapture(c.stderrLimit)
+		c.cmd.Stderr = c.stderrCapture
+		c.ownPipes = true
+	}
+
+	err := c.Run()
+	return c.Result(), err
+}
+
+// Result describes the run of the command once `Wait` has returned.
+// Captured output is included when the command was run with `Output` or
+// `RunResult`.
+func (c *Cmd) Result() Result {
+	r := Result{ExitCode: c.ExitCode()}
+	if c.cmd.ProcessState == nil {
+		return r
+	}
+
+	if sig, ok := exitSignal(c); ok {
+		r.Signal = sig
+	}
+	c.mu.Lock()
+	if c.handled {
+		r.Cause = c.cause
+	}
+	c.mu.Unlock()
+
+	r.StartTime = c.startTime
+	r.EndTime = c.endTime
+	r.Duration = r.EndTime.Sub(r.StartTime)
+
+	if c.stdoutCapture != nil {
+		r.Stdout = c.stdoutCapture.Bytes()
+	}
+	if c.stderrCapture != nil {
+		r.Stderr = c.stderrCapture.Bytes()
+	}
+	r.StdoutTruncated, r.StderrTruncated = c.Truncated()
+	r.Files = append([]FileAccess(nil), c.fileAccesses...)
+	r.ProgramSHA256 = c.programSHA256
+	r.Samples = c.Samples()
+	r.Transcript = c.Transcript()
+	return r
+}
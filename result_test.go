@@ -0,0 +1,44 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRunResult(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a posix shell")
+	}
+
+	c := FromCmd(context.Background(), exec.Command("sh", "-c", "echo out; echo err >&2; exit 3"), nil)
+	r, err := c.RunResult()
+	assert.ErrorContains(t, err, "exit status 3")
+	assert.Equal(t, r.ExitCode, 3)
+	assert.Equal(t, r.Signal, nil)
+	assert.Equal(t, r.Cause, nil)
+	assert.Equal(t, string(r.Stdout), "out\n")
+	assert.Equal(t, string(r.Stderr), "err\n")
+	assert.Assert(t, !r.StartTime.IsZero())
+	assert.Equal(t, r.Duration, r.EndTime.Sub(r.StartTime))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	c = FromCmd(ctx, exec.Command("sh", "-c", "echo 0123456789; exec sleep 99999"), nil, WithStdoutLimit(4, CaptureHead))
+	r, err = c.RunResult()
+	assert.Assert(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.Equal(t, r.Signal, syscall.SIGKILL)
+	assert.Equal(t, r.Cause, context.DeadlineExceeded)
+	assert.Equal(t, string(r.Stdout), "0123")
+	assert.Assert(t, r.StdoutTruncated)
+
+	r, err = FromCmd(context.Background(), exec.Command("/nonexistent"), nil).RunResult()
+	assert.Assert(t, err != nil)
+	assert.Equal(t, r.ExitCode, -1)
+}
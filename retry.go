@@ -0,0 +1,179 @@
+package execctx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// RetryPolicy controls how `Retry` re-runs a failed command.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of times the command is run, including
+	// the first attempt. Values below 1 are treated as 1.
+	MaxAttempts int
+	// Backoff returns how long to wait before the given attempt, starting at
+	// 2 for the first retry. If nil, attempts are retried immediately.
+	// See `ExponentialBackoff`.
+	Backoff func(attempt int) time.Duration
+	// RetryableExitCodes are exit codes, as reported by `Cmd.ExitCode`, for
+	// which the command is retried.
+	RetryableExitCodes []int
+	// RetryOn is called with the error from a failed attempt and reports
+	// whether the command should be retried.
+	RetryOn func(error) bool
+
+	// StdinMemory is how many bytes of stdin are kept in memory to replay
+	// them to later attempts, input beyond that is spilled to a temporary
+	// file. Defaults to 1 MiB.
+	StdinMemory int64
+	// StdinLimit is the most stdin, in bytes, which is recorded for replay.
+	// Zero means no limit.
+	StdinLimit int64
+}
+
+// ExponentialBackoff returns a backoff function for `RetryPolicy` which
+// waits base before the first retry and doubles the wait for every retry
+// after that, up to max.
+func ExponentialBackoff(base, max time.Duration) func(int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 2; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			return max
+		}
+		return d
+	}
+}
+
+// Retry runs the command described by spec, creating and running it again
+// when it fails as allowed by policy. A failed attempt is retried if its
+// exit code is one of `RetryableExitCodes` or `RetryOn` returns true for its
+// error. If neither is set every failure is retried.
+//
+// The options are applied to the command of every attempt, so writers passed
+// in for stdout and stderr see the output of all attempts.
+//
+// Every attempt is fed the same stdin. What the first attempts read from a
+// reader set with `WithStdinReader`, `WithStdinString`, `WithStdinBytes`, or
+// `WithStdin` is recorded, up to `StdinLimit`, and replayed to the next
+// ones. An *os.File which can be seeked, such as a regular file, is instead
+// rewound to where it was before the first attempt. If the input can't be
+// recorded, because it exceeds the limit or the temporary file can't be
+// written, the attempt still gets all of its input but is not retried, and
+// a `*StdinReplayError` is returned. Stdin fed from a channel with
+// `WithStdinChan` can't be replayed, Retry returns `ErrStdinNotReplayable`
+// without running the command when more than one attempt is allowed.
+//
+// Once ctx is done no more attempts are made, if it is done while waiting
+// between attempts its error is returned. Otherwise the error from the last
+// attempt is returned.
+func Retry(ctx context.Context, spec Spec, policy RetryPolicy, opts ...Option) error {
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+
+	var replay stdinReplay
+	defer replay.close()
+
+	for attempt := 1; ; attempt++ {
+		c := spec.command(ctx, nil, opts...)
+		if policy.MaxAttempts > 1 {
+			if err := replay.setup(c, &policy); err != nil {
+				return fmt.Errorf("spec %q: %w", spec.Name, err)
+			}
+		}
+		err := c.Run()
+		if err == nil || attempt >= policy.MaxAttempts || ctx.Err() != nil || !policy.retryable(c, err) {
+			return err
+		}
+		if rerr := replay.recordErr(); rerr != nil {
+			return &StdinReplayError{Err: err, Cause: rerr}
+		}
+
+		var wait time.Duration
+		if policy.Backoff != nil {
+			wait = policy.Backoff(attempt + 1)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *RetryPolicy) retryable(c *Cmd, err error) bool {
+	if len(p.RetryableExitCodes) == 0 && p.RetryOn == nil {
+		return true
+	}
+
+	if code := c.ExitCode(); code >= 0 {
+		for _, rc := range p.RetryableExitCodes {
+			if code == rc {
+				return true
+			}
+		}
+	}
+	return p.RetryOn != nil && p.RetryOn(err)
+}
+
+// stdinReplay feeds every attempt of `Retry` the same stdin.
+type stdinReplay struct {
+	tape *stdinTape
+	// file and offset are set when stdin is a file which is rewound instead.
+	file   *os.File
+	offset int64
+}
+
+// setup sets the stdin of the command of an attempt. The options of every
+// attempt set the same stdin, which is recorded on the first.
+func (r *stdinReplay) setup(c *Cmd, policy *RetryPolicy) error {
+	switch {
+	case c.stdinChan != nil:
+		return fmt.Errorf("%w: stdin is fed from a channel", ErrStdinNotReplayable)
+	case r.tape != nil:
+		c.stdinReader = r.tape.reader()
+		c.cmd.Stdin = nil
+		return nil
+	case r.file != nil:
+		_, err := r.file.Seek(r.offset, io.SeekStart)
+		return err
+	}
+
+	src := c.stdinReader
+	if src == nil {
+		if f, ok := c.cmd.Stdin.(*os.File); ok {
+			if off, err := f.Seek(0, io.SeekCurrent); err == nil {
+				r.file, r.offset = f, off
+				return nil
+			}
+		}
+		src = c.cmd.Stdin
+	}
+	if src == nil {
+		return nil
+	}
+	r.tape = newStdinTape(src, policy.StdinMemory, policy.StdinLimit)
+	c.stdinReader = r.tape.reader()
+	c.cmd.Stdin = nil
+	return nil
+}
+
+func (r *stdinReplay) recordErr() error {
+	if r.tape == nil {
+		return nil
+	}
+	return r.tape.recordErr()
+}
+
+func (r *stdinReplay) close() {
+	if r.tape != nil {
+		r.tape.close()
+	}
+}
@@ -0,0 +1,121 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRetry(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a posix shell")
+	}
+
+	dir, err := ioutil.TempDir("", "execctx-retry")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	count := filepath.Join(dir, "count")
+
+	// Fails with exit code 3 until it has been run three times.
+	spec := Spec{Args: []string{"sh", "-c", `echo x >> "$1"; test $(wc -l < "$1") -ge 3 || exit 3`, "sh", count}}
+	attempts := func() int {
+		data, err := ioutil.ReadFile(count)
+		assert.NilError(t, err)
+		return strings.Count(string(data), "\n")
+	}
+
+	policy := RetryPolicy{MaxAttempts: 5, Backoff: ExponentialBackoff(time.Millisecond, 10*time.Millisecond), RetryableExitCodes: []int{3}}
+	assert.NilError(t, Retry(context.Background(), spec, policy))
+	assert.Equal(t, attempts(), 3)
+
+	// Gives up after MaxAttempts.
+	os.Remove(count)
+	policy.MaxAttempts = 2
+	err = Retry(context.Background(), spec, policy)
+	assert.ErrorContains(t, err, "exit status 3")
+	assert.Equal(t, attempts(), 2)
+
+	// Exit codes which are not retryable are returned right away.
+	os.Remove(count)
+	policy = RetryPolicy{MaxAttempts: 5, RetryableExitCodes: []int{4}}
+	assert.ErrorContains(t, Retry(context.Background(), spec, policy), "exit status 3")
+	assert.Equal(t, attempts(), 1)
+
+	// The context is honoured between attempts.
+	os.Remove(count)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	policy = RetryPolicy{MaxAttempts: 5, Backoff: func(int) time.Duration { return time.Minute }}
+	assert.Equal(t, Retry(ctx, spec, policy), context.DeadlineExceeded)
+	assert.Equal(t, attempts(), 1)
+}
+
+func TestRetryStdin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a posix shell")
+	}
+
+	dir, err := ioutil.TempDir("", "execctx-retry")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	// Appends its stdin to out and fails until it has been run twice.
+	spec := Spec{Args: []string{"sh", "-c", `cat >> "$1"; test $(wc -l < "$1") -ge 4 || exit 3`, "sh", out}}
+	read := func() string {
+		data, err := ioutil.ReadFile(out)
+		assert.NilError(t, err)
+		return string(data)
+	}
+
+	// A reader which can't be seeked, spilled to disk past 4 bytes.
+	policy := RetryPolicy{MaxAttempts: 3, StdinMemory: 4}
+	stdin := io.MultiReader(strings.NewReader("hello\n"), strings.NewReader("world\n"))
+	assert.NilError(t, Retry(context.Background(), spec, policy, WithStdinReader(stdin)))
+	assert.Equal(t, read(), "hello\nworld\nhello\nworld\n")
+
+	// A file is rewound.
+	os.Remove(out)
+	in := filepath.Join(dir, "in")
+	assert.NilError(t, ioutil.WriteFile(in, []byte("skip\nhello\nworld\n"), 0644))
+	f, err := os.Open(in)
+	assert.NilError(t, err)
+	defer f.Close()
+	_, err = f.Seek(5, io.SeekStart)
+	assert.NilError(t, err)
+	assert.NilError(t, Retry(context.Background(), spec, policy, WithStdin(f)))
+	assert.Equal(t, read(), "hello\nworld\nhello\nworld\n")
+
+	// Input over the limit is still fed to the attempt, but not retried.
+	os.Remove(out)
+	policy.StdinLimit = 8
+	stdin = io.MultiReader(strings.NewReader("hello\n"), strings.NewReader("world\n"))
+	err = Retry(context.Background(), spec, policy, WithStdinReader(stdin))
+	assert.Assert(t, errors.Is(err, ErrStdinNotReplayable), err)
+	assert.ErrorContains(t, err, "exit status 3")
+	assert.Equal(t, read(), "hello\nworld\n")
+
+	// Stdin from a channel is rejected up front.
+	os.Remove(out)
+	err = Retry(context.Background(), spec, policy, WithStdinChan(make(chan []byte)))
+	assert.Assert(t, errors.Is(err, ErrStdinNotReplayable), err)
+	_, err = os.Stat(out)
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	b := ExponentialBackoff(time.Second, 5*time.Second)
+	assert.Equal(t, b(2), time.Second)
+	assert.Equal(t, b(3), 2*time.Second)
+	assert.Equal(t, b(4), 4*time.Second)
+	assert.Equal(t, b(5), 5*time.Second)
+}
@@ -0,0 +1,80 @@
+package execctx
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RlimitResource is a resource limited with `WithRlimits`.
+type RlimitResource int
+
+const (
+	// RlimitCPU limits the CPU time of the process, in seconds.
+	RlimitCPU RlimitResource = iota
+	// RlimitFSIZE limits the size of files the process creates, in bytes.
+	RlimitFSIZE
+	// RlimitDATA limits the size of the data segment of the process, in
+	// bytes.
+	RlimitDATA
+	// RlimitSTACK limits the size of the stack of the process, in bytes.
+	RlimitSTACK
+	// RlimitCORE limits the size of core dumps, in bytes.
+	RlimitCORE
+	// RlimitNOFILE limits the number of open files.
+	RlimitNOFILE
+	// RlimitAS limits the address space of the process, in bytes.
+	RlimitAS
+)
+
+// rlimitFlags are the ulimit flags of the resources and the unit the shell
+// takes their value in.
+var rlimitFlags = map[RlimitResource]struct {
+	flag string
+	unit uint64
+}{
+	RlimitCPU:    {"-t", 1},
+	RlimitFSIZE:  {"-f", 512},
+	RlimitDATA:   {"-d", 1024},
+	RlimitSTACK:  {"-s", 1024},
+	RlimitCORE:   {"-c", 512},
+	RlimitNOFILE: {"-n", 1},
+	RlimitAS:     {"-v", 1024},
+}
+
+// Rlimit is a resource limit, see `WithRlimits`.
+type Rlimit struct {
+	Resource RlimitResource
+	// Limit is both the soft and hard limit. Sizes are rounded down to what
+	// the shell can express, 512 bytes or a kilobyte.
+	Limit uint64
+}
+
+// WithRlimits sets resource limits on the process, which are inherited by
+// its children. This bounds runaway processes on systems where `WithCgroup`
+// is not available, but unlike cgroups the limits apply to every process on
+// its own.
+//
+// Like `WithUmask` the limits are set by a /bin/sh shim with ulimit before
+// it execs the command. Limits can only be raised up to the current hard
+// limit without privileges.
+// Resource limits are not supported on Windows.
+func WithRlimits(limits ...Rlimit) Option {
+	return func(c *Cmd) {
+		c.rlimits = append(c.rlimits, limits...)
+	}
+}
+
+var errRlimitResource = errors.New("execctx: unknown rlimit resource")
+
+// rlimitSetup returns the ulimit commands for the limits of the command.
+func (c *Cmd) rlimitSetup() ([]string, error) {
+	var setup []string
+	for _, l := range c.rlimits {
+		f, ok := rlimitFlags[l.Resource]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d", errRlimitResource, l.Resource)
+		}
+		setup = append(setup, fmt.Sprintf("ulimit %s %d", f.flag, l.Limit/f.unit))
+	}
+	return setup, nil
+}
@@ -0,0 +1,30 @@
+package execctx
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestWithRlimits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("rlimits are not supported on windows")
+	}
+
+	cmd := exec.Command("sh", "-c", `ulimit -n; ulimit -t; ulimit -v`)
+	var out strings.Builder
+	c := FromCmd(context.Background(), cmd, nil, WithStdout(&out), WithRlimits(
+		Rlimit{Resource: RlimitNOFILE, Limit: 64},
+		Rlimit{Resource: RlimitCPU, Limit: 10},
+		Rlimit{Resource: RlimitAS, Limit: 1 << 30},
+	))
+	assert.NilError(t, c.Run())
+	assert.Equal(t, out.String(), "64\n10\n1048576\n")
+
+	c = FromCmd(context.Background(), exec.Command("true"), nil, WithRlimits(Rlimit{Resource: 100}))
+	assert.ErrorContains(t, c.Run(), "unknown rlimit resource")
+}
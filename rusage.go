@@ -0,0 +1,37 @@
+package execctx
+
+import "time"
+
+// Rusage is the resource usage of a process which exited.
+type Rusage struct {
+	// UserTime and SystemTime are the CPU time spent by the process in user
+	// and kernel mode.
+	UserTime   time.Duration
+	SystemTime time.Duration
+	// MaxRSS is the peak resident set size of the process in bytes.
+	// It is 0 where the platform doesn't report it.
+	MaxRSS int64
+	// MinorFaults and MajorFaults are the number of page faults serviced
+	// without and with I/O.
+	// They are 0 where the platform doesn't report them.
+	MinorFaults int64
+	MajorFaults int64
+}
+
+// Rusage returns the resource usage of the process once `Wait` has returned.
+// It returns false if the process has not exited yet or was never started.
+//
+// Only the process itself is accounted for, not its children which were not
+// waited for by the process.
+func (c *Cmd) Rusage() (Rusage, bool) {
+	ps := c.cmd.ProcessState
+	if ps == nil {
+		return Rusage{}, false
+	}
+	r := Rusage{
+		UserTime:   ps.UserTime(),
+		SystemTime: ps.SystemTime(),
+	}
+	sysRusage(ps, &r)
+	return r, true
+}
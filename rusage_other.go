@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly && !solaris && !aix
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly,!solaris,!aix
+
+package execctx
+
+import "os"
+
+func sysRusage(ps *os.ProcessState, r *Rusage) {}
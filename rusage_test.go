@@ -0,0 +1,26 @@
+package execctx
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRusage(t *testing.T) {
+	c := FromCmd(context.Background(), exec.Command("sh", "-c", "i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done"), nil)
+	_, ok := c.Rusage()
+	assert.Assert(t, !ok)
+
+	assert.NilError(t, c.Run())
+	ru, ok := c.Rusage()
+	assert.Assert(t, ok)
+	assert.Assert(t, ru.UserTime+ru.SystemTime > 0, ru)
+	if runtime.GOOS == "linux" {
+		// Any process is at least a few hundred kilobytes.
+		assert.Assert(t, ru.MaxRSS > 100*1024, ru.MaxRSS)
+		assert.Assert(t, ru.MinorFaults > 0, ru.MinorFaults)
+	}
+}
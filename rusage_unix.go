@@ -0,0 +1,24 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly || solaris || aix
+// +build linux darwin freebsd netbsd openbsd dragonfly solaris aix
+
+package execctx
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+func sysRusage(ps *os.ProcessState, r *Rusage) {
+	ru, ok := ps.SysUsage().(*syscall.Rusage)
+	if !ok || ru == nil {
+		return
+	}
+	r.MaxRSS = int64(ru.Maxrss)
+	if runtime.GOOS != "darwin" {
+		// Everywhere but on macOS the peak RSS is in kilobytes.
+		r.MaxRSS *= 1024
+	}
+	r.MinorFaults = int64(ru.Minflt)
+	r.MajorFaults = int64(ru.Majflt)
+}
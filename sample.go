@@ -0,0 +1,69 @@
+package execctx
+
+import (
+	"time"
+)
+
+// Sample is the state of a process at one point in time, see
+// `WithSampling`.
+type Sample struct {
+	Time time.Time `json:"time"`
+	// State is the state of the process as reported by the kernel, for
+	// instance "R" when running, "S" when sleeping, and "D" when blocked
+	// on IO.
+	State string `json:"state"`
+	// WChan is the kernel function the process is blocked in, if any.
+	WChan string `json:"wchan,omitempty"`
+	// CPU is the CPU time the process has used so far.
+	CPU time.Duration `json:"cpu_ns"`
+}
+
+// WithSampling samples the state of the process every interval while it
+// runs. The samples give a coarse timeline of the process which tells a
+// process busy on the CPU apart from one which is blocked, and on what.
+// They are available from `Samples` and in the `Result`.
+//
+// Sampling reads /proc, so it is only supported on Linux. On other
+// platforms no samples are taken.
+func WithSampling(interval time.Duration) Option {
+	return func(c *Cmd) {
+		c.sampleInterval = interval
+	}
+}
+
+// Samples returns the samples taken of the process so far.
+func (c *Cmd) Samples() []Sample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Sample(nil), c.samples...)
+}
+
+func (c *Cmd) setupSampling() {
+	if c.sampleInterval <= 0 || !samplingSupported {
+		return
+	}
+	c.onProcessStart(func() {
+		go c.sample()
+	})
+}
+
+func (c *Cmd) sample() {
+	ticker := time.NewTicker(c.sampleInterval)
+	defer ticker.Stop()
+
+	pid := c.cmd.Process.Pid
+	for {
+		s, ok := sampleProcess(pid)
+		if ok {
+			c.mu.Lock()
+			c.samples = append(c.samples, s)
+			c.mu.Unlock()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-c.waitDone:
+			return
+		}
+	}
+}
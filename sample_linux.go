@@ -0,0 +1,35 @@
+package execctx
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const samplingSupported = true
+
+// clockTick is the length of a clock tick, the unit of the CPU times in
+// /proc. It is 100Hz on all Linux architectures.
+const clockTick = 10 * time.Millisecond
+
+func sampleProcess(pid int) (Sample, bool) {
+	dir := "/proc/" + strconv.Itoa(pid)
+	st, err := readProcStat(dir + "/stat")
+	if err != nil || st.state == 'Z' {
+		// Zombies are done running, the process is about to be reaped.
+		return Sample{}, false
+	}
+
+	s := Sample{
+		Time:  time.Now(),
+		State: string(st.state),
+		CPU:   time.Duration(st.cpu) * clockTick,
+	}
+	if data, err := ioutil.ReadFile(dir + "/wchan"); err == nil {
+		if wchan := strings.TrimSpace(string(data)); wchan != "0" {
+			s.WChan = wchan
+		}
+	}
+	return s, true
+}
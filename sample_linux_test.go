@@ -0,0 +1,26 @@
+package execctx
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestSampling(t *testing.T) {
+	c := FromCmd(context.Background(), exec.Command("sleep", "0.3"), nil, WithSampling(10*time.Millisecond))
+	r, err := c.RunResult()
+	assert.NilError(t, err)
+	assert.Assert(t, len(r.Samples) > 5, r.Samples)
+
+	var sleeping int
+	for _, s := range r.Samples {
+		if s.State == "S" {
+			sleeping++
+		}
+	}
+	assert.Assert(t, sleeping > 0, r.Samples)
+	assert.DeepEqual(t, r.Samples, c.Samples())
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package execctx
+
+const samplingSupported = false
+
+func sampleProcess(pid int) (Sample, bool) {
+	return Sample{}, false
+}
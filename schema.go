@@ -0,0 +1,153 @@
+package execctx
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// SpecJSONSchema returns a JSON Schema document describing the JSON form of
+// `Spec`, as accepted by `LoadSpec`.
+func SpecJSONSchema() []byte {
+	s := jsonSchema(reflect.TypeOf(Spec{}))
+	props := s["properties"].(map[string]interface{})
+	props["args"].(map[string]interface{})["minItems"] = 1
+	props["env"].(map[string]interface{})["items"].(map[string]interface{})["pattern"] = "="
+	props["cost"].(map[string]interface{})["minimum"] = 0
+	return marshalSchema("Spec", s)
+}
+
+// ResultJSONSchema returns a JSON Schema document describing the JSON form of
+// `Result`.
+func ResultJSONSchema() []byte {
+	return marshalSchema("Result", jsonSchema(reflect.TypeOf(resultJSON{})))
+}
+
+// resultJSON is the JSON form of `Result`.
+type resultJSON struct {
+	ExitCode        int          `json:"exit_code"`
+	Signal          string       `json:"signal,omitempty"`
+	Cause           string       `json:"cause,omitempty"`
+	StartTime       time.Time    `json:"start_time"`
+	EndTime         time.Time    `json:"end_time"`
+	Duration        int64        `json:"duration_ns"`
+	Stdout          []byte       `json:"stdout,omitempty"`
+	Stderr          []byte       `json:"stderr,omitempty"`
+	StdoutTruncated bool         `json:"stdout_truncated,omitempty"`
+	StderrTruncated bool         `json:"stderr_truncated,omitempty"`
+	Files           []FileAccess `json:"files,omitempty"`
+	Samples         []Sample     `json:"samples,omitempty"`
+	Transcript      []Exchange   `json:"transcript,omitempty"`
+}
+
+// MarshalJSON encodes the result, the signal and cause are encoded as their
+// string form and captured output is base64 encoded.
+func (r Result) MarshalJSON() ([]byte, error) {
+	j := resultJSON{
+		ExitCode:        r.ExitCode,
+		StartTime:       r.StartTime,
+		EndTime:         r.EndTime,
+		Duration:        int64(r.Duration),
+		Stdout:          r.Stdout,
+		Stderr:          r.Stderr,
+		StdoutTruncated: r.StdoutTruncated,
+		StderrTruncated: r.StderrTruncated,
+		Files:           r.Files,
+		Samples:         r.Samples,
+		Transcript:      r.Transcript,
+	}
+	if r.Signal != nil {
+		j.Signal = r.Signal.String()
+	}
+	if r.Cause != nil {
+		j.Cause = r.Cause.Error()
+	}
+	return json.Marshal(j)
+}
+
+func marshalSchema(title string, s map[string]interface{}) []byte {
+	s["$schema"] = jsonSchemaDraft
+	s["title"] = title
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		// The schema only holds maps, slices, strings, and numbers.
+		panic(err)
+	}
+	return data
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// jsonSchema returns the schema of the JSON encoding of t, following the
+// rules of encoding/json for struct tags. It only handles the kinds of types
+// used by the package's serialized types.
+func jsonSchema(t reflect.Type) map[string]interface{} {
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	if t.Implements(textMarshalerType) {
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": jsonSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchema(t.Elem())}
+	case reflect.Ptr:
+		return jsonSchema(t.Elem())
+	case reflect.Struct:
+		props := make(map[string]interface{})
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			name := f.Name
+			omitempty := false
+			if tag, ok := f.Tag.Lookup("json"); ok {
+				if tag == "-" {
+					continue
+				}
+				parts := strings.Split(tag, ",")
+				if parts[0] != "" {
+					name = parts[0]
+				}
+				for _, opt := range parts[1:] {
+					omitempty = omitempty || opt == "omitempty"
+				}
+			}
+			props[name] = jsonSchema(f.Type)
+			if !omitempty {
+				required = append(required, name)
+			}
+		}
+		s := map[string]interface{}{"type": "object", "properties": props}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	default:
+		return map[string]interface{}{}
+	}
+}
@@ -0,0 +1,71 @@
+package execctx
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"syscall"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+type testSchema struct {
+	Title      string                 `json:"title"`
+	Required   []string               `json:"required"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+func TestSpecJSONSchema(t *testing.T) {
+	var s testSchema
+	assert.NilError(t, json.Unmarshal(SpecJSONSchema(), &s))
+	assert.Equal(t, s.Title, "Spec")
+	assert.DeepEqual(t, s.Required, []string{"args"})
+	assert.DeepEqual(t, s.Properties["args"], map[string]interface{}{
+		"type":     "array",
+		"items":    map[string]interface{}{"type": "string"},
+		"minItems": float64(1),
+	})
+	assert.DeepEqual(t, s.Properties["labels"], map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": map[string]interface{}{"type": "string"},
+	})
+	limits := s.Properties["limits"].(map[string]interface{})["properties"].(map[string]interface{})
+	assert.DeepEqual(t, limits["timeout"], map[string]interface{}{"type": "string"})
+}
+
+func TestResultJSONSchema(t *testing.T) {
+	var s testSchema
+	assert.NilError(t, json.Unmarshal(ResultJSONSchema(), &s))
+	assert.Equal(t, s.Title, "Result")
+
+	// Every field of an encoded result is described by the schema.
+	start := time.Now()
+	r := Result{
+		ExitCode:   137,
+		Signal:     syscall.SIGKILL,
+		Cause:      errors.New("stop"),
+		StartTime:  start,
+		EndTime:    start.Add(time.Second),
+		Duration:   time.Second,
+		Stdout:     []byte("out"),
+		Files:      []FileAccess{{Path: "/etc/passwd"}},
+		Transcript: []Exchange{{Time: start, Prompt: "sure? ", Reply: "y\n"}},
+	}
+	data, err := json.Marshal(r)
+	assert.NilError(t, err)
+	var encoded map[string]interface{}
+	assert.NilError(t, json.Unmarshal(data, &encoded))
+
+	var fields []string
+	for k := range encoded {
+		_, ok := s.Properties[k]
+		assert.Assert(t, ok, k)
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+	assert.DeepEqual(t, fields, []string{"cause", "duration_ns", "end_time", "exit_code", "files", "signal", "start_time", "stdout", "transcript"})
+	assert.Equal(t, encoded["signal"], "killed")
+	assert.Equal(t, encoded["cause"], "stop")
+}
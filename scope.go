@@ -0,0 +1,69 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"sync"
+)
+
+// ErrScopeExited is the cancellation cause for commands which were still
+// running when their scope exited.
+var ErrScopeExited = errors.New("execctx: scope exited")
+
+// Scope tracks the commands created in `RunScope`.
+type Scope struct {
+	group *Group
+
+	mu   sync.Mutex
+	cmds []*Cmd
+}
+
+// RunScope calls f with a new scope and returns its error.
+// Commands created through the scope are guaranteed to have exited, and to
+// be reaped, before RunScope returns, even if f panics. Commands still
+// running when f returns are cancelled with `ErrScopeExited`, using their
+// cancel handlers, and then waited on.
+//
+// Commands must be started before f returns, not from goroutines which
+// outlive it.
+func RunScope(ctx context.Context, f func(s *Scope) error) error {
+	s := &Scope{group: NewGroupContext(ctx)}
+	defer func() {
+		p := recover()
+		s.close()
+		if p != nil {
+			panic(p)
+		}
+	}()
+	return f(s)
+}
+
+// Context returns a context which is done once the scope exits, or ctx
+// passed to `RunScope` is done.
+func (s *Scope) Context() context.Context {
+	return s.group.Context()
+}
+
+// FromCmd is like the package level `FromCmd`, but the command is governed
+// by the context of the scope and is bound to its lifetime.
+func (s *Scope) FromCmd(cmd *exec.Cmd, cancel func(), opts ...Option) *Cmd {
+	c := FromCmd(s.group.Context(), cmd, cancel, append(opts, WithGroup(s.group))...)
+	s.mu.Lock()
+	s.cmds = append(s.cmds, c)
+	s.mu.Unlock()
+	return c
+}
+
+func (s *Scope) close() {
+	s.group.Cancel(ErrScopeExited)
+
+	s.mu.Lock()
+	cmds := s.cmds
+	s.mu.Unlock()
+	for _, c := range cmds {
+		if c.cmd.Process != nil {
+			c.Wait()
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestScope(t *testing.T) {
+	var c *Cmd
+	err := RunScope(context.Background(), func(s *Scope) error {
+		c = s.FromCmd(exec.Command("sleep", "99999"), nil)
+		assert.NilError(t, c.Start())
+		assert.NilError(t, s.FromCmd(exec.Command("true"), nil).Run())
+		return nil
+	})
+	assert.NilError(t, err)
+
+	// The command was already reaped when RunScope returned.
+	assert.Assert(t, c.cmd.ProcessState != nil)
+	assert.Assert(t, errors.Is(c.Wait(), ErrScopeExited))
+}
+
+func TestScopePanic(t *testing.T) {
+	var c *Cmd
+	func() {
+		defer func() {
+			assert.Equal(t, recover(), "oops")
+		}()
+		RunScope(context.Background(), func(s *Scope) error {
+			c = s.FromCmd(exec.Command("sleep", "99999"), nil)
+			assert.NilError(t, c.Start())
+			panic("oops")
+		})
+	}()
+
+	assert.Assert(t, c.cmd.ProcessState != nil)
+	assert.Assert(t, errors.Is(c.Wait(), ErrScopeExited))
+}
@@ -0,0 +1,60 @@
+package execctx
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SequenceStage is one command run by `Sequence`.
+type SequenceStage struct {
+	// Name identifies the stage in errors.
+	Name string
+	// Weight is the share of the time budget given to the stage, relative
+	// to the weights of the other stages. Historical durations of the
+	// stages work well, in any unit. Weights <= 0 are treated as 1.
+	Weight float64
+	// Cmd creates the command for the stage, governed by ctx.
+	Cmd func(ctx context.Context) *Cmd
+}
+
+func (s *SequenceStage) weight() float64 {
+	if s.Weight <= 0 {
+		return 1
+	}
+	return s.Weight
+}
+
+// Sequence runs the stages one after the other, stopping at the first one
+// which fails.
+//
+// If ctx has a deadline the remaining time is divided among the stages
+// according to their weights, and each stage gets its own deadline, so a
+// slow stage early on can't use up the time of the ones after it. Time a
+// stage does not use is divided among the remaining stages.
+func Sequence(ctx context.Context, stages ...SequenceStage) error {
+	var remaining float64
+	for i := range stages {
+		remaining += stages[i].weight()
+	}
+
+	for i := range stages {
+		stage := &stages[i]
+		w := stage.weight()
+		err := func() error {
+			stageCtx := ctx
+			if deadline, ok := ctx.Deadline(); ok {
+				budget := time.Duration(float64(time.Until(deadline)) * w / remaining)
+				var cancel context.CancelFunc
+				stageCtx, cancel = context.WithTimeout(ctx, budget)
+				defer cancel()
+			}
+			return stage.Cmd(stageCtx).Run()
+		}()
+		if err != nil {
+			return fmt.Errorf("sequence stage %d (%s): %w", i, stage.Name, err)
+		}
+		remaining -= w
+	}
+	return nil
+}
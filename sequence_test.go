@@ -0,0 +1,40 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestSequence(t *testing.T) {
+	var deadlines []time.Duration
+	stage := func(name string, weight float64, args ...string) SequenceStage {
+		return SequenceStage{Name: name, Weight: weight, Cmd: func(ctx context.Context) *Cmd {
+			deadline, _ := ctx.Deadline()
+			deadlines = append(deadlines, time.Until(deadline))
+			return FromCmd(ctx, exec.Command(args[0], args[1:]...), nil)
+		}}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	assert.NilError(t, Sequence(ctx, stage("a", 1, "true"), stage("b", 3, "true")))
+	assert.Equal(t, len(deadlines), 2)
+	// The first stage gets a quarter of the budget, the second all that is
+	// left.
+	assert.Assert(t, deadlines[0] > 2*time.Second && deadlines[0] <= 2500*time.Millisecond, deadlines[0])
+	assert.Assert(t, deadlines[1] > 9*time.Second, deadlines[1])
+
+	// A stage which is too slow is stopped at its own deadline, rather than
+	// taking the whole budget.
+	ctx, cancel = context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+	err := Sequence(ctx, stage("slow", 1, "sleep", "99999"), stage("next", 1, "true"))
+	assert.Assert(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.ErrorContains(t, err, "sequence stage 0 (slow)")
+	assert.Assert(t, ctx.Err() == nil)
+}
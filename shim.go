@@ -0,0 +1,87 @@
+package execctx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// shimShell is the shell used to apply process attributes which os/exec
+// cannot set for the child only.
+const shimShell = "/bin/sh"
+
+// WithUmask sets the umask of the process.
+//
+// os/exec has no way to set the umask of a child without changing it for the
+// whole current process, so the command is run through a small /bin/sh shim
+// which sets the umask and then execs the command. The shim replaces itself
+// with the command, so the pid and signal handling of the command are not
+// affected.
+// The umask is not supported on Windows.
+func WithUmask(mask os.FileMode) Option {
+	return func(c *Cmd) {
+		c.umask = &mask
+	}
+}
+
+// shimSetup returns the shell commands the shim runs before executing the
+// command, if any.
+func (c *Cmd) shimSetup() ([]string, error) {
+	var setup []string
+	if c.umask != nil {
+		setup = append(setup, fmt.Sprintf("umask %04o", *c.umask&os.ModePerm))
+	}
+	limits, err := c.rlimitSetup()
+	if err != nil {
+		return nil, err
+	}
+	setup = append(setup, limits...)
+	if c.cgroupPath != "" && !c.cgroupFromParent {
+		// The shim's pid is the command's pid once it execs.
+		setup = append(setup, "echo $$ > "+shellQuote(filepath.Join(c.cgroupPath, "cgroup.procs")))
+	}
+	return setup, nil
+}
+
+func (c *Cmd) setupShim() error {
+	setup, err := c.shimSetup()
+	if err != nil {
+		return err
+	}
+	if len(setup) == 0 {
+		return nil
+	}
+
+	// Hermetic mode only sets the umask, which is not worth failing for.
+	onlyUmask := len(c.rlimits) == 0 && (c.cgroupPath == "" || c.cgroupFromParent)
+	if runtime.GOOS == "windows" {
+		if c.hermetic && onlyUmask {
+			c.degrade("umask", "not supported on windows")
+			return nil
+		}
+		return errors.New("execctx: umask and rlimits are not supported on windows")
+	}
+	if _, err := os.Stat(shimShell); err != nil {
+		if c.hermetic && onlyUmask {
+			c.degrade("umask", "no shell to apply it: "+err.Error())
+			return nil
+		}
+		return fmt.Errorf("execctx: error setting up shim: %w", err)
+	}
+
+	// The command is passed as positional parameters so it never goes
+	// through shell parsing.
+	script := strings.Join(setup, " && ") + ` && exec "$0" "$@"`
+	args := []string{"sh", "-c", script, c.cmd.Path}
+	c.cmd.Args = append(args, c.cmd.Args[1:]...)
+	c.cmd.Path = shimShell
+	return nil
+}
+
+// shellQuote quotes s as a single word for the shim shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
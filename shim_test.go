@@ -0,0 +1,24 @@
+package execctx
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestUmask(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("umask is not supported on windows")
+	}
+
+	var out strings.Builder
+	cmd := exec.Command("sh", "-c", `umask; printf '%s\n' "$@"`, "sh", "a b", "$HOME")
+	cmd.Stdout = &out
+	c := FromCmd(context.Background(), cmd, nil, WithUmask(0077))
+	assert.NilError(t, c.Run())
+	assert.Equal(t, out.String(), "0077\na b\n$HOME\n")
+}
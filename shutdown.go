@@ -0,0 +1,92 @@
+package execctx
+
+import (
+	"fmt"
+	"time"
+)
+
+// ShutdownStage is one step of an ordered shutdown, see `Shutdown`.
+type ShutdownStage struct {
+	// Groups are the groups cancelled together in this stage.
+	Groups []*Group
+	// Grace is how long the commands of the stage have to exit once
+	// cancelled before they are killed. If zero they are waited on for as
+	// long as they take.
+	Grace time.Duration
+}
+
+// Shutdown cancels groups of commands stage by stage with the given cause,
+// for instance stopping consumers before the broker they read from, or the
+// application before its sidecars.
+// Each stage is only cancelled once all commands of the previous stage have
+// exited, commands which outlast the grace period of their stage are killed.
+//
+// The commands are stopped with their own cancel handlers, the grace period
+// bounds how long those may take. If commands had to be killed an error
+// describing the first stage where that happened is returned, the remaining
+// stages are still shut down.
+func Shutdown(cause error, stages ...ShutdownStage) error {
+	var err error
+	for i, stage := range stages {
+		var cmds []*Cmd
+		for _, g := range stage.Groups {
+			cmds = append(cmds, g.members()...)
+		}
+		for _, g := range stage.Groups {
+			g.Cancel(cause)
+		}
+
+		if n := waitGrace(cmds, stage.Grace); n > 0 && err == nil {
+			err = fmt.Errorf("execctx: shutdown stage %d: killed %d commands which did not exit within %s", i, n, stage.Grace)
+		}
+	}
+	return err
+}
+
+// waitGrace waits for the commands to exit, killing those still running
+// after grace. It returns the number of commands killed.
+//
+// Commands which were never started, or already waited on, are skipped, a
+// command which has yet to be started is owned by whoever starts it.
+func waitGrace(cmds []*Cmd, grace time.Duration) int {
+	started := cmds[:0:0]
+	for _, c := range cmds {
+		if running(c) {
+			started = append(started, c)
+		}
+	}
+	cmds = started
+
+	done := make(chan struct{})
+	go func() {
+		for _, c := range cmds {
+			c.Wait()
+		}
+		close(done)
+	}()
+	if grace <= 0 {
+		<-done
+		return 0
+	}
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return 0
+	case <-timer.C:
+	}
+
+	var killed int
+	for _, c := range cmds {
+		select {
+		case <-c.waitDone:
+			continue
+		default:
+		}
+		c.kill()
+		killed++
+	}
+	<-done
+	return killed
+}
@@ -0,0 +1,53 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestShutdown(t *testing.T) {
+	app := NewGroupContext(context.Background())
+	sidecars := NewGroupContext(context.Background())
+
+	// The app ignores its cancellation, so it is only stopped once its grace
+	// period runs out.
+	a := FromCmd(context.Background(), exec.Command("sleep", "99999"), func() {}, WithGroup(app))
+	s := FromCmd(context.Background(), exec.Command("sleep", "99999"), nil, WithGroup(sidecars))
+	assert.NilError(t, a.Start())
+	assert.NilError(t, s.Start())
+
+	cause := errors.New("shutdown")
+	err := Shutdown(cause,
+		ShutdownStage{Groups: []*Group{app}, Grace: 50 * time.Millisecond},
+		ShutdownStage{Groups: []*Group{sidecars}},
+	)
+	assert.ErrorContains(t, err, "shutdown stage 0: killed 1 commands")
+
+	assert.Assert(t, errors.Is(a.Wait(), cause))
+	assert.Assert(t, errors.Is(s.Wait(), cause))
+	assert.Assert(t, !s.endTime.Before(a.endTime))
+}
+
+func TestShutdownUnstarted(t *testing.T) {
+	g := NewGroupContext(context.Background())
+	c := FromCmd(context.Background(), exec.Command("sleep", "99999"), nil, WithGroup(g))
+
+	cause := errors.New("shutdown")
+	assert.NilError(t, Shutdown(cause, ShutdownStage{Groups: []*Group{g}, Grace: time.Minute}))
+
+	// The command was not waited on, starting it reports the cancellation.
+	select {
+	case <-c.waitDone:
+		t.Fatal("unstarted command was waited on")
+	default:
+	}
+	var cancelled *StartCancelledError
+	err := c.Start()
+	assert.Assert(t, errors.As(err, &cancelled), err)
+	assert.Assert(t, errors.Is(err, cause), err)
+}
@@ -0,0 +1,140 @@
+package execctx
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// shutdownWindow is how many samples `ShutdownStats` keeps for every
+	// step of every key.
+	shutdownWindow = 10
+	// shutdownMinSamples is how many samples are needed before the wait of
+	// a step is adapted.
+	shutdownMinSamples = 3
+)
+
+// ShutdownSample is how a process reacted to a step of an escalation policy.
+type ShutdownSample struct {
+	// Elapsed is how long the process took to exit after the signal of the
+	// step, or the wait of the step if it did not exit in time.
+	Elapsed time.Duration
+	// Exited reports whether the process exited within the wait of the
+	// step.
+	Exited bool
+}
+
+// ShutdownStats keeps how long commands took to exit once they were
+// signalled by an escalation policy, see `WithAdaptiveEscalation`. Only the
+// most recent samples are kept.
+//
+// It is safe for concurrent use and is meant to be shared by the commands
+// whose history should be pooled. The zero value is ready to use.
+type ShutdownStats struct {
+	mu      sync.Mutex
+	samples map[shutdownKey][]ShutdownSample
+}
+
+type shutdownKey struct {
+	key  string
+	step int
+}
+
+// Record adds a sample for the step of the escalation policy of the commands
+// with key, for instance to restore samples kept from an earlier run of the
+// program.
+func (s *ShutdownStats) Record(key string, step int, sample ShutdownSample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.samples == nil {
+		s.samples = make(map[shutdownKey][]ShutdownSample)
+	}
+	k := shutdownKey{key, step}
+	samples := append(s.samples[k], sample)
+	if len(samples) > shutdownWindow {
+		samples = append(samples[:0], samples[len(samples)-shutdownWindow:]...)
+	}
+	s.samples[k] = samples
+}
+
+// Samples returns the samples kept for the step of the escalation policy of
+// the commands with key, oldest first.
+func (s *ShutdownStats) Samples(key string, step int) []ShutdownSample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ShutdownSample(nil), s.samples[shutdownKey{key, step}]...)
+}
+
+// AdaptiveEscalation is an escalation policy whose waits follow how long the
+// command took to exit in earlier runs, see `WithAdaptiveEscalation`.
+type AdaptiveEscalation struct {
+	// Policy is the escalation policy to adapt. Its waits are used until
+	// enough runs were recorded.
+	Policy EscalationPolicy
+	// Stats keeps the shutdown times. If it is nil the policy is used as
+	// is.
+	Stats *ShutdownStats
+	// Key identifies the commands whose shutdown times are pooled. It
+	// defaults to the base name of the program the command is created with.
+	Key string
+	// MinWait is the shortest wait of a step, zero means no lower bound.
+	MinWait time.Duration
+	// MaxWait is the longest wait of a step. If it is zero the waits of
+	// Policy are the longest, so waits are only shortened.
+	MaxWait time.Duration
+}
+
+// WithAdaptiveEscalation is like `WithEscalation`, but the wait of every
+// step is adapted to how long the process took to exit after the signal of
+// that step in earlier runs recorded in a.Stats, and every run is recorded
+// there.
+//
+// Once there are a few samples for a step, its wait is one and a half times
+// the longest recent shutdown, bounded by a.MinWait and a.MaxWait. A run in
+// which the process outlived the wait counts as needing twice that wait, so
+// the wait grows for commands which routinely need longer.
+func WithAdaptiveEscalation(a AdaptiveEscalation) Option {
+	return func(c *Cmd) {
+		WithEscalation(a.Policy)(c)
+		if a.Stats == nil {
+			return
+		}
+		if a.Key == "" && len(c.cmd.Args) > 0 {
+			a.Key = filepath.Base(c.cmd.Args[0])
+		}
+		c.adaptiveEscalation = &a
+	}
+}
+
+// wait returns the wait of step i, which is configured to be d.
+func (a *AdaptiveEscalation) wait(i int, d time.Duration) time.Duration {
+	samples := a.Stats.Samples(a.Key, i)
+	if len(samples) < shutdownMinSamples {
+		return d
+	}
+
+	var longest time.Duration
+	for _, s := range samples {
+		elapsed := s.Elapsed
+		if !s.Exited {
+			elapsed *= 2
+		}
+		if elapsed > longest {
+			longest = elapsed
+		}
+	}
+	wait := longest * 3 / 2
+
+	max := a.MaxWait
+	if max <= 0 {
+		max = d
+	}
+	if wait > max {
+		wait = max
+	}
+	if wait < a.MinWait {
+		wait = a.MinWait
+	}
+	return wait
+}
@@ -0,0 +1,81 @@
+package execctx
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestAdaptiveEscalation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals are not supported on windows")
+	}
+
+	run := func(script string, a AdaptiveEscalation) *Cmd {
+		t.Helper()
+		cmd := exec.Command("sh", "-c", script)
+		stdout, err := cmd.StdoutPipe()
+		assert.NilError(t, err)
+		c := FromCmd(context.Background(), cmd, nil, WithAdaptiveEscalation(a))
+		assert.NilError(t, c.Start())
+		line, err := bufio.NewReader(stdout).ReadString('\n')
+		assert.NilError(t, err)
+		assert.Equal(t, strings.TrimSpace(line), "ready")
+		c.Cancel(nil)
+		c.Wait()
+		return c
+	}
+	policy := EscalationPolicy{{Signal: syscall.SIGTERM, Wait: time.Minute}}
+
+	// The command used to exit quickly, so the long wait is shortened and it
+	// is killed soon once it hangs.
+	stats := &ShutdownStats{}
+	for i := 0; i < shutdownMinSamples; i++ {
+		stats.Record("fast", 0, ShutdownSample{Elapsed: 10 * time.Millisecond, Exited: true})
+	}
+	start := time.Now()
+	c := run(`trap '' TERM; echo ready; while :; do sleep 0.01; done`, AdaptiveEscalation{Policy: policy, Stats: stats, Key: "fast"})
+	assert.Assert(t, time.Since(start) < 10*time.Second)
+	assert.Equal(t, c.EscalationStep(), 1)
+	samples := stats.Samples("fast", 0)
+	assert.Equal(t, len(samples), shutdownMinSamples+1)
+	assert.Equal(t, samples[len(samples)-1], ShutdownSample{Elapsed: 15 * time.Millisecond})
+
+	// The command used to need longer than the wait, so it is extended up
+	// to the cap and the command gets to exit on its own.
+	policy = EscalationPolicy{{Signal: syscall.SIGTERM, Wait: 50 * time.Millisecond}}
+	for i := 0; i < shutdownMinSamples; i++ {
+		stats.Record("slow", 0, ShutdownSample{Elapsed: 500 * time.Millisecond})
+	}
+	c = run(`trap 'sleep 0.2; exit 3' TERM; echo ready; while :; do sleep 0.01; done`, AdaptiveEscalation{Policy: policy, Stats: stats, Key: "slow", MaxWait: 5 * time.Second})
+	assert.Equal(t, c.EscalationStep(), 0)
+	assert.Equal(t, c.ExitCode(), 3)
+	// The sample is recorded by the cancel handler, which may still be
+	// running when Wait returns.
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if samples = stats.Samples("slow", 0); len(samples) > shutdownMinSamples {
+			break
+		}
+	}
+	assert.Equal(t, len(samples), shutdownMinSamples+1)
+	assert.Assert(t, samples[len(samples)-1].Exited)
+
+	// The key defaults to the program name and only the recent samples are
+	// kept.
+	stats = &ShutdownStats{}
+	c = FromCmd(context.Background(), exec.Command("/bin/sh"), nil, WithAdaptiveEscalation(AdaptiveEscalation{Policy: policy, Stats: stats}))
+	assert.Equal(t, c.adaptiveEscalation.Key, "sh")
+	for i := 0; i < 2*shutdownWindow; i++ {
+		stats.Record("sh", 0, ShutdownSample{Elapsed: time.Duration(i)})
+	}
+	samples = stats.Samples("sh", 0)
+	assert.Equal(t, len(samples), shutdownWindow)
+	assert.Equal(t, samples[0].Elapsed, time.Duration(shutdownWindow))
+}
@@ -0,0 +1,15 @@
+module github.com/cpuguy83/execctx/slog
+
+go 1.21
+
+require (
+	github.com/cpuguy83/execctx v0.0.0
+	gotest.tools/v3 v3.0.2
+)
+
+require (
+	github.com/google/go-cmp v0.5.0 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+)
+
+replace github.com/cpuguy83/execctx => ../
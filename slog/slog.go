@@ -0,0 +1,58 @@
+// Package slog logs the lifecycle of execctx commands with log/slog.
+package slog
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/cpuguy83/execctx"
+)
+
+// WithLogger logs the lifecycle of the command to l: when it starts, when it
+// is cancelled and its cancel handler runs, every signal the package sends
+// it while stopping it, and how it exited.
+// Records carry the command line as "args", the "pid" of the process, the
+// "labels" set with `execctx.WithLabels`, if any, and the "program_sha256"
+// verified with `execctx.WithExpectedChecksum`, if any.
+func WithLogger(l *slog.Logger) execctx.Option {
+	attrs := func(c *execctx.Cmd, extra ...interface{}) []interface{} {
+		a := []interface{}{"args", c.Snapshot().Args, "pid", c.Pid()}
+		if labels := c.Labels(); len(labels) > 0 {
+			a = append(a, "labels", labels)
+		}
+		if digest := c.ProgramSHA256(); digest != "" {
+			a = append(a, "program_sha256", digest)
+		}
+		return append(a, extra...)
+	}
+
+	return func(c *execctx.Cmd) {
+		execctx.OnStarted(func(c *execctx.Cmd) {
+			l.Info("command started", attrs(c)...)
+		})(c)
+		execctx.OnCancelHandler(func(c *execctx.Cmd, cause error) {
+			l.Info("command cancelled, running cancel handler", attrs(c, "cause", cause.Error())...)
+		})(c)
+		execctx.OnSignal(func(c *execctx.Cmd, sig os.Signal) {
+			l.Warn("signalling command", attrs(c, "signal", sig.String())...)
+		})(c)
+		execctx.WithInterceptors(execctx.InterceptorFunc(func(ctx context.Context, c *execctx.Cmd, next func() error) error {
+			err := next()
+			if c.Pid() == 0 {
+				return err
+			}
+			r := c.Result()
+			level := slog.LevelInfo
+			if r.ExitCode != 0 {
+				level = slog.LevelError
+			}
+			extra := []interface{}{"duration", r.Duration, "exit_code", r.ExitCode}
+			if r.Signal != nil {
+				extra = append(extra, "signal", r.Signal.String())
+			}
+			l.Log(ctx, level, "command exited", attrs(c, extra...)...)
+			return err
+		}))(c)
+	}
+}
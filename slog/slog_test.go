@@ -0,0 +1,92 @@
+package slog
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cpuguy83/execctx"
+	"gotest.tools/v3/assert"
+)
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := execctx.FromCmd(ctx, exec.Command("sleep", "99999"), nil, WithLogger(l), execctx.GracefulKill(syscall.SIGTERM, time.Minute))
+	assert.NilError(t, c.Start())
+	cancel()
+	c.Wait()
+
+	var msgs []string
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var rec struct {
+			Msg  string   `json:"msg"`
+			Args []string `json:"args"`
+			Pid  int      `json:"pid"`
+		}
+		assert.NilError(t, dec.Decode(&rec))
+		assert.DeepEqual(t, rec.Args, []string{"sleep", "99999"})
+		assert.Equal(t, rec.Pid, c.Pid())
+		msgs = append(msgs, rec.Msg)
+	}
+	assert.DeepEqual(t, msgs, []string{
+		"command started",
+		"command cancelled, running cancel handler",
+		"signalling command",
+		"command exited",
+	})
+}
+
+func TestWithLoggerClone(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	c := execctx.FromCmd(context.Background(), exec.Command("true"), nil, WithLogger(l), execctx.WithLabels(map[string]string{"job": "build"}))
+	nc := c.Clone(context.Background())
+	assert.NilError(t, nc.Run())
+
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var rec struct {
+			Pid    int               `json:"pid"`
+			Labels map[string]string `json:"labels"`
+		}
+		assert.NilError(t, dec.Decode(&rec))
+		assert.Equal(t, rec.Pid, nc.Pid())
+		assert.DeepEqual(t, rec.Labels, map[string]string{"job": "build"})
+	}
+}
+
+func TestWithLoggerChecksum(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	cmd := exec.Command("true")
+	data, err := os.ReadFile(cmd.Path)
+	assert.NilError(t, err)
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+
+	c := execctx.FromCmd(context.Background(), cmd, nil, WithLogger(l), execctx.WithExpectedChecksum(digest))
+	assert.NilError(t, c.Run())
+
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var rec struct {
+			Digest string `json:"program_sha256"`
+		}
+		assert.NilError(t, dec.Decode(&rec))
+		assert.Equal(t, rec.Digest, digest)
+	}
+}
@@ -0,0 +1,34 @@
+package execctx
+
+import (
+	"time"
+)
+
+// WithSlowThreshold calls f if the command is still running d after it was
+// started. The command is not stopped, this is only meant to give early
+// warning about commands which take longer than expected.
+//
+// The command is considered running until `Wait` returns.
+func WithSlowThreshold(d time.Duration, f func(*Cmd)) Option {
+	return func(c *Cmd) {
+		c.slowThreshold = d
+		c.onSlow = f
+	}
+}
+
+func (c *Cmd) watchSlow() {
+	if c.slowThreshold <= 0 || c.onSlow == nil {
+		return
+	}
+
+	go func() {
+		timer := time.NewTimer(c.slowThreshold)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			c.onSlow(c)
+		case <-c.waitDone:
+		}
+	}()
+}
@@ -0,0 +1,27 @@
+package execctx
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestSlowThreshold(t *testing.T) {
+	slow := make(chan *Cmd, 1)
+	onSlow := func(c *Cmd) { slow <- c }
+
+	c := FromCmd(context.Background(), exec.Command("sleep", "0.2"), nil, WithSlowThreshold(10*time.Millisecond, onSlow))
+	assert.NilError(t, c.Run())
+	assert.Equal(t, <-slow, c)
+
+	c = FromCmd(context.Background(), exec.Command("true"), nil, WithSlowThreshold(time.Minute, onSlow))
+	assert.NilError(t, c.Run())
+	select {
+	case <-slow:
+		t.Fatal("unexpected slow callback")
+	default:
+	}
+}
@@ -0,0 +1,324 @@
+package execctx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Spec is a declarative description of a command.
+// Specs can be loaded from configuration files with `LoadSpec` and
+// `LoadSpecs`.
+type Spec struct {
+	// Name identifies the command. Defaults to the base name of the program.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Args holds the command line arguments, including the program as Args[0].
+	Args []string `json:"args" yaml:"args"`
+	// Env is the environment of the command in "key=value" form.
+	// If nil, the command inherits the environment of the current process.
+	Env []string `json:"env,omitempty" yaml:"env,omitempty"`
+	// Dir is the working directory of the command.
+	Dir string `json:"dir,omitempty" yaml:"dir,omitempty"`
+	// Labels are added to the command, see `WithLabels`.
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	// Cost is the cost of the command when run in a `Pool`. Defaults to 1.
+	Cost int64 `json:"cost,omitempty" yaml:"cost,omitempty"`
+
+	// Limits bound the resources the command may use.
+	Limits SpecLimits `json:"limits,omitempty" yaml:"limits,omitempty"`
+	// Cancel is how the command is stopped when it is cancelled.
+	Cancel SpecCancel `json:"cancel,omitempty" yaml:"cancel,omitempty"`
+	// Retry is how the command is retried when it fails, see `RetryPolicy`.
+	Retry SpecRetry `json:"retry,omitempty" yaml:"retry,omitempty"`
+	// Schedule is how the command is kept running, see `Supervise`.
+	Schedule SpecSchedule `json:"schedule,omitempty" yaml:"schedule,omitempty"`
+}
+
+// SpecLimits are the resource limits of a `Spec`.
+type SpecLimits struct {
+	// Timeout cancels the command once it has run for this long, see
+	// `WithTimeout`.
+	Timeout Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// MaxOutput limits the output of the command, see `WithMaxOutput`.
+	MaxOutput int64 `json:"max_output,omitempty" yaml:"max_output,omitempty"`
+	// Rlimits maps resources to their limit, see `WithRlimits`. Resources
+	// are named like the RLIMIT constants, in lower case, such as "nofile".
+	Rlimits map[string]uint64 `json:"rlimits,omitempty" yaml:"rlimits,omitempty"`
+}
+
+// SpecCancel is the cancel policy of a `Spec`.
+type SpecCancel struct {
+	// Signal is sent to the process when the command is cancelled, by name
+	// such as "SIGTERM". If it is not set the process is killed right away.
+	Signal string `json:"signal,omitempty" yaml:"signal,omitempty"`
+	// Grace is how long the process is given to exit after Signal before it
+	// is killed, see `GracefulKill`.
+	Grace Duration `json:"grace,omitempty" yaml:"grace,omitempty"`
+	// ProcessGroup signals the process group of the command, see
+	// `WithProcessGroup`.
+	ProcessGroup bool `json:"process_group,omitempty" yaml:"process_group,omitempty"`
+	// KillTree signals all descendants of the command, see `WithKillTree`.
+	KillTree bool `json:"kill_tree,omitempty" yaml:"kill_tree,omitempty"`
+}
+
+// SpecRetry is the retry policy of a `Spec`, see `Spec.RetryPolicy`.
+type SpecRetry struct {
+	// MaxAttempts is the total number of times the command is run.
+	MaxAttempts int `json:"max_attempts,omitempty" yaml:"max_attempts,omitempty"`
+	// Backoff is the wait before the first retry, it doubles for every
+	// retry after that up to MaxBackoff.
+	Backoff Duration `json:"backoff,omitempty" yaml:"backoff,omitempty"`
+	// MaxBackoff caps the wait between attempts. Defaults to Backoff.
+	MaxBackoff Duration `json:"max_backoff,omitempty" yaml:"max_backoff,omitempty"`
+	// ExitCodes are the exit codes which are retried. If empty every
+	// failure is retried.
+	ExitCodes []int `json:"exit_codes,omitempty" yaml:"exit_codes,omitempty"`
+}
+
+// SpecSchedule is how a `Spec` is kept running by `Supervise`.
+// A periodic job is a command which is always restarted, with the period as
+// the delay.
+type SpecSchedule struct {
+	// Restart is "always", "on-failure", or "never", see `RestartPolicy`.
+	// Defaults to "always".
+	Restart string `json:"restart,omitempty" yaml:"restart,omitempty"`
+	// Delay is the wait before the command is restarted.
+	Delay Duration `json:"delay,omitempty" yaml:"delay,omitempty"`
+	// PoisonCrashes and PoisonWithin stop restarting a command which keeps
+	// crashing, see `WithPoisonDetection`.
+	PoisonCrashes int      `json:"poison_crashes,omitempty" yaml:"poison_crashes,omitempty"`
+	PoisonWithin  Duration `json:"poison_within,omitempty" yaml:"poison_within,omitempty"`
+}
+
+// Duration is a time.Duration which is written as a string accepted by
+// time.ParseDuration, such as "1m30s", in spec files.
+type Duration time.Duration
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Validate checks that the spec describes a runnable command.
+func (s *Spec) Validate() error {
+	if len(s.Args) == 0 || s.Args[0] == "" {
+		return fmt.Errorf("spec %q: no program specified", s.Name)
+	}
+	for _, kv := range s.Env {
+		if !strings.Contains(kv, "=") {
+			return fmt.Errorf("spec %q: invalid environment variable %q: expected key=value", s.Name, kv)
+		}
+	}
+	if s.Cost < 0 {
+		return fmt.Errorf("spec %q: cost must not be negative", s.Name)
+	}
+
+	if s.Limits.Timeout < 0 || s.Limits.MaxOutput < 0 {
+		return fmt.Errorf("spec %q: limits must not be negative", s.Name)
+	}
+	for name := range s.Limits.Rlimits {
+		if _, ok := rlimitNames[name]; !ok {
+			return fmt.Errorf("spec %q: unknown rlimit %q", s.Name, name)
+		}
+	}
+	if s.Cancel.Signal != "" {
+		if _, ok := signalNames[strings.TrimPrefix(s.Cancel.Signal, "SIG")]; !ok {
+			return fmt.Errorf("spec %q: unknown cancel signal %q", s.Name, s.Cancel.Signal)
+		}
+	}
+	if s.Cancel.Grace < 0 {
+		return fmt.Errorf("spec %q: cancel grace must not be negative", s.Name)
+	}
+	if s.Retry.MaxAttempts < 0 || s.Retry.Backoff < 0 || s.Retry.MaxBackoff < 0 {
+		return fmt.Errorf("spec %q: retry policy must not be negative", s.Name)
+	}
+	if _, ok := restartPolicies[s.Schedule.Restart]; !ok {
+		return fmt.Errorf("spec %q: unknown restart policy %q", s.Name, s.Schedule.Restart)
+	}
+	if s.Schedule.Delay < 0 || s.Schedule.PoisonCrashes < 0 || s.Schedule.PoisonWithin < 0 {
+		return fmt.Errorf("spec %q: schedule must not be negative", s.Name)
+	}
+	return nil
+}
+
+var rlimitNames = map[string]RlimitResource{
+	"cpu":    RlimitCPU,
+	"fsize":  RlimitFSIZE,
+	"data":   RlimitDATA,
+	"stack":  RlimitSTACK,
+	"core":   RlimitCORE,
+	"nofile": RlimitNOFILE,
+	"as":     RlimitAS,
+}
+
+// signalNames are the signals a spec may be cancelled with, without the
+// "SIG" prefix.
+var signalNames = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"TERM": syscall.SIGTERM,
+}
+
+var restartPolicies = map[string]RestartPolicy{
+	"":           RestartAlways,
+	"always":     RestartAlways,
+	"on-failure": RestartOnFailure,
+	"never":      RestartNever,
+}
+
+// setDefaults fills in default values for unset fields.
+func (s *Spec) setDefaults() {
+	if s.Name == "" && len(s.Args) > 0 {
+		s.Name = filepath.Base(s.Args[0])
+	}
+	if s.Cost == 0 {
+		s.Cost = 1
+	}
+}
+
+// Command validates the spec and creates a `Cmd` from it.
+// The cancel function and options are passed through to `FromCmd`, options
+// are applied after the ones derived from the spec.
+func (s *Spec) Command(ctx context.Context, cancel func(), opts ...Option) (*Cmd, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s.command(ctx, cancel, opts...), nil
+}
+
+// command is like Command for a spec which was already validated.
+func (s *Spec) command(ctx context.Context, cancel func(), opts ...Option) *Cmd {
+	cmd := exec.Command(s.Args[0], s.Args[1:]...)
+	if s.Env != nil {
+		cmd.Env = append([]string(nil), s.Env...)
+	}
+	cmd.Dir = s.Dir
+
+	specOpts := []Option{WithCost(s.Cost)}
+	if len(s.Labels) > 0 {
+		specOpts = append(specOpts, WithLabels(s.Labels))
+	}
+
+	if s.Limits.Timeout > 0 {
+		specOpts = append(specOpts, WithTimeout(time.Duration(s.Limits.Timeout)))
+	}
+	if s.Limits.MaxOutput > 0 {
+		specOpts = append(specOpts, WithMaxOutput(s.Limits.MaxOutput))
+	}
+	if len(s.Limits.Rlimits) > 0 {
+		limits := make([]Rlimit, 0, len(s.Limits.Rlimits))
+		for name, limit := range s.Limits.Rlimits {
+			limits = append(limits, Rlimit{Resource: rlimitNames[name], Limit: limit})
+		}
+		sort.Slice(limits, func(i, j int) bool { return limits[i].Resource < limits[j].Resource })
+		specOpts = append(specOpts, WithRlimits(limits...))
+	}
+
+	if s.Cancel.Signal != "" {
+		sig := signalNames[strings.TrimPrefix(s.Cancel.Signal, "SIG")]
+		specOpts = append(specOpts, GracefulKill(sig, time.Duration(s.Cancel.Grace)))
+	}
+	if s.Cancel.ProcessGroup {
+		specOpts = append(specOpts, WithProcessGroup())
+	}
+	if s.Cancel.KillTree {
+		specOpts = append(specOpts, WithKillTree())
+	}
+	return FromCmd(ctx, cmd, cancel, append(specOpts, opts...)...)
+}
+
+// RetryPolicy returns the retry policy of the spec, to run it with `Retry`.
+func (s *Spec) RetryPolicy() RetryPolicy {
+	p := RetryPolicy{
+		MaxAttempts:        s.Retry.MaxAttempts,
+		RetryableExitCodes: append([]int(nil), s.Retry.ExitCodes...),
+	}
+	if s.Retry.Backoff > 0 {
+		max := s.Retry.MaxBackoff
+		if max < s.Retry.Backoff {
+			max = s.Retry.Backoff
+		}
+		p.Backoff = ExponentialBackoff(time.Duration(s.Retry.Backoff), time.Duration(max))
+	}
+	return p
+}
+
+// Supervise validates the spec and keeps its command running under a
+// `Supervisor` according to its schedule. The options are applied to the
+// command of every run.
+func (s *Spec) Supervise(ctx context.Context, opts ...Option) (*Supervisor, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	spec := *s
+	var sopts []SupervisorOption
+	if spec.Schedule.PoisonCrashes > 0 {
+		sopts = append(sopts, WithPoisonDetection(spec.Schedule.PoisonCrashes, time.Duration(spec.Schedule.PoisonWithin)))
+	}
+	newCmd := func(ctx context.Context) *Cmd {
+		return spec.command(ctx, nil, opts...)
+	}
+	return NewSupervisor(ctx, restartPolicies[spec.Schedule.Restart], time.Duration(spec.Schedule.Delay), newCmd, sopts...), nil
+}
+
+// UnmarshalFunc decodes data into v, for example `json.Unmarshal`.
+//
+// Spec fields carry both json and yaml struct tags, so YAML decoders such as
+// gopkg.in/yaml.v3 can be used as well. TOML decoders which match field names
+// case-insensitively work too.
+type UnmarshalFunc func(data []byte, v interface{}) error
+
+// LoadSpec decodes, defaults, and validates a single spec.
+// If unmarshal is nil, data is decoded as JSON.
+func LoadSpec(data []byte, unmarshal UnmarshalFunc) (Spec, error) {
+	if unmarshal == nil {
+		unmarshal = json.Unmarshal
+	}
+
+	var s Spec
+	if err := unmarshal(data, &s); err != nil {
+		return Spec{}, fmt.Errorf("error decoding spec: %w", err)
+	}
+	s.setDefaults()
+	if err := s.Validate(); err != nil {
+		return Spec{}, err
+	}
+	return s, nil
+}
+
+// LoadSpecs is like `LoadSpec` but decodes a list of specs.
+func LoadSpecs(data []byte, unmarshal UnmarshalFunc) ([]Spec, error) {
+	if unmarshal == nil {
+		unmarshal = json.Unmarshal
+	}
+
+	var specs []Spec
+	if err := unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("error decoding specs: %w", err)
+	}
+	for i := range specs {
+		specs[i].setDefaults()
+		if err := specs[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return specs, nil
+}
@@ -0,0 +1,97 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestLoadSpec(t *testing.T) {
+	s, err := LoadSpec([]byte(`{"args": ["/bin/sh", "-c", "echo $FOO"], "env": ["FOO=bar"], "labels": {"team": "a"}}`), nil)
+	assert.NilError(t, err)
+	assert.Equal(t, s.Name, "sh")
+	assert.Equal(t, s.Cost, int64(1))
+
+	c, err := s.Command(context.Background(), nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, c.Labels(), map[string]string{"team": "a"})
+
+	out, err := c.Output(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "bar\n")
+
+	_, err = LoadSpec([]byte(`{"name": "empty"}`), nil)
+	assert.ErrorContains(t, err, "no program specified")
+
+	_, err = LoadSpecs([]byte(`[{"args": ["true"]}, {"args": ["true"], "env": ["FOO"]}]`), nil)
+	assert.ErrorContains(t, err, "invalid environment variable")
+}
+
+func TestSpecPolicies(t *testing.T) {
+	s, err := LoadSpec([]byte(`{
+		"args": ["sleep", "99999"],
+		"limits": {"timeout": "50ms", "max_output": 1024, "rlimits": {"nofile": 64}},
+		"cancel": {"signal": "SIGTERM", "grace": "10s", "process_group": true},
+		"retry": {"max_attempts": 3, "backoff": "1s", "max_backoff": "3s", "exit_codes": [2]},
+		"schedule": {"restart": "on-failure", "delay": "1m"}
+	}`), nil)
+	assert.NilError(t, err)
+	assert.Equal(t, time.Duration(s.Limits.Timeout), 50*time.Millisecond)
+	assert.Equal(t, time.Duration(s.Schedule.Delay), time.Minute)
+
+	p := s.RetryPolicy()
+	assert.Equal(t, p.MaxAttempts, 3)
+	assert.DeepEqual(t, p.RetryableExitCodes, []int{2})
+	assert.Equal(t, p.Backoff(2), time.Second)
+	assert.Equal(t, p.Backoff(4), 3*time.Second)
+
+	if runtime.GOOS != "windows" {
+		// The timeout cancels the command, which is stopped with SIGTERM.
+		c, err := s.Command(context.Background(), nil)
+		assert.NilError(t, err)
+		err = c.Run()
+		assert.Assert(t, errors.Is(err, context.DeadlineExceeded), err)
+		assert.Equal(t, c.Result().Signal, syscall.SIGTERM)
+	}
+
+	for _, bad := range []string{
+		`{"args": ["true"], "limits": {"rlimits": {"bogus": 1}}}`,
+		`{"args": ["true"], "cancel": {"signal": "SIGBOGUS"}}`,
+		`{"args": ["true"], "schedule": {"restart": "sometimes"}}`,
+		`{"args": ["true"], "retry": {"max_attempts": -1}}`,
+	} {
+		_, err := LoadSpec([]byte(bad), nil)
+		assert.Assert(t, err != nil, bad)
+	}
+	_, err = LoadSpec([]byte(`{"args": ["true"], "limits": {"timeout": "soon"}}`), nil)
+	assert.ErrorContains(t, err, "invalid duration")
+}
+
+func TestSpecCommandInvalid(t *testing.T) {
+	var s Spec
+	_, err := s.Command(context.Background(), nil)
+	assert.ErrorContains(t, err, "no program specified")
+}
+
+func TestSpecSupervise(t *testing.T) {
+	s := Spec{Args: []string{"false"}, Schedule: SpecSchedule{Restart: "on-failure", Delay: Duration(time.Millisecond), PoisonCrashes: 3, PoisonWithin: Duration(time.Minute)}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sup, err := s.Supervise(ctx)
+	assert.NilError(t, err)
+	for i := 0; i < 500 && sup.State() != SupervisorPoisoned; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, sup.State(), SupervisorPoisoned)
+	assert.Equal(t, sup.Restarts(), 2)
+	cancel()
+	<-sup.Done()
+
+	_, err = (&Spec{}).Supervise(context.Background())
+	assert.ErrorContains(t, err, "no program specified")
+}
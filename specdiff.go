@@ -0,0 +1,151 @@
+package execctx
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ChangeKind is the kind of a `SpecChange`.
+type ChangeKind int
+
+const (
+	// ChangeModified means the value differs between the specs.
+	ChangeModified ChangeKind = iota
+	// ChangeAdded means the value is only set in the new spec.
+	ChangeAdded
+	// ChangeRemoved means the value is only set in the old spec.
+	ChangeRemoved
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	default:
+		return "modified"
+	}
+}
+
+// SpecChange is a single difference between two specs.
+type SpecChange struct {
+	// Field is the name of the changed field. Environment variables and
+	// labels are reported per key as "env.KEY" and "labels.KEY".
+	// A change of "env" means the spec switched between inheriting the
+	// environment and setting it explicitly.
+	Field string
+	Kind  ChangeKind
+	Old   string
+	New   string
+	// Restart is set if a running process needs to be restarted to pick up
+	// the change. Metadata such as the name, labels, cost, retry policy, and
+	// schedule only affect how the command is managed, not the process
+	// itself.
+	Restart bool
+}
+
+func (c SpecChange) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+%s: %s", c.Field, c.New)
+	case ChangeRemoved:
+		return fmt.Sprintf("-%s: %s", c.Field, c.Old)
+	default:
+		return fmt.Sprintf("~%s: %s -> %s", c.Field, c.Old, c.New)
+	}
+}
+
+// DiffSpecs returns the differences between two specs, in a stable order.
+// Defaults are not applied, use specs returned by `LoadSpec` to compare
+// them as they run.
+func DiffSpecs(old, new Spec) []SpecChange {
+	var changes []SpecChange
+	diff := func(field, o, n string, restart bool) {
+		if o == n {
+			return
+		}
+		changes = append(changes, SpecChange{Field: field, Kind: ChangeModified, Old: o, New: n, Restart: restart})
+	}
+
+	diff("name", old.Name, new.Name, false)
+	diff("args", fmt.Sprintf("%q", old.Args), fmt.Sprintf("%q", new.Args), true)
+	diff("dir", old.Dir, new.Dir, true)
+
+	if (old.Env == nil) != (new.Env == nil) {
+		diff("env", envMode(old.Env), envMode(new.Env), true)
+	}
+	changes = append(changes, diffMaps("env.", envToMap(old.Env), envToMap(new.Env), true)...)
+	changes = append(changes, diffMaps("labels.", old.Labels, new.Labels, false)...)
+
+	diff("cost", strconv.FormatInt(old.Cost, 10), strconv.FormatInt(new.Cost, 10), false)
+
+	// The limits and cancel policy are set on the process when it starts.
+	diff("limits", jsonString(old.Limits), jsonString(new.Limits), true)
+	diff("cancel", jsonString(old.Cancel), jsonString(new.Cancel), true)
+	diff("retry", jsonString(old.Retry), jsonString(new.Retry), false)
+	diff("schedule", jsonString(old.Schedule), jsonString(new.Schedule), false)
+	return changes
+}
+
+// NeedsRestart reports whether any of the changes requires a running process
+// to be restarted.
+func NeedsRestart(changes []SpecChange) bool {
+	for _, c := range changes {
+		if c.Restart {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonString(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func envMode(env []string) string {
+	if env == nil {
+		return "inherited"
+	}
+	return "explicit"
+}
+
+func envToMap(env []string) map[string]string {
+	m := make(map[string]string, len(env))
+	for _, kv := range dedupEnv(env) {
+		k, v := splitEnv(kv)
+		m[k] = v
+	}
+	return m
+}
+
+func diffMaps(prefix string, old, new map[string]string, restart bool) []SpecChange {
+	keys := make([]string, 0, len(old)+len(new))
+	for k := range old {
+		keys = append(keys, k)
+	}
+	for k := range new {
+		if _, ok := old[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []SpecChange
+	for _, k := range keys {
+		o, inOld := old[k]
+		n, inNew := new[k]
+		switch {
+		case !inOld:
+			changes = append(changes, SpecChange{Field: prefix + k, Kind: ChangeAdded, New: n, Restart: restart})
+		case !inNew:
+			changes = append(changes, SpecChange{Field: prefix + k, Kind: ChangeRemoved, Old: o, Restart: restart})
+		case o != n:
+			changes = append(changes, SpecChange{Field: prefix + k, Kind: ChangeModified, Old: o, New: n, Restart: restart})
+		}
+	}
+	return changes
+}
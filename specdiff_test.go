@@ -0,0 +1,62 @@
+package execctx
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestDiffSpecs(t *testing.T) {
+	old := Spec{
+		Name:   "worker",
+		Args:   []string{"worker", "--fast"},
+		Env:    []string{"A=1", "B=2"},
+		Labels: map[string]string{"team": "a"},
+		Cost:   1,
+	}
+
+	assert.Equal(t, len(DiffSpecs(old, old)), 0)
+
+	new := old
+	new.Env = []string{"A=1", "B=3", "C=4"}
+	new.Labels = map[string]string{"team": "b"}
+	new.Cost = 2
+
+	changes := DiffSpecs(old, new)
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	assert.DeepEqual(t, got, []string{
+		"~env.B: 2 -> 3",
+		"+env.C: 4",
+		"~labels.team: a -> b",
+		"~cost: 1 -> 2",
+	})
+	assert.Assert(t, NeedsRestart(changes))
+
+	new = old
+	new.Labels = nil
+	changes = DiffSpecs(old, new)
+	assert.Equal(t, len(changes), 1)
+	assert.Equal(t, changes[0].Kind, ChangeRemoved)
+	assert.Assert(t, !NeedsRestart(changes))
+
+	new = old
+	new.Env = nil
+	changes = DiffSpecs(old, new)
+	assert.Equal(t, changes[0].String(), "~env: explicit -> inherited")
+
+	new = old
+	new.Schedule.Restart = "never"
+	changes = DiffSpecs(old, new)
+	assert.Equal(t, changes[0].String(), `~schedule: {} -> {"restart":"never"}`)
+	assert.Assert(t, !NeedsRestart(changes))
+
+	new = old
+	new.Limits.Timeout = Duration(time.Second)
+	changes = DiffSpecs(old, new)
+	assert.Equal(t, changes[0].String(), `~limits: {} -> {"timeout":"1s"}`)
+	assert.Assert(t, NeedsRestart(changes))
+}
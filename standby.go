@@ -0,0 +1,154 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrStandbyClosed is used as the cancellation cause for idle instances when
+// a `Standby` is closed, and returned from `Get` after it is closed.
+var ErrStandbyClosed = errors.New("execctx: standby closed")
+
+// Standby keeps a number of instances of a command started and idle, so they
+// can be handed out without paying the startup cost of the command.
+// This is meant for interpreter style helpers (python, node, etc.) which
+// block reading their stdin until they are given work.
+//
+// Idle instances are not monitored, an instance which exits on its own while
+// idle is only noticed by whoever checks it out.
+type Standby struct {
+	ctx    context.Context
+	newCmd func(context.Context) *Cmd
+	ready  chan *Instance
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// Instance is a command handed out by a `Standby`.
+type Instance struct {
+	*Cmd
+	// Stdin is connected to the stdin of the command.
+	Stdin io.WriteCloser
+	// Stdout is connected to the stdout of the command.
+	Stdout io.ReadCloser
+}
+
+// NewStandby creates a Standby which keeps n instances of the command
+// created by newCmd ready.
+//
+// newCmd is called with the context passed to NewStandby and must return an
+// unstarted command with stdin and stdout left unset, the standby connects
+// them to pipes.
+func NewStandby(ctx context.Context, n int, newCmd func(context.Context) *Cmd) *Standby {
+	s := &Standby{
+		ctx:    ctx,
+		newCmd: newCmd,
+		ready:  make(chan *Instance, n),
+	}
+	for i := 0; i < n; i++ {
+		go s.replenish()
+	}
+	return s
+}
+
+func (s *Standby) spawn() (*Instance, error) {
+	c := s.newCmd(s.ctx)
+	stdin, err := c.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := c.cmd.StdoutPipe()
+	if err != nil {
+		stdin.Close()
+		return nil, err
+	}
+	if err := c.Start(); err != nil {
+		stdin.Close()
+		stdout.Close()
+		return nil, err
+	}
+	return &Instance{Cmd: c, Stdin: stdin, Stdout: stdout}, nil
+}
+
+// replenish starts a new idle instance.
+// Failures are ignored, `Get` falls back to starting an instance on demand.
+func (s *Standby) replenish() {
+	inst, err := s.spawn()
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		inst.Cancel(ErrStandbyClosed)
+		go inst.Wait()
+		return
+	}
+	s.ready <- inst
+}
+
+// Get hands out an instance, starting one if none are ready.
+//
+// The instance is cancelled, as if its own context was cancelled, when the
+// passed in context is done. The caller must `Wait` on the instance once it
+// is done with it.
+func (s *Standby) Get(ctx context.Context) (*Instance, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var inst *Instance
+	select {
+	case ready, ok := <-s.ready:
+		if !ok {
+			return nil, ErrStandbyClosed
+		}
+		inst = ready
+		go s.replenish()
+	default:
+		s.mu.Lock()
+		closed := s.closed
+		s.mu.Unlock()
+		if closed {
+			return nil, ErrStandbyClosed
+		}
+
+		var err error
+		inst, err = s.spawn()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			inst.Cancel(ctx.Err())
+		case <-inst.waitDone:
+		}
+	}()
+	return inst, nil
+}
+
+// Close stops all idle instances and prevents new ones from being started.
+func (s *Standby) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.ready)
+	s.mu.Unlock()
+
+	for inst := range s.ready {
+		inst.Cancel(ErrStandbyClosed)
+		inst.Stdin.Close()
+		inst.Wait()
+	}
+	return nil
+}
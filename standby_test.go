@@ -0,0 +1,41 @@
+package execctx
+
+import (
+	"context"
+	"io/ioutil"
+	"os/exec"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestStandby(t *testing.T) {
+	s := NewStandby(context.Background(), 1, func(ctx context.Context) *Cmd {
+		return FromCmd(ctx, exec.Command("cat"), nil)
+	})
+	defer s.Close()
+
+	for i := 0; i < 3; i++ {
+		inst, err := s.Get(context.Background())
+		assert.NilError(t, err)
+
+		_, err = inst.Stdin.Write([]byte("hello\n"))
+		assert.NilError(t, err)
+		inst.Stdin.Close()
+
+		out, err := ioutil.ReadAll(inst.Stdout)
+		assert.NilError(t, err)
+		assert.Equal(t, string(out), "hello\n")
+		assert.NilError(t, inst.Wait())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	inst, err := s.Get(ctx)
+	assert.NilError(t, err)
+	cancel()
+	assert.ErrorContains(t, inst.Wait(), "killed")
+
+	assert.NilError(t, s.Close())
+	_, err = s.Get(context.Background())
+	assert.Equal(t, err, ErrStandbyClosed)
+}
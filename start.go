@@ -0,0 +1,64 @@
+package execctx
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrStartTimeout is returned by `Start` when starting the process took longer
+// than the timeout set with `WithStartTimeout`.
+var ErrStartTimeout = errors.New("execctx: timeout starting process")
+
+// WithStartTimeout bounds how long starting the process may take.
+// This covers things like resolving the program and loading it from slow
+// storage, before the process is actually running.
+//
+// If the timeout expires `Start` returns an error wrapping `ErrStartTimeout`.
+// Should the process still start after that, it is killed and reaped in the
+// background, and the resources set up for it, like its temporary directory,
+// are only released then.
+func WithStartTimeout(d time.Duration) Option {
+	return func(c *Cmd) {
+		c.startTimeout = d
+	}
+}
+
+func (c *Cmd) startProcess() error {
+	if c.startTimeout <= 0 {
+		return c.cmd.Start()
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.cmd.Start()
+	}()
+
+	timer := time.NewTimer(c.startTimeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-timer.C:
+		// Start is still running and may still use the files and dirs set
+		// up for the process, so the started hooks and the release are left
+		// to run once it returns.
+		started, releasers := c.started, c.releasers
+		c.started, c.releasers = nil, nil
+		go func() {
+			err := <-errCh
+			for _, f := range started {
+				f()
+			}
+			if err == nil {
+				c.kill()
+				c.reap()
+			}
+			for i := len(releasers) - 1; i >= 0; i-- {
+				releasers[i]()
+			}
+		}()
+		return fmt.Errorf("%w after %s", ErrStartTimeout, c.startTimeout)
+	}
+}
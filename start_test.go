@@ -0,0 +1,42 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestStartTimeout(t *testing.T) {
+	c := FromCmd(context.Background(), exec.Command("true"), nil, WithStartTimeout(time.Minute))
+	assert.NilError(t, c.Run())
+
+	c = FromCmd(context.Background(), exec.Command("true"), nil, WithStartTimeout(time.Nanosecond))
+	err := c.Start()
+	if err == nil {
+		// Starting a process should never take less than a nanosecond, but
+		// don't fail if this machine is somehow that fast.
+		assert.NilError(t, c.Wait())
+		return
+	}
+	assert.Assert(t, errors.Is(err, ErrStartTimeout), err)
+}
+
+func TestStartTimeoutRelease(t *testing.T) {
+	// The resources of the process are only released once the start which
+	// timed out returned.
+	released := make(chan bool, 1)
+	c := FromCmd(context.Background(), exec.Command("true"), nil, WithStartTimeout(time.Nanosecond), func(c *Cmd) {
+		c.onRelease(func() { released <- c.cmd.Process != nil })
+	})
+	err := c.Start()
+	if err == nil {
+		assert.NilError(t, c.Wait())
+		return
+	}
+	assert.Assert(t, errors.Is(err, ErrStartTimeout), err)
+	assert.Assert(t, <-released)
+}
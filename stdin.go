@@ -0,0 +1,112 @@
+package execctx
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// WithStdinString is like `WithStdinReader` with the string as input.
+func WithStdinString(s string) Option {
+	return WithStdinReader(strings.NewReader(s))
+}
+
+// WithStdinBytes is like `WithStdinReader` with b as input.
+func WithStdinBytes(b []byte) Option {
+	return WithStdinReader(bytes.NewReader(b))
+}
+
+// WithStdinReader feeds the stdin of the process from r through a pipe.
+//
+// Unlike setting a reader with `WithStdin`, stdin is closed as soon as the
+// command is cancelled, so a process blocked reading its input sees EOF and
+// can exit on its own, for instance with a cancel handler which waits for it.
+// Stdin is also closed once r is exhausted or the process stops reading.
+// A read from r which is blocked when the command is cancelled is left to
+// finish in the background.
+//
+// This replaces any stdin set on the command.
+func WithStdinReader(r io.Reader) Option {
+	return func(c *Cmd) {
+		c.stdinReader = r
+		c.stdinChan = nil
+	}
+}
+
+// WithStdinChan feeds the stdin of the process from ch. Each slice received
+// is written to the process in full before the next one is received, so a
+// producer is held back while the process doesn't read its input.
+//
+// Stdin is closed once ch is closed, when the command is cancelled so the
+// process sees EOF, or when the process stops reading.
+// After that ch is no longer read, producers should stop sending when the
+// command's context is done.
+//
+// This replaces any stdin set on the command.
+func WithStdinChan(ch <-chan []byte) Option {
+	return func(c *Cmd) {
+		c.stdinChan = ch
+		c.stdinReader = nil
+	}
+}
+
+func (c *Cmd) setupStdinFeed() error {
+	var feed func(w io.Writer, stop <-chan struct{})
+	switch {
+	case c.stdinChan != nil:
+		feed = c.feedStdinChan
+	case c.stdinReader != nil:
+		feed = func(w io.Writer, stop <-chan struct{}) {
+			io.Copy(w, c.stdinReader)
+		}
+	default:
+		return nil
+	}
+
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	c.cmd.Stdin = pr
+
+	var once sync.Once
+	closeStdin := func() { once.Do(func() { pw.Close() }) }
+	c.onStarted(func() { pr.Close() })
+	c.onRelease(closeStdin)
+	c.onProcessStart(func() {
+		stop := make(chan struct{})
+		go func() {
+			defer close(stop)
+			// A write blocked on a full pipe is only interrupted by closing
+			// stdin from another goroutine.
+			select {
+			case <-c.cancelled:
+			case <-c.waitDone:
+			}
+			closeStdin()
+		}()
+		go func() {
+			defer closeStdin()
+			feed(pw, stop)
+		}()
+	})
+	return nil
+}
+
+func (c *Cmd) feedStdinChan(w io.Writer, stop <-chan struct{}) {
+	for {
+		select {
+		case b, ok := <-c.stdinChan:
+			if !ok {
+				return
+			}
+			if _, err := w.Write(b); err != nil {
+				return
+			}
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package execctx
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestWithStdinChan(t *testing.T) {
+	ch := make(chan []byte)
+	var out strings.Builder
+	c := FromCmd(context.Background(), exec.Command("cat"), nil, WithStdinChan(ch), WithStdout(&out))
+	assert.NilError(t, c.Start())
+	for _, s := range []string{"a", "b", "c\n"} {
+		ch <- []byte(s)
+	}
+	close(ch)
+	assert.NilError(t, c.Wait())
+	assert.Equal(t, out.String(), "abc\n")
+}
+
+func TestWithStdinChanCancel(t *testing.T) {
+	// A producer which never closes the channel, with a large write pending.
+	ch := make(chan []byte, 1)
+	ch <- make([]byte, 1<<20)
+
+	var out strings.Builder
+	cmd := exec.Command("sh", "-c", "sleep 0.1; cat >/dev/null; echo eof")
+	// The handler does nothing, the process exits because stdin is closed.
+	c := FromCmd(context.Background(), cmd, func() {}, WithStdinChan(ch), WithStdout(&out))
+	assert.NilError(t, c.Start())
+	time.Sleep(50 * time.Millisecond)
+	c.Cancel(nil)
+	assert.NilError(t, c.Wait())
+	assert.Equal(t, out.String(), "eof\n")
+}
+
+func TestWithStdinReader(t *testing.T) {
+	ctx := context.Background()
+
+	var out strings.Builder
+	c := FromCmd(ctx, exec.Command("cat"), nil, WithStdinString("hello\n"), WithStdout(&out))
+	assert.NilError(t, c.Run())
+	assert.Equal(t, out.String(), "hello\n")
+
+	out.Reset()
+	c = FromCmd(ctx, exec.Command("cat"), nil, WithStdinBytes([]byte("bytes\n")), WithStdout(&out))
+	assert.NilError(t, c.Run())
+	assert.Equal(t, out.String(), "bytes\n")
+
+	// The reader never ends, the process only exits because stdin is closed
+	// when the command is cancelled.
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	out.Reset()
+	echoed := make(chan struct{}, 1)
+	c = FromCmd(ctx, exec.Command("sh", "-c", "cat; echo eof"), func() {}, WithStdinReader(pr), WithStdout(&out),
+		OnStdoutLine(func([]byte) {
+			select {
+			case echoed <- struct{}{}:
+			default:
+			}
+		}))
+	assert.NilError(t, c.Start())
+	_, err := pw.Write([]byte("partial\n"))
+	assert.NilError(t, err)
+	<-echoed
+	c.Cancel(nil)
+	assert.NilError(t, c.Wait())
+	assert.Equal(t, out.String(), "partial\neof\n")
+}
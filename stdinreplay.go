@@ -0,0 +1,197 @@
+package execctx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// defaultStdinMemory is how much stdin `Retry` keeps in memory for replay
+// when `RetryPolicy.StdinMemory` is not set.
+const defaultStdinMemory = 1 << 20
+
+// ErrStdinNotReplayable is returned by `Retry` when the command would be
+// retried but its stdin can't be fed to it again.
+var ErrStdinNotReplayable = errors.New("execctx: stdin can't be replayed")
+
+// StdinReplayError is returned by `Retry` when a failed attempt would be
+// retried but the stdin it read could not be recorded for replay.
+type StdinReplayError struct {
+	// Err is the error of the failed attempt.
+	Err error
+	// Cause is the reason stdin could not be recorded, for instance
+	// because it exceeded `RetryPolicy.StdinLimit`.
+	Cause error
+}
+
+func (e *StdinReplayError) Error() string {
+	return fmt.Sprintf("%v; not retried, stdin can't be replayed: %v", e.Err, e.Cause)
+}
+
+func (e *StdinReplayError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is `ErrStdinNotReplayable`.
+func (e *StdinReplayError) Is(target error) bool {
+	return target == ErrStdinNotReplayable
+}
+
+// stdinTape records what is read from a stdin reader so every attempt of a
+// retried command reads the same input. Input is kept in memory up to
+// memLimit and spilled to a temporary file beyond that.
+//
+// A read from the source which is left running in the background by a
+// cancelled attempt is still recorded, so the next attempt doesn't miss it.
+type stdinTape struct {
+	src      io.Reader
+	memLimit int64
+	limit    int64
+
+	// readMu serializes reads from src.
+	readMu sync.Mutex
+
+	mu     sync.Mutex
+	mem    []byte
+	file   *os.File
+	size   int64
+	srcErr error // the error which ended src, io.EOF once it is exhausted
+	err    error // why the input is not fully recorded
+}
+
+func newStdinTape(src io.Reader, memLimit, limit int64) *stdinTape {
+	if memLimit <= 0 {
+		memLimit = defaultStdinMemory
+	}
+	return &stdinTape{src: src, memLimit: memLimit, limit: limit}
+}
+
+// reader returns a reader which reads the input from the start.
+func (t *stdinTape) reader() io.Reader {
+	return &tapeReader{t: t}
+}
+
+// recordErr returns why the input could not be recorded, if it couldn't.
+func (t *stdinTape) recordErr() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+func (t *stdinTape) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file != nil {
+		t.file.Close()
+		os.Remove(t.file.Name())
+	}
+	if t.err == nil {
+		t.err = errors.New("stdin recording closed")
+	}
+}
+
+// readAt reads recorded input at off. It must be called with t.mu held.
+func (t *stdinTape) readAt(p []byte, off int64) (int, error) {
+	if max := t.size - off; int64(len(p)) > max {
+		p = p[:max]
+	}
+	if off < int64(len(t.mem)) {
+		return copy(p, t.mem[off:]), nil
+	}
+	return t.file.ReadAt(p, off-int64(len(t.mem)))
+}
+
+// record appends p to the recording. It must be called with t.mu held.
+func (t *stdinTape) record(p []byte) error {
+	if t.err != nil {
+		return t.err
+	}
+	if t.limit > 0 && t.size+int64(len(p)) > t.limit {
+		return fmt.Errorf("more than %d bytes read", t.limit)
+	}
+	if t.file == nil && int64(len(t.mem)+len(p)) <= t.memLimit {
+		t.mem = append(t.mem, p...)
+		t.size += int64(len(p))
+		return nil
+	}
+	if t.file == nil {
+		f, err := ioutil.TempFile("", "execctx-stdin")
+		if err != nil {
+			return err
+		}
+		t.file = f
+	}
+	if _, err := t.file.WriteAt(p, t.size-int64(len(t.mem))); err != nil {
+		return err
+	}
+	t.size += int64(len(p))
+	return nil
+}
+
+type tapeReader struct {
+	t   *stdinTape
+	off int64
+	// live is set once the reader has read input which could not be
+	// recorded, from then on it reads from the source directly.
+	live bool
+}
+
+func (r *tapeReader) Read(p []byte) (int, error) {
+	t := r.t
+	if r.live {
+		t.readMu.Lock()
+		defer t.readMu.Unlock()
+		return t.src.Read(p)
+	}
+
+	for {
+		t.mu.Lock()
+		if r.off < t.size {
+			n, err := t.readAt(p, r.off)
+			t.mu.Unlock()
+			r.off += int64(n)
+			return n, err
+		}
+		if err := t.srcErr; err != nil {
+			t.mu.Unlock()
+			return 0, err
+		}
+		if t.err != nil {
+			t.mu.Unlock()
+			r.live = true
+			return r.Read(p)
+		}
+		t.mu.Unlock()
+
+		t.readMu.Lock()
+		t.mu.Lock()
+		if r.off < t.size || t.srcErr != nil || t.err != nil {
+			// Another reader got to the source first.
+			t.mu.Unlock()
+			t.readMu.Unlock()
+			continue
+		}
+		t.mu.Unlock()
+
+		n, err := t.src.Read(p)
+
+		t.mu.Lock()
+		if n > 0 {
+			if rerr := t.record(p[:n]); rerr != nil {
+				t.err = rerr
+				r.live = true
+			} else {
+				r.off += int64(n)
+			}
+		}
+		if err != nil {
+			t.srcErr = err
+		}
+		t.mu.Unlock()
+		t.readMu.Unlock()
+		return n, err
+	}
+}
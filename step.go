@@ -0,0 +1,51 @@
+package execctx
+
+import (
+	"context"
+	"sync"
+)
+
+// Step returns a function which runs the spec under ctx, the shape task and
+// workflow frameworks commonly accept for a step. Every call creates and
+// runs a new command, with opts applied.
+//
+// Combine it with `WithLogf` to log the step, and `OnExit` to collect the
+// `Result`.
+func (s *Spec) Step(opts ...Option) func(ctx context.Context) error {
+	spec := *s
+	return func(ctx context.Context) error {
+		c, err := spec.Command(ctx, nil, opts...)
+		if err != nil {
+			return err
+		}
+		return c.Run()
+	}
+}
+
+// WithLogf logs the command through logf, which has the signature of
+// `log.Printf` and `testing.T.Logf`: the command line when it starts, every
+// line of output, and how it exited. Calls to logf are serialized.
+func WithLogf(logf func(format string, args ...interface{})) Option {
+	var mu sync.Mutex
+	log := func(format string, args ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		logf(format, args...)
+	}
+
+	return func(c *Cmd) {
+		name := c.cmd.Args[0]
+		OnStarted(func(c *Cmd) {
+			log("%s: started (pid %d)", c, c.Pid())
+		})(c)
+		OnStdoutLine(func(line []byte) {
+			log("%s: %s", name, line)
+		})(c)
+		OnStderrLine(func(line []byte) {
+			log("%s: stderr: %s", name, line)
+		})(c)
+		OnExit(func(r Result) {
+			log("%s: exited with code %d after %s", name, r.ExitCode, r.Duration)
+		})(c)
+	}
+}
@@ -0,0 +1,42 @@
+package execctx
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestStep(t *testing.T) {
+	var logs []string
+	logf := func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}
+
+	spec := Spec{Args: []string{"sh", "-c", "echo out; echo err >&2; exit 1"}}
+	step := spec.Step(WithLogf(logf))
+	assert.ErrorContains(t, step(context.Background()), "exit status 1")
+
+	assert.Equal(t, len(logs), 4, logs)
+	assert.Assert(t, strings.Contains(logs[0], "started (pid "), logs[0])
+	assert.Assert(t, strings.HasPrefix(logs[len(logs)-1], "sh: exited with code 1 after "), logs)
+	assert.Assert(t, strings.Contains(strings.Join(logs, "\n"), "sh: out\n"), logs)
+	assert.Assert(t, strings.Contains(strings.Join(logs, "\n"), "sh: stderr: err\n"), logs)
+
+	assert.ErrorContains(t, (&Spec{}).Step()(context.Background()), "no program specified")
+}
+
+func TestWithLogfClone(t *testing.T) {
+	var logs []string
+	logf := func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}
+
+	c := FromCmd(context.Background(), exec.Command("true"), nil, WithLogf(logf))
+	nc := c.Clone(context.Background())
+	assert.NilError(t, nc.Run())
+	assert.Assert(t, strings.Contains(logs[0], fmt.Sprintf("started (pid %d)", nc.Pid())), logs[0])
+}
@@ -0,0 +1,107 @@
+package execctx
+
+import (
+	"os"
+	"time"
+)
+
+// stopFilePollInterval is how often the files passed to `WithStopFile` are
+// checked.
+const stopFilePollInterval = 500 * time.Millisecond
+
+// StopError is the cancellation cause for commands stopped by an external
+// trigger set with `WithStopFile` or `WithStopChan`.
+type StopError struct {
+	// Trigger describes which trigger fired, e.g. "file /run/stop".
+	Trigger string
+}
+
+func (e *StopError) Error() string {
+	return "execctx: stopped by " + e.Trigger
+}
+
+// WithStopFile cancels the command once the file at path exists, in addition
+// to the context. The cause is a `*StopError`.
+// This gives operators an escape hatch, such as
+// "touch /var/run/stop-workers", which doesn't require reaching into the
+// process. If the file already exists when the command is started, `Start`
+// fails with the cause.
+//
+// The file is polled, so it may take up to half a second to notice it.
+func WithStopFile(path string) Option {
+	return func(c *Cmd) {
+		c.stopFiles = append(c.stopFiles, path)
+	}
+}
+
+// WithStopChan cancels the command once ch is closed or receives a value,
+// in addition to the context. The cause is a `*StopError` with the
+// trigger set to name.
+func WithStopChan(name string, ch <-chan struct{}) Option {
+	return func(c *Cmd) {
+		c.stopChans = append(c.stopChans, stopChan{name: name, ch: ch})
+	}
+}
+
+type stopChan struct {
+	name string
+	ch   <-chan struct{}
+}
+
+// checkStopTriggers cancels the command if any stop trigger already fired.
+func (c *Cmd) checkStopTriggers() {
+	for _, sc := range c.stopChans {
+		select {
+		case <-sc.ch:
+			c.Cancel(&StopError{Trigger: sc.name})
+			return
+		default:
+		}
+	}
+	if p, ok := c.stopFileExists(); ok {
+		c.Cancel(&StopError{Trigger: "file " + p})
+	}
+}
+
+func (c *Cmd) stopFileExists() (string, bool) {
+	for _, p := range c.stopFiles {
+		if _, err := os.Stat(p); err == nil {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+func (c *Cmd) watchStopTriggers() {
+	for _, sc := range c.stopChans {
+		go func(sc stopChan) {
+			select {
+			case <-sc.ch:
+				c.Cancel(&StopError{Trigger: sc.name})
+			case <-c.cancelled:
+			case <-c.waitDone:
+			}
+		}(sc)
+	}
+
+	if len(c.stopFiles) == 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(stopFilePollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-c.cancelled:
+				return
+			case <-c.waitDone:
+				return
+			}
+			if p, ok := c.stopFileExists(); ok {
+				c.Cancel(&StopError{Trigger: "file " + p})
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,45 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestStopFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "execctx-stop")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	stop := filepath.Join(dir, "stop")
+
+	c := FromCmd(context.Background(), exec.Command("sleep", "99999"), nil, WithStopFile(stop))
+	assert.NilError(t, c.Start())
+	assert.NilError(t, ioutil.WriteFile(stop, nil, 0600))
+
+	err = c.Wait()
+	var stopErr *StopError
+	assert.Assert(t, errors.As(err, &stopErr), err)
+	assert.Equal(t, stopErr.Trigger, "file "+stop)
+
+	// The file is checked before starting.
+	c = FromCmd(context.Background(), exec.Command("true"), nil, WithStopFile(stop))
+	assert.Assert(t, errors.As(c.Start(), &stopErr))
+}
+
+func TestStopChan(t *testing.T) {
+	ch := make(chan struct{})
+	c := FromCmd(context.Background(), exec.Command("sleep", "99999"), nil, WithStopChan("drain", ch))
+	assert.NilError(t, c.Start())
+	close(ch)
+
+	err := c.Wait()
+	var stopErr *StopError
+	assert.Assert(t, errors.As(err, &stopErr), err)
+	assert.Equal(t, stopErr.Trigger, "drain")
+}
@@ -0,0 +1,33 @@
+package execctx
+
+import (
+	"time"
+)
+
+// StrayProcess is a child process of the current process reported by
+// `ScanStrayChildren`.
+type StrayProcess struct {
+	Pid  int
+	Args []string
+	// Age is how long ago the process was started.
+	Age time.Duration
+	// Zombie is set if the process has exited but was not reaped yet.
+	Zombie bool
+	// Cmd is the command the process belongs to, or nil if it was not
+	// started through this package.
+	Cmd *Cmd
+}
+
+// ScanStrayChildren scans the process table for children of the current
+// process which point to lifecycle bugs: processes not started through this
+// package, and processes started through it which exited but were never
+// waited on. It is cheap enough to call from health endpoints.
+//
+// This is only supported on Linux.
+func ScanStrayChildren() ([]StrayProcess, error) {
+	known := make(map[int]*Cmd)
+	for _, c := range Running() {
+		known[c.Pid()] = c
+	}
+	return scanStrayChildren(known)
+}
@@ -0,0 +1,92 @@
+package execctx
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is the unit of process start times in /proc, USER_HZ, which is
+// 100 on all Linux platforms Go supports.
+const clockTicks = 100
+
+func scanStrayChildren(known map[int]*Cmd) ([]StrayProcess, error) {
+	boot, err := bootTime()
+	if err != nil {
+		return nil, err
+	}
+
+	dirs, err := filepath.Glob("/proc/[0-9]*")
+	if err != nil {
+		return nil, err
+	}
+
+	self := os.Getpid()
+	now := time.Now()
+	var stray []StrayProcess
+	for _, dir := range dirs {
+		st, err := readProcStat(filepath.Join(dir, "stat"))
+		if err != nil || st.ppid != self {
+			// The process may have exited since listing the directory.
+			continue
+		}
+		pid, _ := strconv.Atoi(filepath.Base(dir))
+		c := known[pid]
+		zombie := st.state == 'Z'
+		if c != nil && !zombie {
+			continue
+		}
+
+		p := StrayProcess{
+			Pid:    pid,
+			Args:   readCmdline(filepath.Join(dir, "cmdline")),
+			Age:    now.Sub(boot.Add(time.Duration(st.start) * time.Second / clockTicks)),
+			Zombie: zombie,
+			Cmd:    c,
+		}
+		if len(p.Args) == 0 && c != nil {
+			// The command line of zombies is gone.
+			p.Args = append([]string(nil), c.cmd.Args...)
+		}
+		stray = append(stray, p)
+	}
+	return stray, nil
+}
+
+func bootTime() (time.Time, error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if !strings.HasPrefix(line, "btime ") {
+			continue
+		}
+		sec, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(line, "btime ")), 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(sec, 0), nil
+	}
+	if err := s.Err(); err != nil {
+		return time.Time{}, err
+	}
+	return time.Time{}, errProcStat
+}
+
+func readCmdline(path string) []string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	return strings.Split(string(bytes.TrimSuffix(data, []byte{0})), "\x00")
+}
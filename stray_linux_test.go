@@ -0,0 +1,59 @@
+package execctx
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestScanStrayChildren(t *testing.T) {
+	unknown := exec.Command("sleep", "99999")
+	assert.NilError(t, unknown.Start())
+	defer func() {
+		unknown.Process.Kill()
+		unknown.Wait()
+	}()
+
+	unreaped := FromCmd(context.Background(), exec.Command("true"), nil)
+	assert.NilError(t, unreaped.Start())
+	defer unreaped.Wait()
+
+	running := FromCmd(context.Background(), exec.Command("sleep", "99999"), nil)
+	assert.NilError(t, running.Start())
+	defer func() {
+		running.Cancel(nil)
+		running.Wait()
+	}()
+
+	// Give the unreaped command time to exit. Other tests may leave stray
+	// processes behind too, so only look at the ones started here.
+	var byPid map[int]StrayProcess
+	for i := 0; i < 100; i++ {
+		stray, err := ScanStrayChildren()
+		assert.NilError(t, err)
+		byPid = make(map[int]StrayProcess)
+		for _, p := range stray {
+			byPid[p.Pid] = p
+		}
+		if byPid[unreaped.Pid()].Zombie {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	_, ok := byPid[running.Pid()]
+	assert.Assert(t, !ok)
+
+	p := byPid[unknown.Process.Pid]
+	assert.Assert(t, p.Cmd == nil)
+	assert.DeepEqual(t, p.Args, []string{"sleep", "99999"})
+	assert.Assert(t, !p.Zombie)
+
+	p = byPid[unreaped.Pid()]
+	assert.Assert(t, p.Cmd == unreaped)
+	assert.Assert(t, p.Zombie)
+	assert.DeepEqual(t, p.Args, []string{"true"})
+}
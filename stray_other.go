@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package execctx
+
+import (
+	"errors"
+	"runtime"
+)
+
+func scanStrayChildren(known map[int]*Cmd) ([]StrayProcess, error) {
+	return nil, errors.New("execctx: scanning child processes is not supported on " + runtime.GOOS)
+}
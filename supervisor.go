@@ -0,0 +1,267 @@
+package execctx
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RestartPolicy decides whether a `Supervisor` restarts its command once it
+// exits.
+type RestartPolicy int
+
+const (
+	// RestartAlways restarts the command whenever it exits.
+	RestartAlways RestartPolicy = iota
+	// RestartOnFailure restarts the command only when it fails, either
+	// exiting with an error or failing to start.
+	RestartOnFailure
+	// RestartNever runs the command once.
+	RestartNever
+)
+
+func (p RestartPolicy) String() string {
+	switch p {
+	case RestartAlways:
+		return "always"
+	case RestartOnFailure:
+		return "on-failure"
+	default:
+		return "never"
+	}
+}
+
+// SupervisorState is the state of a `Supervisor`.
+type SupervisorState int
+
+const (
+	// SupervisorRunning means the command is running.
+	SupervisorRunning SupervisorState = iota
+	// SupervisorBackoff means the command exited and the supervisor waits
+	// before restarting it.
+	SupervisorBackoff
+	// SupervisorPoisoned means the command crashed right after starting too
+	// many times in a row, it is not restarted until `Supervisor.Reset` is
+	// called. See `WithPoisonDetection`.
+	SupervisorPoisoned
+	// SupervisorStopped means the supervisor will not restart the command
+	// anymore.
+	SupervisorStopped
+)
+
+func (s SupervisorState) String() string {
+	switch s {
+	case SupervisorRunning:
+		return "running"
+	case SupervisorBackoff:
+		return "backoff"
+	case SupervisorPoisoned:
+		return "poisoned"
+	default:
+		return "stopped"
+	}
+}
+
+// SupervisorOption configures a `Supervisor`.
+type SupervisorOption func(*Supervisor)
+
+// WithPoisonDetection stops restarting a command which fails within
+// `within` of being started, or fails to start, crashes times in a row.
+// Such a "poison" command would otherwise be restarted in a tight loop
+// forever. The supervisor then enters `SupervisorPoisoned` until `Reset` is
+// called or its context is done.
+func WithPoisonDetection(crashes int, within time.Duration) SupervisorOption {
+	return func(s *Supervisor) {
+		s.poisonCrashes = crashes
+		s.poisonWithin = within
+	}
+}
+
+// OnStateChange calls f whenever the state of the supervisor changes.
+// f is called synchronously by the supervisor and must not block.
+func OnStateChange(f func(SupervisorState)) SupervisorOption {
+	return func(s *Supervisor) {
+		s.onStateChange = append(s.onStateChange, f)
+	}
+}
+
+// Supervisor keeps a command running according to a `RestartPolicy`.
+//
+// When the context passed to `NewSupervisor` is cancelled the running
+// command is cancelled as usual, so its cancel handler (for instance
+// `GracefulKill`) shuts it down, and no more restarts are made.
+type Supervisor struct {
+	ctx    context.Context
+	newCmd func(context.Context) *Cmd
+	policy RestartPolicy
+	delay  time.Duration
+	done   chan struct{}
+	reset  chan struct{}
+
+	poisonCrashes int
+	poisonWithin  time.Duration
+	onStateChange []func(SupervisorState)
+
+	mu       sync.Mutex
+	current  *Cmd
+	restarts int
+	lastErr  error
+	state    SupervisorState
+	crashes  int
+}
+
+// NewSupervisor starts the command created by newCmd and keeps it running
+// according to policy, waiting delay between an exit and the restart.
+//
+// newCmd is called with the context passed to NewSupervisor for every run and
+// must return a new, unstarted command.
+func NewSupervisor(ctx context.Context, policy RestartPolicy, delay time.Duration, newCmd func(context.Context) *Cmd, opts ...SupervisorOption) *Supervisor {
+	s := &Supervisor{
+		ctx:    ctx,
+		newCmd: newCmd,
+		policy: policy,
+		delay:  delay,
+		done:   make(chan struct{}),
+		reset:  make(chan struct{}, 1),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	go s.run()
+	return s
+}
+
+func (s *Supervisor) run() {
+	defer close(s.done)
+	defer s.setState(SupervisorStopped)
+
+	for {
+		c := s.newCmd(s.ctx)
+		s.mu.Lock()
+		s.current = c
+		s.mu.Unlock()
+		s.setState(SupervisorRunning)
+
+		started := time.Now()
+		err := c.Run()
+
+		s.mu.Lock()
+		s.lastErr = err
+		poisoned := s.crashed(err, time.Since(started))
+		s.mu.Unlock()
+
+		if s.ctx.Err() != nil || s.policy == RestartNever || (s.policy == RestartOnFailure && err == nil) {
+			return
+		}
+
+		if poisoned {
+			s.setState(SupervisorPoisoned)
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-s.reset:
+			}
+			s.mu.Lock()
+			s.restarts++
+			s.mu.Unlock()
+			continue
+		}
+
+		s.setState(SupervisorBackoff)
+		timer := time.NewTimer(s.delay)
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.mu.Lock()
+		s.restarts++
+		s.mu.Unlock()
+	}
+}
+
+// crashed records the outcome of a run for poison detection and reports
+// whether the command is now considered poisoned.
+// s.mu must be held.
+func (s *Supervisor) crashed(err error, ran time.Duration) bool {
+	if s.poisonCrashes <= 0 {
+		return false
+	}
+	if err == nil || ran >= s.poisonWithin {
+		s.crashes = 0
+		return false
+	}
+	s.crashes++
+	return s.crashes >= s.poisonCrashes
+}
+
+func (s *Supervisor) setState(state SupervisorState) {
+	s.mu.Lock()
+	changed := s.state != state
+	s.state = state
+	s.mu.Unlock()
+	if changed {
+		for _, f := range s.onStateChange {
+			f(state)
+		}
+	}
+}
+
+// State returns the current state of the supervisor.
+func (s *Supervisor) State() SupervisorState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// Reset clears the count of consecutive crashes used by
+// `WithPoisonDetection`, and restarts the command if it was poisoned.
+func (s *Supervisor) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.crashes = 0
+	if s.state == SupervisorPoisoned {
+		select {
+		case s.reset <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Cmd returns the most recently started command.
+func (s *Supervisor) Cmd() *Cmd {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current
+}
+
+// Restarts returns the number of times the command was restarted.
+func (s *Supervisor) Restarts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.restarts
+}
+
+// LastError returns the error from the most recent run of the command, nil
+// if it succeeded or is still running for the first time.
+func (s *Supervisor) LastError() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastErr
+}
+
+// Done returns a channel which is closed once the supervisor stops
+// restarting the command and the last run has exited. A poisoned supervisor
+// is not done, it may still be reset.
+func (s *Supervisor) Done() <-chan struct{} {
+	return s.done
+}
+
+// Wait waits for the supervisor to stop and returns the error from the last
+// run of the command.
+func (s *Supervisor) Wait() error {
+	<-s.done
+	return s.LastError()
+}
@@ -0,0 +1,93 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestSupervisor(t *testing.T) {
+	runs := 0
+	s := NewSupervisor(context.Background(), RestartOnFailure, time.Millisecond, func(ctx context.Context) *Cmd {
+		runs++
+		if runs < 3 {
+			return FromCmd(ctx, exec.Command("false"), nil)
+		}
+		return FromCmd(ctx, exec.Command("true"), nil)
+	})
+	assert.NilError(t, s.Wait())
+	assert.Equal(t, s.Restarts(), 2)
+
+	s = NewSupervisor(context.Background(), RestartNever, 0, func(ctx context.Context) *Cmd {
+		return FromCmd(ctx, exec.Command("false"), nil)
+	})
+	assert.ErrorContains(t, s.Wait(), "exit status 1")
+	assert.Equal(t, s.Restarts(), 0)
+}
+
+func TestSupervisorCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := NewSupervisor(ctx, RestartAlways, time.Millisecond, func(ctx context.Context) *Cmd {
+		return FromCmd(ctx, exec.Command("sleep", "99999"), nil)
+	})
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-s.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("supervisor did not stop")
+	}
+	assert.Assert(t, errors.Is(s.LastError(), context.Canceled), s.LastError())
+	assert.Equal(t, s.Restarts(), 0)
+}
+
+func TestSupervisorPoison(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var states []SupervisorState
+	poisoned := make(chan struct{}, 1)
+	runs := 0
+	s := NewSupervisor(ctx, RestartAlways, 0, func(ctx context.Context) *Cmd {
+		runs++
+		if runs > 3 {
+			return FromCmd(ctx, exec.Command("sleep", "99999"), nil)
+		}
+		return FromCmd(ctx, exec.Command("false"), nil)
+	}, WithPoisonDetection(3, time.Minute), OnStateChange(func(state SupervisorState) {
+		states = append(states, state)
+		if state == SupervisorPoisoned {
+			poisoned <- struct{}{}
+		}
+	}))
+
+	<-poisoned
+	assert.Equal(t, s.State(), SupervisorPoisoned)
+	assert.Equal(t, s.Restarts(), 2)
+	assert.ErrorContains(t, s.LastError(), "exit status 1")
+	select {
+	case <-s.Done():
+		t.Fatal("poisoned supervisor stopped")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.Reset()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, s.State(), SupervisorRunning)
+	assert.Equal(t, s.Restarts(), 3)
+
+	cancel()
+	<-s.Done()
+	assert.DeepEqual(t, states, []SupervisorState{
+		SupervisorBackoff, SupervisorRunning,
+		SupervisorBackoff, SupervisorRunning,
+		SupervisorPoisoned, SupervisorRunning,
+		SupervisorStopped,
+	})
+}
@@ -0,0 +1,74 @@
+package execctx
+
+import "io"
+
+// TeeStdout copies stdout of the command to the writers as well, in
+// addition to the stdout writer of the command or the capture of `Output`
+// and `RunResult`. This works whether or not stdout is set on the command.
+//
+// The writers get the output after any stdout filters are applied. A writer
+// passed to both TeeStdout and `TeeStderr`, e.g. a log file, is never
+// written to concurrently. Like for the stdout writer, a failing write stops
+// the copy of the output.
+// This may be passed multiple times.
+func TeeStdout(w ...io.Writer) Option {
+	return func(c *Cmd) {
+		c.stdoutTees = append(c.stdoutTees, w...)
+	}
+}
+
+// TeeStderr is like `TeeStdout` but for stderr.
+func TeeStderr(w ...io.Writer) Option {
+	return func(c *Cmd) {
+		c.stderrTees = append(c.stderrTees, w...)
+	}
+}
+
+// teeWriters returns the writers combining the tees of stdout and stderr,
+// nil if there are none.
+func (c *Cmd) teeWriters() (stdout, stderr io.Writer) {
+	stdoutTees, stderrTees := c.stdoutTees, c.stderrTees
+	if c.outputTail > 0 {
+		c.stdoutRecent = &outputTail{t: tailCapture{n: c.outputTail}}
+		c.stderrRecent = &outputTail{t: tailCapture{n: c.outputTail}}
+		stdoutTees = append(stdoutTees[:len(stdoutTees):len(stdoutTees)], c.stdoutRecent)
+		stderrTees = append(stderrTees[:len(stderrTees):len(stderrTees)], c.stderrRecent)
+	}
+	if len(stdoutTees) == 0 && len(stderrTees) == 0 {
+		return nil, nil
+	}
+
+	// Writers shared by both streams are locked so they are not written
+	// from the goroutines copying stdout and stderr at the same time.
+	var locked []*lockedWriter
+	lock := func(w io.Writer) io.Writer {
+		for _, l := range locked {
+			if interfaceEqual(l.w, w) {
+				return l
+			}
+		}
+		l := &lockedWriter{w: w}
+		locked = append(locked, l)
+		return l
+	}
+	shared := func(w io.Writer, others []io.Writer) bool {
+		for _, o := range others {
+			if interfaceEqual(w, o) {
+				return true
+			}
+		}
+		return false
+	}
+
+	combine := func(tees, others []io.Writer) io.Writer {
+		var out io.Writer
+		for _, w := range tees {
+			if shared(w, others) {
+				w = lock(w)
+			}
+			out = multiWriter(out, w)
+		}
+		return out
+	}
+	return combine(stdoutTees, stderrTees), combine(stderrTees, stdoutTees)
+}
@@ -0,0 +1,33 @@
+package execctx
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestTee(t *testing.T) {
+	ctx := context.Background()
+
+	var log, teeOut bytes.Buffer
+	cmd := exec.Command("sh", "-c", "for i in 1 2 3; do echo out$i; echo err$i >&2; done")
+	c := FromCmd(ctx, cmd, nil, TeeStdout(&log, &teeOut), TeeStderr(&log))
+	res, err := c.RunResult()
+	assert.NilError(t, err)
+
+	assert.Equal(t, string(res.Stdout), "out1\nout2\nout3\n")
+	assert.Equal(t, string(res.Stderr), "err1\nerr2\nerr3\n")
+	assert.Equal(t, teeOut.String(), "out1\nout2\nout3\n")
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	assert.Equal(t, len(lines), 6, log.String())
+
+	// Tees work without stdout being set.
+	teeOut.Reset()
+	c = FromCmd(ctx, exec.Command("echo", "hello"), nil, TeeStdout(&teeOut))
+	assert.NilError(t, c.Run())
+	assert.Equal(t, teeOut.String(), "hello\n")
+}
@@ -0,0 +1,52 @@
+package execctx
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// WithTempDir runs the command in a new scratch directory, created with
+// pattern as by ioutil.TempDir, which is removed once the command exits.
+// The directory is created when the command is started, before the
+// `OnPreStart` hooks run, and replaces any working directory set on the
+// command. Its path is available from `TempDir`.
+func WithTempDir(pattern string) Option {
+	return func(c *Cmd) {
+		c.tempDirPattern = &pattern
+	}
+}
+
+// WithKeepTempDirOnFailure keeps the directory created by `WithTempDir` when
+// the process exits unsuccessfully, so what it left behind can be
+// inspected.
+func WithKeepTempDirOnFailure() Option {
+	return func(c *Cmd) {
+		c.keepTempDir = true
+	}
+}
+
+// TempDir returns the scratch directory created by `WithTempDir`, or "" if
+// there is none. It is only set once the command is started.
+func (c *Cmd) TempDir() string {
+	return c.tempDir
+}
+
+func (c *Cmd) setupTempDir() error {
+	if c.tempDirPattern == nil {
+		return nil
+	}
+	dir, err := ioutil.TempDir("", *c.tempDirPattern)
+	if err != nil {
+		return fmt.Errorf("execctx: error creating temp dir: %w", err)
+	}
+	c.tempDir = dir
+	c.cmd.Dir = dir
+	c.onRelease(func() {
+		if c.keepTempDir && c.cmd.ProcessState != nil && !c.cmd.ProcessState.Success() {
+			return
+		}
+		os.RemoveAll(dir)
+	})
+	return nil
+}
@@ -0,0 +1,38 @@
+package execctx
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestWithTempDir(t *testing.T) {
+	var out strings.Builder
+	c := FromCmd(context.Background(), exec.Command("sh", "-c", "pwd; touch file"), nil, WithStdout(&out), WithTempDir("scratch-"))
+	assert.NilError(t, c.Run())
+
+	dir := c.TempDir()
+	assert.Assert(t, strings.HasPrefix(filepath.Base(dir), "scratch-"), dir)
+	real, err := filepath.EvalSymlinks(filepath.Dir(dir))
+	assert.NilError(t, err)
+	assert.Equal(t, out.String(), filepath.Join(real, filepath.Base(dir))+"\n")
+	_, err = os.Stat(dir)
+	assert.Assert(t, os.IsNotExist(err), err)
+
+	// Kept on failure only.
+	c = FromCmd(context.Background(), exec.Command("sh", "-c", "touch file; exit 1"), nil, WithTempDir(""), WithKeepTempDirOnFailure())
+	assert.ErrorContains(t, c.Run(), "exit status 1")
+	defer os.RemoveAll(c.TempDir())
+	_, err = os.Stat(filepath.Join(c.TempDir(), "file"))
+	assert.NilError(t, err)
+
+	c = FromCmd(context.Background(), exec.Command("true"), nil, WithTempDir(""), WithKeepTempDirOnFailure())
+	assert.NilError(t, c.Run())
+	_, err = os.Stat(c.TempDir())
+	assert.Assert(t, os.IsNotExist(err), err)
+}
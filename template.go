@@ -0,0 +1,171 @@
+package execctx
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LabelTemplate is the label set on commands created from a template, with
+// the template reference, e.g. "git-clone@v2", as the value.
+const LabelTemplate = "execctx.template"
+
+// Template is a named, versioned spec with parameters.
+// Occurrences of "{{param}}" in the args, env, and dir of the spec are
+// replaced with the value of the parameter, like with `Matrix`.
+type Template struct {
+	Name    string
+	Version string
+	Spec    Spec
+	Params  []Param
+}
+
+// Ref returns the reference of the template, as "name@version".
+func (t *Template) Ref() string {
+	return t.Name + "@" + t.Version
+}
+
+// Param declares a parameter of a `Template`.
+type Param struct {
+	Name string
+	// Default is used if no value is passed for the parameter.
+	Default string
+	// Required parameters must be passed, the default is ignored.
+	Required bool
+	// Pattern, if set, must match the whole value.
+	Pattern *regexp.Regexp
+}
+
+// Registry holds command templates, so a code base runs its commands by name
+// rather than with exec.Command calls scattered around.
+type Registry struct {
+	mu        sync.Mutex
+	templates map[string]Template
+	// latest maps a template name to the reference of its most recently
+	// registered version.
+	latest map[string]string
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		templates: make(map[string]Template),
+		latest:    make(map[string]string),
+	}
+}
+
+// Register adds a template to the registry.
+// A template with the same name and version must not be registered yet, and
+// all placeholders in the spec must refer to declared parameters.
+func (r *Registry) Register(t Template) error {
+	if t.Name == "" || t.Version == "" {
+		return fmt.Errorf("template %q: name and version are required", t.Ref())
+	}
+	if strings.Contains(t.Name, "@") {
+		return fmt.Errorf("template %q: name must not contain \"@\"", t.Ref())
+	}
+
+	// Expanding with placeholder values catches undeclared parameters early.
+	kvs := make([]KV, 0, len(t.Params))
+	for _, p := range t.Params {
+		kvs = append(kvs, KV{Key: p.Name, Value: p.Name})
+	}
+	if _, err := expandSpec(t.Spec, kvs); err != nil {
+		return fmt.Errorf("template %q: %w", t.Ref(), err)
+	}
+
+	t.Params = append([]Param(nil), t.Params...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.templates[t.Ref()]; ok {
+		return fmt.Errorf("template %q: already registered", t.Ref())
+	}
+	r.templates[t.Ref()] = t
+	r.latest[t.Name] = t.Ref()
+	return nil
+}
+
+// Templates returns the references of all registered templates, sorted.
+func (r *Registry) Templates() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	refs := make([]string, 0, len(r.templates))
+	for ref := range r.templates {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// Command creates a command from the template referenced by ref, either
+// "name@version" or just "name" for the most recently registered version.
+//
+// Parameters are validated and defaulted, passing an undeclared parameter is
+// an error. The command is labeled with `LabelTemplate`, parameter values
+// are not recorded since they may be sensitive.
+// The cancel function and options are passed through to `FromCmd`.
+func (r *Registry) Command(ctx context.Context, ref string, params map[string]string, cancel func(), opts ...Option) (*Cmd, error) {
+	r.mu.Lock()
+	if !strings.Contains(ref, "@") {
+		if latest, ok := r.latest[ref]; ok {
+			ref = latest
+		}
+	}
+	t, ok := r.templates[ref]
+	r.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("template %q: not registered", ref)
+	}
+
+	kvs, err := t.resolveParams(params)
+	if err != nil {
+		return nil, fmt.Errorf("template %q: %w", ref, err)
+	}
+	spec, err := expandSpec(t.Spec, kvs)
+	if err != nil {
+		return nil, fmt.Errorf("template %q: %w", ref, err)
+	}
+	spec.setDefaults()
+	if err := spec.Validate(); err != nil {
+		return nil, fmt.Errorf("template %q: %w", ref, err)
+	}
+
+	opts = append([]Option{WithLabels(map[string]string{LabelTemplate: ref})}, opts...)
+	return spec.command(ctx, cancel, opts...), nil
+}
+
+func (t *Template) resolveParams(params map[string]string) ([]KV, error) {
+	declared := make(map[string]bool, len(t.Params))
+	kvs := make([]KV, 0, len(t.Params))
+	for _, p := range t.Params {
+		declared[p.Name] = true
+
+		v, ok := params[p.Name]
+		if !ok {
+			if p.Required {
+				return nil, fmt.Errorf("missing required parameter %q", p.Name)
+			}
+			v = p.Default
+		}
+		if p.Pattern != nil && !fullMatch(p.Pattern, v) {
+			return nil, fmt.Errorf("invalid value %q for parameter %q: must match %s", v, p.Name, p.Pattern)
+		}
+		kvs = append(kvs, KV{Key: p.Name, Value: v})
+	}
+
+	for k := range params {
+		if !declared[k] {
+			return nil, fmt.Errorf("unknown parameter %q", k)
+		}
+	}
+	return kvs, nil
+}
+
+func fullMatch(re *regexp.Regexp, s string) bool {
+	loc := re.FindStringIndex(s)
+	return loc != nil && loc[0] == 0 && loc[1] == len(s)
+}
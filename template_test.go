@@ -0,0 +1,52 @@
+package execctx
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	assert.NilError(t, r.Register(Template{
+		Name:    "greet",
+		Version: "v1",
+		Spec:    Spec{Args: []string{"echo", "hello"}},
+	}))
+	assert.NilError(t, r.Register(Template{
+		Name:    "greet",
+		Version: "v2",
+		Spec:    Spec{Args: []string{"echo", "{{greeting}}", "{{name}}"}},
+		Params: []Param{
+			{Name: "greeting", Default: "hello"},
+			{Name: "name", Required: true, Pattern: regexp.MustCompile(`[a-z]+`)},
+		},
+	}))
+	assert.ErrorContains(t, r.Register(Template{Name: "greet", Version: "v2", Spec: Spec{Args: []string{"true"}}}), "already registered")
+	assert.ErrorContains(t, r.Register(Template{Name: "bad", Version: "v1", Spec: Spec{Args: []string{"{{nope}}"}}}), "unknown parameter")
+	assert.DeepEqual(t, r.Templates(), []string{"greet@v1", "greet@v2"})
+
+	c, err := r.Command(context.Background(), "greet", map[string]string{"name": "world"}, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, c.Labels()[LabelTemplate], "greet@v2")
+	out, err := c.Output(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "hello world\n")
+
+	c, err = r.Command(context.Background(), "greet@v1", nil, nil)
+	assert.NilError(t, err)
+	out, err = c.Output(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "hello\n")
+
+	_, err = r.Command(context.Background(), "greet", nil, nil)
+	assert.ErrorContains(t, err, `missing required parameter "name"`)
+	_, err = r.Command(context.Background(), "greet", map[string]string{"name": "World"}, nil)
+	assert.ErrorContains(t, err, "invalid value")
+	_, err = r.Command(context.Background(), "greet", map[string]string{"name": "world", "x": "y"}, nil)
+	assert.ErrorContains(t, err, `unknown parameter "x"`)
+	_, err = r.Command(context.Background(), "greet@v3", nil, nil)
+	assert.ErrorContains(t, err, "not registered")
+}
@@ -0,0 +1,92 @@
+package execctx
+
+import (
+	"os"
+	"time"
+)
+
+// EscalationStep is a step of an `EscalationPolicy`.
+type EscalationStep struct {
+	// Signal is sent to the process.
+	Signal os.Signal
+	// Wait is how long to wait for the process to exit before moving on to
+	// the next step.
+	Wait time.Duration
+}
+
+// EscalationPolicy is a termination ladder walked when the command is
+// cancelled, e.g. SIGINT, wait 5s, SIGTERM, wait 5s. If the process is still
+// running after the last step it is killed.
+type EscalationPolicy []EscalationStep
+
+// WithEscalation sets the cancel handler to walk the escalation policy.
+// This takes precedence over the cancel function passed to `FromCmd`.
+// Use `EscalationStep` to find out which step terminated the process.
+//
+// The process counts as running until `Wait` returns. Steps whose signal
+// cannot be delivered, such as most signals on Windows, are skipped.
+func WithEscalation(p EscalationPolicy) Option {
+	return func(c *Cmd) {
+		c.escalation = append(EscalationPolicy(nil), p...)
+		c.adaptiveEscalation = nil
+	}
+}
+
+// GracefulKill sets the cancel handler to send sig to the process, and kill
+// it if it is still running after the grace period, so the common case
+// doesn't need a custom handler.
+// It is a shorthand for a `WithEscalation` with a single step.
+func GracefulKill(sig os.Signal, grace time.Duration) Option {
+	return WithEscalation(EscalationPolicy{{Signal: sig, Wait: grace}})
+}
+
+// EscalationStep returns the index of the step of the escalation policy
+// which was last taken before the process exited. It is the length of the
+// policy if the process had to be killed after the last step, and -1 if the
+// policy was not used.
+func (c *Cmd) EscalationStep() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.escalated {
+		return -1
+	}
+	return c.escalationStep
+}
+
+func (c *Cmd) setEscalationStep(i int) {
+	c.mu.Lock()
+	c.escalated = true
+	c.escalationStep = i
+	c.mu.Unlock()
+}
+
+func (c *Cmd) escalate() {
+	for i, step := range c.escalation {
+		c.notifySignal(step.Signal)
+		if err := c.Signal(step.Signal); err != nil {
+			continue
+		}
+		c.setEscalationStep(i)
+
+		wait := step.Wait
+		if a := c.adaptiveEscalation; a != nil {
+			wait = a.wait(i, wait)
+		}
+		sent := time.Now()
+		timer := time.NewTimer(wait)
+		select {
+		case <-c.waitDone:
+			timer.Stop()
+			if a := c.adaptiveEscalation; a != nil {
+				a.Stats.Record(a.Key, i, ShutdownSample{Elapsed: time.Since(sent), Exited: true})
+			}
+			return
+		case <-timer.C:
+			if a := c.adaptiveEscalation; a != nil {
+				a.Stats.Record(a.Key, i, ShutdownSample{Elapsed: wait})
+			}
+		}
+	}
+	c.setEscalationStep(len(c.escalation))
+	c.kill()
+}
@@ -0,0 +1,67 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestGracefulKill(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals are not supported on windows")
+	}
+
+	// Exits on SIGTERM.
+	c := FromCmd(context.Background(), exec.Command("sleep", "99999"), nil, GracefulKill(syscall.SIGTERM, time.Minute))
+	assert.NilError(t, c.Start())
+	c.Cancel(nil)
+	err := c.Wait()
+	assert.Assert(t, errors.Is(err, context.Canceled), err)
+	sig, _ := exitSignal(c)
+	assert.Equal(t, sig, syscall.SIGTERM)
+
+	// Ignores SIGTERM so it is killed after the grace period.
+	script := `trap '' TERM; echo ready; while :; do sleep 0.01; done`
+	cmd := exec.Command("sh", "-c", script)
+	stdout, err := cmd.StdoutPipe()
+	assert.NilError(t, err)
+	c = FromCmd(context.Background(), cmd, nil, GracefulKill(syscall.SIGTERM, 50*time.Millisecond))
+	assert.NilError(t, c.Start())
+	_, err = stdout.Read(make([]byte, 6))
+	assert.NilError(t, err)
+	c.Cancel(nil)
+	c.Wait()
+	sig, _ = exitSignal(c)
+	assert.Equal(t, sig, syscall.SIGKILL)
+	assert.Equal(t, c.EscalationStep(), 1)
+}
+
+func TestEscalation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals are not supported on windows")
+	}
+
+	// Ignores SIGINT, exits on SIGTERM.
+	script := `trap '' INT; trap 'exit 7' TERM; echo ready; while :; do sleep 0.01; done`
+	cmd := exec.Command("sh", "-c", script)
+	stdout, err := cmd.StdoutPipe()
+	assert.NilError(t, err)
+	c := FromCmd(context.Background(), cmd, nil, WithEscalation(EscalationPolicy{
+		{Signal: syscall.SIGINT, Wait: 50 * time.Millisecond},
+		{Signal: syscall.SIGTERM, Wait: time.Minute},
+	}))
+	assert.Equal(t, c.EscalationStep(), -1)
+	assert.NilError(t, c.Start())
+	_, err = stdout.Read(make([]byte, 6))
+	assert.NilError(t, err)
+	c.Cancel(nil)
+	c.Wait()
+	assert.Equal(t, c.ExitCode(), 7)
+	assert.Equal(t, c.EscalationStep(), 1)
+}
@@ -0,0 +1,84 @@
+package execctx
+
+import (
+	"errors"
+	"time"
+)
+
+// throttlePeriod is the length of one stop/continue cycle of the CPU throttle.
+const throttlePeriod = 100 * time.Millisecond
+
+// WithCPUThrottle limits the process to roughly the given fraction of wall
+// clock time, e.g. 0.3 for 30%, by alternately stopping and continuing it
+// with SIGSTOP and SIGCONT. This works without cgroups, at the cost of
+// precision: the process is paused as a whole rather than limited to a
+// share of a CPU, and only the process itself is throttled, not its
+// descendants.
+//
+// The process is continued when the command is cancelled so it can react to
+// the cancel handler. A limit >= 1 disables the throttle.
+// It is not supported on Windows.
+func WithCPUThrottle(limit float64) Option {
+	return func(c *Cmd) {
+		c.cpuThrottle = limit
+	}
+}
+
+func (c *Cmd) setupThrottle() error {
+	if c.cpuThrottle == 0 || c.cpuThrottle >= 1 {
+		return nil
+	}
+	if c.cpuThrottle < 0 {
+		return errors.New("execctx: cpu throttle must be positive")
+	}
+	if !throttleSupported {
+		return errors.New("execctx: cpu throttling is not supported on this platform")
+	}
+	c.onProcessStart(func() {
+		go c.throttle()
+	})
+	return nil
+}
+
+func (c *Cmd) throttle() {
+	run := time.Duration(float64(throttlePeriod) * c.cpuThrottle)
+	stop := throttlePeriod - run
+	timer := time.NewTimer(run)
+	defer timer.Stop()
+
+	stopped := false
+	for {
+		select {
+		case <-timer.C:
+		case <-c.cancelled:
+			if stopped {
+				c.setThrottled(false)
+			}
+			return
+		case <-c.waitDone:
+			return
+		}
+
+		stopped = !stopped
+		c.setThrottled(stopped)
+		if stopped {
+			timer.Reset(stop)
+		} else {
+			timer.Reset(run)
+		}
+	}
+}
+
+// setThrottled stops or continues the process for the throttle.
+// The state is tracked so stops caused by the throttle are not mistaken for
+// the process reading from the terminal, see `WithNoStdin`.
+func (c *Cmd) setThrottled(stopped bool) {
+	c.throttleMu.Lock()
+	defer c.throttleMu.Unlock()
+	if stopped {
+		stopProcess(c.cmd.Process)
+	} else {
+		continueProcess(c.cmd.Process)
+	}
+	c.throttleStopped = stopped
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly && !solaris && !aix
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly,!solaris,!aix
+
+package execctx
+
+import (
+	"os"
+)
+
+const throttleSupported = false
+
+func stopProcess(p *os.Process) {}
+
+func continueProcess(p *os.Process) {}
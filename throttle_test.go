@@ -0,0 +1,33 @@
+package execctx
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestCPUThrottle(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cpu throttling is not supported on windows")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	c := FromCmd(ctx, exec.Command("sh", "-c", "while :; do :; done"), nil, WithCPUThrottle(0.2))
+	err := c.Run()
+	assert.Assert(t, err != nil)
+
+	ps := c.cmd.ProcessState
+	used := ps.UserTime() + ps.SystemTime()
+	assert.Assert(t, used < 600*time.Millisecond, used)
+}
+
+func TestCPUThrottleInvalid(t *testing.T) {
+	c := FromCmd(context.Background(), exec.Command("true"), nil, WithCPUThrottle(-1))
+	assert.ErrorContains(t, c.Start(), "must be positive")
+}
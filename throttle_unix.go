@@ -0,0 +1,19 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly || solaris || aix
+// +build linux darwin freebsd netbsd openbsd dragonfly solaris aix
+
+package execctx
+
+import (
+	"os"
+	"syscall"
+)
+
+const throttleSupported = true
+
+func stopProcess(p *os.Process) {
+	p.Signal(syscall.SIGSTOP)
+}
+
+func continueProcess(p *os.Process) {
+	p.Signal(syscall.SIGCONT)
+}
@@ -0,0 +1,35 @@
+package execctx
+
+import (
+	"context"
+	"time"
+)
+
+// WithTimeout cancels the command with `context.DeadlineExceeded` as the
+// cause if it is still running d after it was started. Unlike a context
+// deadline, the time it spends waiting to start, for instance in a `Pool`,
+// doesn't count.
+//
+// The command is considered running until `Wait` returns.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Cmd) {
+		c.timeout = d
+	}
+}
+
+func (c *Cmd) watchTimeout() {
+	if c.timeout <= 0 {
+		return
+	}
+
+	go func() {
+		timer := time.NewTimer(c.timeout)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			c.Cancel(context.DeadlineExceeded)
+		case <-c.waitDone:
+		}
+	}()
+}
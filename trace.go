@@ -0,0 +1,228 @@
+package execctx
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// FileAccess is a file opened by a traced command.
+type FileAccess struct {
+	// Path is the path as passed by the command, relative paths are relative
+	// to the working directory of the process which opened the file.
+	Path string `json:"path"`
+	// Write is set if the file was opened for writing or created.
+	Write bool `json:"write,omitempty"`
+}
+
+// Tracer records the files a command opens, see `WithTracer`.
+type Tracer interface {
+	// Trace is called right before the command is started and rewrites cmd
+	// so it runs under the tracer.
+	// The returned function is called once the command has exited, or failed
+	// to start, and returns the files which were opened.
+	Trace(cmd *exec.Cmd) (func() ([]FileAccess, error), error)
+}
+
+// WithTracer records the files opened by the command and its children using
+// t. The result is available from `FileAccesses` once `Wait` returns.
+//
+// Tracing slows the command down considerably, it is meant for deriving
+// cache keys or sandbox policies and for debugging.
+func WithTracer(t Tracer) Option {
+	return func(c *Cmd) {
+		c.tracer = t
+	}
+}
+
+// FileAccesses returns the files opened by the command, in the order they
+// were first opened, as recorded by the tracer set with `WithTracer`.
+// Files which failed to open are not included.
+func (c *Cmd) FileAccesses() ([]FileAccess, error) {
+	return append([]FileAccess(nil), c.fileAccesses...), c.traceErr
+}
+
+func (c *Cmd) setupTracer() error {
+	if c.tracer == nil {
+		return nil
+	}
+	finish, err := c.tracer.Trace(c.cmd)
+	if err != nil {
+		return fmt.Errorf("execctx: error setting up tracer: %w", err)
+	}
+	c.onRelease(func() {
+		c.fileAccesses, c.traceErr = finish()
+	})
+	_, c.straced = c.tracer.(straceTracer)
+	return nil
+}
+
+// signalTracee sends sig to the command traced by strace, the child of the
+// process. It reports false if the child can't be found.
+func (c *Cmd) signalTracee(sig os.Signal) (bool, error) {
+	children, err := processChildren(c.cmd.Process.Pid)
+	if err != nil || len(children) == 0 {
+		return false, nil
+	}
+	for _, pid := range children {
+		signalPid(pid, sig)
+	}
+	return true, nil
+}
+
+// Strace returns a `Tracer` which runs the command under strace. It is only
+// available on Linux, and strace 6.0 or newer must be installed.
+//
+// strace is run with --kill-on-exit, so when the command is killed the
+// processes it traces are killed along with it. strace blocks other signals
+// such as SIGTERM, so these are forwarded to the traced command instead,
+// letting it shut down gracefully on the steps of `WithEscalation` and on
+// `Signal`.
+func Strace() Tracer {
+	return straceTracer{}
+}
+
+type straceTracer struct{}
+
+func (straceTracer) Trace(cmd *exec.Cmd) (func() ([]FileAccess, error), error) {
+	strace, err := exec.LookPath("strace")
+	if err != nil {
+		return nil, err
+	}
+	f, err := ioutil.TempFile("", "execctx-strace")
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	args := []string{"strace", "-f", "--kill-on-exit", "-qq", "-s", "4096", "-e", "trace=open,openat,creat", "-o", f.Name(), "--", cmd.Path}
+	cmd.Args = append(args, cmd.Args[1:]...)
+	cmd.Path = strace
+
+	return func() ([]FileAccess, error) {
+		defer os.Remove(f.Name())
+		data, err := ioutil.ReadFile(f.Name())
+		if err != nil {
+			return nil, err
+		}
+		return parseStrace(string(data)), nil
+	}, nil
+}
+
+type straceCall struct {
+	name string
+	args string
+}
+
+// parseStrace extracts the successfully opened files from the output of
+// `strace -f -e trace=open,openat,creat`.
+func parseStrace(out string) []FileAccess {
+	var (
+		files      []FileAccess
+		index      = make(map[string]int)
+		unfinished = make(map[string]straceCall)
+	)
+	add := func(call straceCall, result string) {
+		if strings.HasPrefix(strings.TrimSpace(result), "-") {
+			return
+		}
+		path, flags, ok := parseStraceArgs(call)
+		if !ok {
+			return
+		}
+		write := call.name == "creat" || strings.Contains(flags, "O_WRONLY") || strings.Contains(flags, "O_RDWR") || strings.Contains(flags, "O_CREAT")
+		if i, ok := index[path]; ok {
+			files[i].Write = files[i].Write || write
+			return
+		}
+		index[path] = len(files)
+		files = append(files, FileAccess{Path: path, Write: write})
+	}
+
+	s := bufio.NewScanner(strings.NewReader(out))
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		pid, line := splitStracePid(s.Text())
+		if strings.HasPrefix(line, "<... ") {
+			// "<... openat resumed>, O_RDONLY) = 3"
+			call, ok := unfinished[pid]
+			if !ok {
+				continue
+			}
+			delete(unfinished, pid)
+			i := strings.Index(line, "resumed>")
+			if i < 0 {
+				continue
+			}
+			rest := line[i+len("resumed>"):]
+			eq := strings.LastIndex(rest, ") = ")
+			if eq < 0 {
+				continue
+			}
+			call.args += rest[:eq]
+			add(call, rest[eq+len(") = "):])
+			continue
+		}
+
+		paren := strings.IndexByte(line, '(')
+		if paren < 0 {
+			continue
+		}
+		call := straceCall{name: line[:paren]}
+		rest := line[paren+1:]
+		if i := strings.Index(rest, " <unfinished ...>"); i >= 0 {
+			call.args = rest[:i]
+			unfinished[pid] = call
+			continue
+		}
+		eq := strings.LastIndex(rest, ") = ")
+		if eq < 0 {
+			continue
+		}
+		call.args = rest[:eq]
+		add(call, rest[eq+len(") = "):])
+	}
+	return files
+}
+
+// splitStracePid splits the pid prefix added by `strace -f` from a line.
+func splitStracePid(line string) (string, string) {
+	i := strings.IndexByte(line, ' ')
+	if i < 0 {
+		return "", line
+	}
+	if _, err := strconv.Atoi(line[:i]); err != nil {
+		return "", line
+	}
+	return line[:i], strings.TrimLeft(line[i+1:], " ")
+}
+
+// parseStraceArgs returns the path and flags arguments of an open call.
+func parseStraceArgs(call straceCall) (path, flags string, ok bool) {
+	start := strings.IndexByte(call.args, '"')
+	if start < 0 {
+		return "", "", false
+	}
+	end := start + 1
+	for ; end < len(call.args); end++ {
+		if call.args[end] == '\\' {
+			end++
+			continue
+		}
+		if call.args[end] == '"' {
+			break
+		}
+	}
+	if end >= len(call.args) {
+		return "", "", false
+	}
+	path, err := strconv.Unquote(call.args[start : end+1])
+	if err != nil {
+		return "", "", false
+	}
+	return path, call.args[end+1:], true
+}
@@ -0,0 +1,62 @@
+package execctx
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestStraceCancel(t *testing.T) {
+	// Like strace, the fake runs the command as its child, and only kills
+	// it when exiting with --kill-on-exit.
+	defer fakeTool(t, "strace", `while [ "$1" != "--" ]; do
+	if [ "$1" = "--kill-on-exit" ]; then killonexit=1; fi
+	shift
+done
+shift
+"$@" &
+pid=$!
+if [ -n "$killonexit" ]; then
+	(while kill -0 $$ 2>/dev/null; do sleep 0.01; done; kill -9 $pid) &
+fi
+wait $pid`)()
+
+	dir, err := ioutil.TempDir("", "execctx-strace")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	pidFile := filepath.Join(dir, "pid")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := FromCmd(ctx, exec.Command("sh", "-c", `echo $$ > "$0"; exec sleep 99999`, pidFile), nil, WithTracer(Strace()))
+	assert.NilError(t, c.Start())
+
+	var pid int
+	for i := 0; i < 100 && pid == 0; i++ {
+		data, _ := ioutil.ReadFile(pidFile)
+		pid, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Assert(t, pid != 0)
+
+	cancel()
+	c.Wait()
+
+	gone := false
+	for i := 0; i < 100 && !gone; i++ {
+		st, err := readProcStat("/proc/" + strconv.Itoa(pid) + "/stat")
+		gone = err != nil || st.state == 'Z'
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !gone {
+		signalPid(pid, os.Kill)
+	}
+	assert.Assert(t, gone, "traced process %d still running", pid)
+}
@@ -0,0 +1,75 @@
+package execctx
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+const straceOutput = `100 openat(AT_FDCWD, "/etc/ld.so.cache", O_RDONLY|O_CLOEXEC) = 3
+100 openat(AT_FDCWD, "/nonexistent", O_RDONLY) = -1 ENOENT (No such file or directory)
+101 openat(AT_FDCWD, "out \"1\".txt", O_WRONLY|O_CREAT|O_TRUNC, 0666 <unfinished ...>
+100 open("/etc/passwd", O_RDONLY) = 4
+101 <... openat resumed>) = 5
+100 creat("/tmp/new", 0644) = 6
+100 openat(AT_FDCWD, "/etc/passwd", O_RDWR) = 7
+100 +++ exited with 0 +++
+`
+
+func TestParseStrace(t *testing.T) {
+	assert.DeepEqual(t, parseStrace(straceOutput), []FileAccess{
+		{Path: "/etc/ld.so.cache"},
+		{Path: "/etc/passwd", Write: true},
+		{Path: `out "1".txt`, Write: true},
+		{Path: "/tmp/new", Write: true},
+	})
+}
+
+func TestStrace(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a posix shell")
+	}
+
+	// The fake strace writes canned output to the -o file and runs the
+	// command.
+	defer fakeTool(t, "strace", `while [ "$1" != "--" ]; do
+	if [ "$1" = "-o" ]; then out="$2"; fi
+	shift
+done
+shift
+echo '100 open("/etc/passwd", O_RDONLY) = 3' > "$out"
+exec "$@"`)()
+
+	c := FromCmd(context.Background(), exec.Command("true"), nil, WithTracer(Strace()))
+	assert.NilError(t, c.Run())
+	files, err := c.FileAccesses()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, files, []FileAccess{{Path: "/etc/passwd"}})
+}
+
+func TestStraceSignal(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("signals are only forwarded on linux")
+	}
+
+	// Like strace, the fake one doesn't die from SIGTERM and exits with the
+	// status of the command.
+	defer fakeTool(t, "strace", `trap : TERM
+while [ "$1" != "--" ]; do shift; done
+shift
+"$@"`)()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	cmd := exec.Command("sh", "-c", `trap "exit 7" TERM; sleep 10 & wait`)
+	c := FromCmd(ctx, cmd, nil, WithTracer(Strace()), GracefulKill(syscall.SIGTERM, 10*time.Second))
+	start := time.Now()
+	err := c.Run()
+	assert.Assert(t, time.Since(start) < 5*time.Second)
+	assert.Equal(t, c.ExitCode(), 7, err)
+}
@@ -0,0 +1,170 @@
+package execctx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// WithUser runs the command as another user. The user is given as "user" or
+// "user:group", where both can be a name or a numeric id, as in "nobody",
+// "1000:1000", or "www-data:www-data".
+// If no group is given the primary group of the user is used.
+//
+// Besides the credentials, HOME, USER, and LOGNAME are set for the user in
+// the environment of the process. Supplementary groups are cleared, unless
+// `WithInitGroups` is set. The directories of `WithTempDir`,
+// `WithHealthFile`, and the private TMPDIR of `Hermetic` are owned by the
+// user.
+//
+// The user is resolved when the command is started. A numeric user which
+// doesn't exist is only accepted along with a group.
+// Switching users requires privileges and is not supported on Windows.
+func WithUser(spec string) Option {
+	return func(c *Cmd) {
+		c.user = spec
+	}
+}
+
+// WithInitGroups sets the supplementary groups of the process to the groups
+// of the user set with `WithUser`, like initgroups(3).
+func WithInitGroups() Option {
+	return func(c *Cmd) {
+		c.initGroups = true
+	}
+}
+
+// credential is a resolved `WithUser` spec.
+type credential struct {
+	uid, gid uint32
+	groups   []uint32
+	user     *user.User
+}
+
+var errUnknownUserGroup = errors.New("unknown user requires a group")
+
+func resolveUser(spec string, initGroups bool) (credential, error) {
+	name, group := spec, ""
+	if i := strings.IndexByte(spec, ':'); i >= 0 {
+		name, group = spec[:i], spec[i+1:]
+	}
+
+	var cred credential
+	u, err := lookupUser(name)
+	switch {
+	case err == nil:
+		cred.user = u
+		if cred.uid, err = parseID(u.Uid); err != nil {
+			return credential{}, err
+		}
+		if cred.gid, err = parseID(u.Gid); err != nil {
+			return credential{}, err
+		}
+	case isNumeric(name) && group != "":
+		cred.uid, _ = parseID(name)
+	case isNumeric(name):
+		return credential{}, errUnknownUserGroup
+	default:
+		return credential{}, err
+	}
+
+	if group != "" {
+		g, err := user.LookupGroup(group)
+		if err != nil && isNumeric(group) {
+			g, err = user.LookupGroupId(group)
+			if err != nil {
+				g, err = &user.Group{Gid: group}, nil
+			}
+		}
+		if err != nil {
+			return credential{}, err
+		}
+		if cred.gid, err = parseID(g.Gid); err != nil {
+			return credential{}, err
+		}
+	}
+
+	if initGroups && cred.user != nil {
+		ids, err := cred.user.GroupIds()
+		if err != nil {
+			return credential{}, err
+		}
+		for _, id := range ids {
+			gid, err := parseID(id)
+			if err != nil {
+				return credential{}, err
+			}
+			cred.groups = append(cred.groups, gid)
+		}
+	}
+	return cred, nil
+}
+
+// lookupUser looks up a user by name, or by id for numeric names.
+func lookupUser(name string) (*user.User, error) {
+	u, err := user.Lookup(name)
+	if err != nil && isNumeric(name) {
+		return user.LookupId(name)
+	}
+	return u, err
+}
+
+func isNumeric(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 32)
+	return err == nil
+}
+
+func parseID(s string) (uint32, error) {
+	id, err := strconv.ParseUint(s, 10, 32)
+	return uint32(id), err
+}
+
+// userEnv returns the environment variables identifying the user set with
+// `WithUser`.
+func (c *Cmd) userEnv() []string {
+	u := c.userCred.user
+	if u == nil {
+		return nil
+	}
+	return []string{"HOME=" + u.HomeDir, "USER=" + u.Username, "LOGNAME=" + u.Username}
+}
+
+func (c *Cmd) setupUser() error {
+	if c.user == "" {
+		return nil
+	}
+	cred, err := resolveUser(c.user, c.initGroups)
+	if err != nil {
+		return fmt.Errorf("execctx: error resolving user %q: %w", c.user, err)
+	}
+	c.userCred = cred
+	if err := c.setCredential(cred); err != nil {
+		return err
+	}
+	for _, dir := range []string{c.tempDir, c.hermeticTmp} {
+		if dir == "" {
+			continue
+		}
+		if err := c.chownForUser(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chownForUser hands files the package creates for the process over to the
+// user set with `WithUser`, so the process can use them.
+func (c *Cmd) chownForUser(paths ...string) error {
+	if c.user == "" {
+		return nil
+	}
+	for _, p := range paths {
+		if err := os.Chown(p, int(c.userCred.uid), int(c.userCred.gid)); err != nil {
+			return fmt.Errorf("execctx: error handing %s to user %q: %w", p, c.user, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly && !solaris && !aix
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly,!solaris,!aix
+
+package execctx
+
+import "errors"
+
+func (c *Cmd) setCredential(cred credential) error {
+	return errors.New("execctx: running as another user is not supported on this platform")
+}
@@ -0,0 +1,81 @@
+package execctx
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"os/user"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestResolveUser(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("users are not supported on windows")
+	}
+
+	cred, err := resolveUser("root", false)
+	assert.NilError(t, err)
+	assert.Equal(t, cred.uid, uint32(0))
+	assert.Equal(t, cred.gid, uint32(0))
+	assert.Equal(t, cred.user.Username, "root")
+
+	cred, err = resolveUser("0:12345", false)
+	assert.NilError(t, err)
+	assert.Equal(t, cred.uid, uint32(0))
+	assert.Equal(t, cred.gid, uint32(12345))
+
+	// Unknown numeric users need a group.
+	cred, err = resolveUser("54321:54321", false)
+	assert.NilError(t, err)
+	assert.Equal(t, cred.uid, uint32(54321))
+	assert.Assert(t, cred.user == nil)
+	_, err = resolveUser("54321", false)
+	assert.ErrorContains(t, err, "requires a group")
+
+	_, err = resolveUser("no-such-user-execctx", false)
+	assert.ErrorContains(t, err, "no-such-user-execctx")
+}
+
+func TestWithUser(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Getuid() != 0 {
+		t.Skip("requires root")
+	}
+
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	var out strings.Builder
+	cmd := exec.Command("sh", "-c", `echo "$(id -u):$(id -g):$(id -G) $HOME $USER"`)
+	c := FromCmd(context.Background(), cmd, nil, WithStdout(&out), WithUser("nobody"))
+	assert.NilError(t, c.Run())
+	// Root's supplementary groups are dropped.
+	expected := nobody.Uid + ":" + nobody.Gid + ":" + nobody.Gid + " " + nobody.HomeDir + " nobody\n"
+	assert.Equal(t, out.String(), expected)
+
+	out.Reset()
+	cmd = exec.Command("sh", "-c", `echo "$(id -u):$(id -g)"`)
+	c = FromCmd(context.Background(), cmd, nil, WithStdout(&out), WithUser("54321:54321"), WithInitGroups())
+	assert.NilError(t, c.Run())
+	assert.Equal(t, out.String(), "54321:54321\n")
+}
+
+func TestWithUserFiles(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Getuid() != 0 {
+		t.Skip("requires root")
+	}
+	if _, err := user.Lookup("nobody"); err != nil {
+		t.Skip(err)
+	}
+
+	// The process can use its temp dirs and health file.
+	cmd := exec.Command("sh", "-c", `touch scratch && touch "$TMPDIR/scratch" && touch "$EXECCTX_HEALTH_FILE"`)
+	c := FromCmd(context.Background(), cmd, nil, WithUser("nobody"), WithTempDir("execctx-user"), WithHealthFile(time.Minute), Hermetic("/bin", "/usr/bin"))
+	assert.NilError(t, c.Run())
+}
@@ -0,0 +1,18 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly || solaris || aix
+// +build linux darwin freebsd netbsd openbsd dragonfly solaris aix
+
+package execctx
+
+import "syscall"
+
+func (c *Cmd) setCredential(cred credential) error {
+	if c.cmd.SysProcAttr == nil {
+		c.cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	c.cmd.SysProcAttr.Credential = &syscall.Credential{
+		Uid:    cred.uid,
+		Gid:    cred.gid,
+		Groups: cred.groups,
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package execctx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Verifier checks the program of a command before it is started.
+type Verifier interface {
+	// Verify is passed the resolved path of the program.
+	// If it returns an error the command is not started.
+	Verify(path string) error
+}
+
+// VerifierFunc adapts a function to the `Verifier` interface.
+type VerifierFunc func(path string) error
+
+// Verify calls f(path)
+func (f VerifierFunc) Verify(path string) error {
+	return f(path)
+}
+
+// WithVerifier adds a verifier which is run right before the command is
+// started.
+func WithVerifier(v Verifier) Option {
+	return func(c *Cmd) {
+		c.verifiers = append(c.verifiers, v)
+	}
+}
+
+// WithExpectedChecksum verifies that the program has the provided
+// hex encoded sha256 digest before the command is started.
+// On mismatch `Start` returns a `*ChecksumError`. Otherwise the observed
+// digest is recorded, see `ProgramSHA256`.
+//
+// Note that the program is read once to compute the digest and again by the
+// kernel to execute it, so this guards against tampered installations rather
+// than against an attacker racing the start of the command.
+func WithExpectedChecksum(sha256Hex string) Option {
+	return WithVerifier(sha256Verifier(strings.ToLower(sha256Hex)))
+}
+
+type sha256Verifier string
+
+func (v sha256Verifier) Verify(path string) error {
+	_, err := v.verify(path)
+	return err
+}
+
+// verify is like Verify, and also returns the observed digest.
+func (v sha256Verifier) verify(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("error opening program to verify checksum: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("error reading program to verify checksum: %w", err)
+	}
+
+	actual := hex.EncodeToString(h.Sum(nil))
+	if actual != string(v) {
+		return actual, &ChecksumError{Path: path, Expected: string(v), Actual: actual}
+	}
+	return actual, nil
+}
+
+// ChecksumError is returned when the program of a command does not match
+// the expected checksum.
+type ChecksumError struct {
+	Path     string
+	Expected string
+	// Actual is the observed sha256 digest of the program.
+	Actual string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("execctx: checksum mismatch for %s: expected sha256:%s, got sha256:%s", e.Path, e.Expected, e.Actual)
+}
+
+// ProgramSHA256 returns the hex encoded sha256 digest of the program observed
+// by `WithExpectedChecksum` when the command was started, or "" if the
+// checksum was not verified.
+func (c *Cmd) ProgramSHA256() string {
+	return c.programSHA256
+}
+
+func (c *Cmd) runVerifiers() error {
+	for _, v := range c.verifiers {
+		if sv, ok := v.(sha256Verifier); ok {
+			digest, err := sv.verify(c.cmd.Path)
+			if err != nil {
+				return err
+			}
+			c.programSHA256 = digest
+			continue
+		}
+		if err := v.Verify(c.cmd.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
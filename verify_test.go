@@ -0,0 +1,32 @@
+package execctx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os/exec"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestExpectedChecksum(t *testing.T) {
+	cmd := exec.Command("true")
+	data, err := ioutil.ReadFile(cmd.Path)
+	assert.NilError(t, err)
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+
+	c := FromCmd(context.Background(), cmd, nil, WithExpectedChecksum(digest))
+	r, err := c.RunResult()
+	assert.NilError(t, err)
+	assert.Equal(t, c.ProgramSHA256(), digest)
+	assert.Equal(t, r.ProgramSHA256, digest)
+
+	err = FromCmd(context.Background(), exec.Command("true"), nil, WithExpectedChecksum("abc123")).Run()
+	var ce *ChecksumError
+	assert.Assert(t, errors.As(err, &ce), err)
+	assert.Equal(t, ce.Actual, digest)
+}
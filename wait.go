@@ -0,0 +1,87 @@
+package execctx
+
+import (
+	"context"
+)
+
+// WaitAny waits for any of the passed in commands to exit.
+// It returns the first command to exit along with the result of its `Wait`.
+//
+// If the context is cancelled before any command exits, the context error is
+// returned.
+// The remaining commands continue to be waited on in the background, so
+// calling `Wait` on them later returns their result as normal.
+func WaitAny(ctx context.Context, cmds ...*Cmd) (*Cmd, error) {
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+
+	ch := make(chan *Cmd, len(cmds))
+	for _, c := range cmds {
+		go func(c *Cmd) {
+			c.Wait()
+			ch <- c
+		}(c)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case c := <-ch:
+		return c, c.Wait()
+	}
+}
+
+// WaitAll waits for all the passed in commands to exit.
+// The returned error is the first non-nil error from the commands in the
+// order they were passed in.
+//
+// If the context is cancelled before all commands have exited, the context
+// error is returned.
+func WaitAll(ctx context.Context, cmds ...*Cmd) error {
+	for _, c := range cmds {
+		go c.Wait()
+	}
+
+	var err error
+	for _, c := range cmds {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.done():
+		}
+		if err == nil {
+			err = c.Wait()
+		}
+	}
+	return err
+}
+
+// WaitContext is like `Wait`, but gives up waiting once ctx is done and
+// returns its error. This bounds how long the caller blocks on a process
+// which does not exit, for example because it ignores the signals sent when
+// it was cancelled.
+//
+// The process is still waited on in the background, so it is reaped once it
+// exits, and later calls to `Wait` return its result.
+func (c *Cmd) WaitContext(ctx context.Context) error {
+	c.reapOnce.Do(func() {
+		go c.Wait()
+	})
+
+	select {
+	case <-c.done():
+		return c.Wait()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// done returns a channel which is closed once `Wait` returns without
+// blocking.
+func (c *Cmd) done() <-chan struct{} {
+	if c.interception != nil {
+		return c.interception.done
+	}
+	return c.waitDone
+}
@@ -0,0 +1,70 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestWaitAny(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	slow := FromCmd(ctx, exec.Command("sleep", "99999"), nil)
+	fast := FromCmd(ctx, exec.Command("true"), nil)
+	assert.NilError(t, slow.Start())
+	assert.NilError(t, fast.Start())
+
+	c, err := WaitAny(context.Background(), slow, fast)
+	assert.NilError(t, err)
+	assert.Equal(t, c, fast)
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer waitCancel()
+	_, err = WaitAny(waitCtx, slow)
+	assert.Equal(t, err, context.DeadlineExceeded)
+
+	cancel()
+	assert.ErrorContains(t, slow.Wait(), "killed")
+}
+
+func TestWaitAll(t *testing.T) {
+	a := FromCmd(context.Background(), exec.Command("true"), nil)
+	b := FromCmd(context.Background(), exec.Command("false"), nil)
+	assert.NilError(t, a.Start())
+	assert.NilError(t, b.Start())
+
+	assert.ErrorContains(t, WaitAll(context.Background(), a, b), "exit status 1")
+	assert.NilError(t, a.Wait())
+
+	// The interceptor doesn't run the command, so its process never exits.
+	skip := InterceptorFunc(func(context.Context, *Cmd, func() error) error {
+		return nil
+	})
+	c := FromCmd(context.Background(), exec.Command("true"), nil, WithInterceptors(skip))
+	c.Start()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := WaitAll(ctx, a, c)
+	assert.Assert(t, errors.Is(err, ErrNotRun), err)
+}
+
+func TestWaitContext(t *testing.T) {
+	// The handler does nothing, so the process keeps running.
+	c := FromCmd(context.Background(), exec.Command("sleep", "99999"), func() {})
+	assert.NilError(t, c.Start())
+	c.Cancel(nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, c.WaitContext(ctx), context.DeadlineExceeded)
+
+	c.cmd.Process.Kill()
+	err := c.WaitContext(context.Background())
+	assert.Assert(t, errors.Is(err, context.Canceled), err)
+	assert.Equal(t, c.Wait(), err)
+}